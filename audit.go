@@ -0,0 +1,66 @@
+package grpcsrv
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// returns caller identity for audit log.
+func (s *Service) auditPrincipal(ctx context.Context) string {
+	if s.auditPrincipalFunc != nil {
+		return s.auditPrincipalFunc(ctx)
+	}
+
+	if cert, ok := PeerCertificateFromContext(ctx); ok {
+		return cert.Subject.CommonName
+	}
+
+	return ""
+}
+
+// sends audit record of the call to audit sink.
+func (s *Service) audit(ctx context.Context, method string, start time.Time, err error) {
+	traceID, _ := s.traceIDFromContext(ctx)
+
+	s.auditSink.Audit(ctx, AuditRecord{
+		Method:     method,
+		Principal:  s.auditPrincipal(ctx),
+		RemoteAddr: extractRemoteAddr(ctx),
+		TraceID:    traceID,
+		Time:       start,
+		Code:       status.Code(err),
+	})
+}
+
+// interceptor for audit logging of unary calls.
+func (s *Service) auditUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if _, ok := s.auditMethods[info.FullMethod]; !ok {
+		return handler(ctx, req)
+	}
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	s.audit(ctx, info.FullMethod, start, err)
+
+	return resp, err
+}
+
+// interceptor for audit logging of streams.
+func (s *Service) auditStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if _, ok := s.auditMethods[info.FullMethod]; !ok {
+		return handler(srv, ss)
+	}
+
+	start := time.Now()
+	err := handler(srv, ss)
+	s.audit(ss.Context(), info.FullMethod, start, err)
+
+	return err
+}
@@ -0,0 +1,96 @@
+package grpcsrv
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// testAuditSink IAuditSink, which records audit records.
+type testAuditSink struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (s *testAuditSink) Audit(_ context.Context, r AuditRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, r)
+}
+
+// auditContext returns context of mTLS call from 10.0.0.1 with client certificate CN=client and sampled span.
+func auditContext() context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "client"}}
+
+	return peer.NewContext(testSpanContext(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 12345},
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{cert},
+			VerifiedChains:   [][]*x509.Certificate{{cert}},
+		}},
+	})
+}
+
+func TestAuditUnaryInterceptor(t *testing.T) {
+	sink := &testAuditSink{}
+	s := New(context.Background(), nil, WithAuditLog([]string{"/test.Service/Delete"}, sink))
+
+	_, _ = s.auditUnaryInterceptor(auditContext(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Get"},
+		func(context.Context, any) (any, error) { return nil, nil })
+	if len(sink.records) != 0 {
+		t.Fatalf("not audited method must not be recorded, got %v", sink.records)
+	}
+
+	_, err := s.auditUnaryInterceptor(auditContext(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Delete"},
+		func(context.Context, any) (any, error) { return nil, status.Error(codes.PermissionDenied, "denied") })
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("handler error must be returned, got %v", err)
+	}
+
+	if len(sink.records) != 1 {
+		t.Fatalf("expected 1 audit record, got %d", len(sink.records))
+	}
+
+	r := sink.records[0]
+	switch {
+	case r.Method != "/test.Service/Delete":
+		t.Fatalf("unexpected method %q", r.Method)
+	case r.Principal != "client":
+		t.Fatalf("expected principal from client certificate, got %q", r.Principal)
+	case r.RemoteAddr != "10.0.0.1":
+		t.Fatalf("unexpected remote address %q", r.RemoteAddr)
+	case r.TraceID != (trace.TraceID{1, 2, 3}).String():
+		t.Fatalf("unexpected trace ID %q", r.TraceID)
+	case r.Code != codes.PermissionDenied:
+		t.Fatalf("unexpected code %s", r.Code)
+	case r.Time.IsZero():
+		t.Fatal("call start time must be set")
+	}
+}
+
+func TestAuditStreamInterceptor(t *testing.T) {
+	sink := &testAuditSink{}
+	s := New(context.Background(), nil, WithAuditLog([]string{"/test.Service/Watch"}, sink),
+		WithAuditPrincipal(func(context.Context) string { return "user" }))
+
+	err := s.auditStreamInterceptor(nil, &testServerStream{ctx: auditContext()},
+		&grpc.StreamServerInfo{FullMethod: "/test.Service/Watch"}, func(any, grpc.ServerStream) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.records) != 1 || sink.records[0].Principal != "user" || sink.records[0].Code != codes.OK {
+		t.Fatalf("expected audit record with custom principal, got %+v", sink.records)
+	}
+}
@@ -0,0 +1,131 @@
+package grpcsrv
+
+import (
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// backpressureServerStream sends messages of the handler asynchronously, keeping at most
+// maxInFlight messages that were not yet accepted by transport. SendMsg blocks when the limit is reached.
+// Only the writer goroutine calls SendMsg of the wrapped stream. SetHeader and SendHeader wait until the queued
+// messages are written, so they are never called concurrently with SendMsg.
+type backpressureServerStream struct {
+	grpc.ServerStream
+
+	queue   chan any
+	pending sync.WaitGroup // messages queued, but not yet written
+	writer  sync.WaitGroup
+
+	errMu sync.Mutex
+	err   error
+}
+
+func newBackpressureServerStream(ss grpc.ServerStream, maxInFlight int) *backpressureServerStream {
+	b := &backpressureServerStream{
+		ServerStream: ss,
+		// one more message is held by the writer
+		queue: make(chan any, maxInFlight-1),
+	}
+
+	b.writer.Add(1)
+	go b.write()
+
+	return b
+}
+
+// write sends queued messages to the transport. After the first error the rest of the queue is discarded.
+func (b *backpressureServerStream) write() {
+	defer b.writer.Done()
+
+	for msg := range b.queue {
+		if b.getErr() == nil {
+			if err := b.ServerStream.SendMsg(msg); err != nil {
+				b.setErr(err)
+			}
+		}
+		b.pending.Done()
+	}
+}
+
+// SendMsg implements grpc.ServerStream. Proto message is copied, so it can be modified
+// by the handler after SendMsg returns. Error of writing a message is returned by the subsequent
+// calls of SendMsg and by the handler call itself.
+func (b *backpressureServerStream) SendMsg(m any) error {
+	if err := b.getErr(); err != nil {
+		return err
+	}
+
+	msg := m
+	if pm, ok := m.(proto.Message); ok {
+		msg = proto.Clone(pm)
+	}
+
+	b.pending.Add(1)
+	select {
+	case b.queue <- msg:
+		return nil
+	case <-b.Context().Done():
+		b.pending.Done()
+		return b.Context().Err()
+	}
+}
+
+// SetHeader implements grpc.ServerStream.
+func (b *backpressureServerStream) SetHeader(md metadata.MD) error {
+	b.pending.Wait()
+	return b.ServerStream.SetHeader(md)
+}
+
+// SendHeader implements grpc.ServerStream.
+func (b *backpressureServerStream) SendHeader(md metadata.MD) error {
+	b.pending.Wait()
+	if err := b.getErr(); err != nil {
+		return err
+	}
+
+	return b.ServerStream.SendHeader(md)
+}
+
+// flush waits for queued messages to be sent and returns the first send error.
+func (b *backpressureServerStream) flush() error {
+	close(b.queue)
+	b.writer.Wait()
+
+	return b.getErr()
+}
+
+func (b *backpressureServerStream) getErr() error {
+	b.errMu.Lock()
+	defer b.errMu.Unlock()
+
+	return b.err
+}
+
+func (b *backpressureServerStream) setErr(err error) {
+	b.errMu.Lock()
+	defer b.errMu.Unlock()
+
+	b.err = err
+}
+
+// interceptor for limiting number of in-flight messages of server streams.
+func (s *Service) backpressureStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) (err error) {
+	if !info.IsServerStream {
+		return handler(srv, ss)
+	}
+
+	bs := newBackpressureServerStream(ss, s.maxInFlightWrites)
+	// status and trailer are sent by gRPC after the interceptor returns, so queued messages are written first
+	defer func() {
+		if errFlush := bs.flush(); err == nil {
+			err = errFlush
+		}
+	}()
+
+	return handler(srv, bs)
+}
@@ -0,0 +1,185 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// testServerStream grpc.ServerStream for tests. send is called by SendMsg and recv by RecvMsg, if set.
+type testServerStream struct {
+	ctx  context.Context //nolint:containedctx // test
+	send func(m any) error
+	recv func(m any) error
+	sent []any
+}
+
+func (s *testServerStream) SetHeader(metadata.MD) error  { return nil }
+func (s *testServerStream) SendHeader(metadata.MD) error { return nil }
+func (s *testServerStream) SetTrailer(metadata.MD)       {}
+func (s *testServerStream) Context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+func (s *testServerStream) SendMsg(m any) error {
+	if s.send != nil {
+		if err := s.send(m); err != nil {
+			return err
+		}
+	}
+	s.sent = append(s.sent, m)
+	return nil
+}
+
+func (s *testServerStream) RecvMsg(m any) error {
+	if s.recv != nil {
+		return s.recv(m)
+	}
+	return nil
+}
+
+func TestMaxInFlightWrites(t *testing.T) {
+	const limit, total = 2, 5
+	s := New(context.Background(), nil, WithMaxInFlightWrites(limit))
+
+	// slow reader: transport accepts a message only when the client reads it
+	read := make(chan struct{})
+	ss := &testServerStream{send: func(any) error {
+		<-read
+		return nil
+	}}
+
+	var returned atomic.Int32
+	done := make(chan error, 1)
+	go func() {
+		done <- s.backpressureStreamInterceptor(nil, ss, &grpc.StreamServerInfo{IsServerStream: true},
+			func(_ any, stream grpc.ServerStream) error {
+				msg := wrapperspb.Int32(0)
+				for i := range total {
+					msg.Value = int32(i)
+					if err := stream.SendMsg(msg); err != nil {
+						return err
+					}
+					returned.Add(1)
+				}
+				return nil
+			})
+	}()
+
+	waitReturned := func(want int32) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for returned.Load() < want {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d messages to be accepted, got %d", want, returned.Load())
+			}
+			time.Sleep(time.Millisecond)
+		}
+		// Send blocks while the limit of unread messages is reached
+		time.Sleep(20 * time.Millisecond)
+		if got := returned.Load(); got != want {
+			t.Fatalf("expected SendMsg to block after %d messages, got %d", want, got)
+		}
+	}
+
+	waitReturned(limit)
+	read <- struct{}{}
+	waitReturned(limit + 1)
+
+	close(read)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ss.sent) != total {
+		t.Fatalf("expected %d messages sent, got %d", total, len(ss.sent))
+	}
+	for i, m := range ss.sent {
+		if v := m.(*wrapperspb.Int32Value).GetValue(); v != int32(i) { //nolint:forcetypeassert // test
+			t.Fatalf("message %d was modified after SendMsg: %d", i, v)
+		}
+	}
+}
+
+func TestMaxInFlightWritesError(t *testing.T) {
+	s := New(context.Background(), nil, WithMaxInFlightWrites(1))
+
+	errSend := status.Error(codes.Unavailable, "transport closed")
+	ss := &testServerStream{send: func(m any) error {
+		if m.(*wrapperspb.Int32Value).GetValue() == 2 { //nolint:forcetypeassert // test
+			return errSend
+		}
+		return nil
+	}}
+
+	var results []error
+	err := s.backpressureStreamInterceptor(nil, ss, &grpc.StreamServerInfo{IsServerStream: true},
+		func(_ any, stream grpc.ServerStream) error {
+			for i := range 10 {
+				err := stream.SendMsg(wrapperspb.Int32(int32(i)))
+				results = append(results, err)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	if !errors.Is(err, errSend) {
+		t.Fatalf("expected send error, got %v", err)
+	}
+	// delivered messages are never reported as failed
+	for i := range 2 {
+		if results[i] != nil {
+			t.Fatalf("message %d was delivered, got error %v", i, results[i])
+		}
+	}
+	if len(ss.sent) != 2 {
+		t.Fatalf("expected 2 messages delivered, got %d", len(ss.sent))
+	}
+}
+
+// headerServerStream testServerStream, which records number of messages sent before SendHeader.
+type headerServerStream struct {
+	testServerStream
+	sentBeforeHeader int
+}
+
+func (s *headerServerStream) SendHeader(metadata.MD) error {
+	s.sentBeforeHeader = len(s.sent)
+	return nil
+}
+
+func TestMaxInFlightWritesSendHeader(t *testing.T) {
+	s := New(context.Background(), nil, WithMaxInFlightWrites(3))
+
+	ss := &headerServerStream{testServerStream: testServerStream{send: func(any) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}}}
+
+	err := s.backpressureStreamInterceptor(nil, ss, &grpc.StreamServerInfo{IsServerStream: true},
+		func(_ any, stream grpc.ServerStream) error {
+			_ = stream.SendMsg(wrapperspb.Int32(1))
+			_ = stream.SendMsg(wrapperspb.Int32(2))
+			return stream.SendHeader(metadata.Pairs("x-key", "value"))
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ss.sentBeforeHeader != 2 {
+		t.Fatalf("SendHeader must wait for queued messages, %d sent before it", ss.sentBeforeHeader)
+	}
+}
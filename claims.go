@@ -0,0 +1,114 @@
+package grpcsrv
+
+import (
+	"context"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type claimsKey struct{}
+
+// defaultClaimsSkipMethods services that don't require bearer token by default.
+var defaultClaimsSkipMethods = []string{
+	"/grpc.health.v1.Health/",
+	"/grpc.reflection.v1.ServerReflection/",
+	"/grpc.reflection.v1alpha.ServerReflection/",
+}
+
+// claimsRequired reports whether the method requires bearer token.
+func (s *Service) claimsRequired(fullMethod string) bool {
+	if len(s.claimsMethods) > 0 {
+		_, ok := s.claimsMethods[fullMethod]
+		return ok
+	}
+
+	for _, m := range s.claimsSkipMethods {
+		if m == fullMethod || (strings.HasSuffix(m, "/") && strings.HasPrefix(fullMethod, m)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ClaimsFromContext returns claims of the bearer token parsed by WithJWTClaims.
+// T is the type of claims created by the claims factory, e.g. *MyClaims.
+func ClaimsFromContext[T jwt.Claims](ctx context.Context) (T, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(T)
+	return claims, ok
+}
+
+// bearerTokenFromMetadata returns token from "authorization: Bearer <token>" metadata.
+func bearerTokenFromMetadata(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	const prefix = "bearer "
+	for _, v := range md.Get("authorization") {
+		if len(v) > len(prefix) && strings.EqualFold(v[:len(prefix)], prefix) {
+			return strings.TrimSpace(v[len(prefix):]), true
+		}
+	}
+
+	return "", false
+}
+
+// parses bearer token and puts claims into context.
+func (s *Service) claimsToContext(ctx context.Context) (context.Context, error) {
+	token, ok := bearerTokenFromMetadata(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "bearer token is required")
+	}
+
+	// signature, algorithm (by keyFunc) and registered claims (exp, nbf, iat) are validated
+	claims := s.claimsFactory()
+	if _, err := jwt.ParseWithClaims(token, claims, s.claimsKeyFunc); err != nil {
+		s.logger.Debug(ctx, "invalid bearer token", "error", err)
+		return nil, status.Error(codes.Unauthenticated, "invalid bearer token")
+	}
+
+	return context.WithValue(ctx, claimsKey{}, claims), nil
+}
+
+// interceptor for parsing bearer token claims of unary calls.
+func (s *Service) claimsUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if !s.claimsRequired(info.FullMethod) {
+		return handler(ctx, req)
+	}
+
+	ctx, err := s.claimsToContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// interceptor for parsing bearer token claims of streams.
+func (s *Service) claimsStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if !s.claimsRequired(info.FullMethod) {
+		return handler(srv, ss)
+	}
+
+	ctx, err := s.claimsToContext(ss.Context())
+	if err != nil {
+		return err
+	}
+
+	wrapped := grpc_middleware.WrapServerStream(ss)
+	wrapped.WrappedContext = ctx
+
+	return handler(srv, wrapped)
+}
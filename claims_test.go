@@ -0,0 +1,161 @@
+package grpcsrv
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var testJWTKey = []byte("test-secret")
+
+type testClaims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role"`
+}
+
+// testKeyFunc accepts tokens signed with HMAC by testJWTKey.
+func testKeyFunc(token *jwt.Token) (any, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+
+	return testJWTKey, nil
+}
+
+func testClaimsFactory() jwt.Claims {
+	return &testClaims{}
+}
+
+// signTestToken returns token with claims signed by the method and key.
+func signTestToken(t *testing.T, method jwt.SigningMethod, key any, claims jwt.Claims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestClaimsUnaryInterceptor(t *testing.T) {
+	s := New(context.Background(), nil, WithJWTClaims(testKeyFunc, testClaimsFactory))
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := func(expiresAt time.Time) *testClaims {
+		return &testClaims{
+			RegisteredClaims: jwt.RegisteredClaims{Subject: "user", ExpiresAt: jwt.NewNumericDate(expiresAt)},
+			Role:             "admin",
+		}
+	}
+	valid := claims(time.Now().Add(time.Hour))
+	expired := claims(time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name       string
+		method     string
+		token      string
+		code       codes.Code
+		wantClaims bool
+	}{
+		{
+			name:       "valid token",
+			method:     "/test.Service/Method",
+			token:      signTestToken(t, jwt.SigningMethodHS256, testJWTKey, valid),
+			code:       codes.OK,
+			wantClaims: true,
+		},
+		{
+			name:   "bad signature",
+			method: "/test.Service/Method",
+			token:  signTestToken(t, jwt.SigningMethodHS256, []byte("other-secret"), valid),
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "expired token",
+			method: "/test.Service/Method",
+			token:  signTestToken(t, jwt.SigningMethodHS256, testJWTKey, expired),
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "wrong algorithm",
+			method: "/test.Service/Method",
+			token:  signTestToken(t, jwt.SigningMethodES256, ecKey, valid),
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "unsigned token",
+			method: "/test.Service/Method",
+			token:  signTestToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid),
+			code:   codes.Unauthenticated,
+		},
+		{name: "malformed token", method: "/test.Service/Method", token: "invalid", code: codes.Unauthenticated},
+		{name: "missing token", method: "/test.Service/Method", code: codes.Unauthenticated},
+		{name: "health is skipped", method: "/grpc.health.v1.Health/Check", code: codes.OK},
+		{name: "reflection is skipped", method: "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo", code: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.token != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+tt.token))
+			}
+
+			var (
+				gotClaims *testClaims
+				gotOK     bool
+			)
+			handler := func(ctx context.Context, _ any) (any, error) {
+				gotClaims, gotOK = ClaimsFromContext[*testClaims](ctx)
+				return nil, nil
+			}
+
+			_, err := s.claimsUnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if status.Code(err) != tt.code {
+				t.Fatalf("expected code %s, got %v", tt.code, err)
+			}
+
+			if tt.wantClaims {
+				if !gotOK || gotClaims.Subject != "user" || gotClaims.Role != "admin" {
+					t.Fatalf("unexpected claims %#v", gotClaims)
+				}
+			} else if gotOK {
+				t.Fatalf("unexpected claims %#v", gotClaims)
+			}
+		})
+	}
+}
+
+func TestClaimsRequired(t *testing.T) {
+	s := New(context.Background(), nil, WithJWTClaims(testKeyFunc, testClaimsFactory, "/test.Service/Secure"))
+	if !s.claimsRequired("/test.Service/Secure") {
+		t.Fatal("configured method must require token")
+	}
+	if s.claimsRequired("/test.Service/Public") {
+		t.Fatal("not configured method must not require token")
+	}
+
+	s = New(context.Background(), nil,
+		WithJWTClaims(testKeyFunc, testClaimsFactory), WithJWTClaimsSkipMethods("/test.Service/"))
+	if s.claimsRequired("/test.Service/Method") {
+		t.Fatal("skipped service must not require token")
+	}
+	if !s.claimsRequired("/grpc.health.v1.Health/Check") {
+		t.Fatal("default skip list must be replaced")
+	}
+}
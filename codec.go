@@ -0,0 +1,27 @@
+package grpcsrv
+
+import (
+	"google.golang.org/grpc/encoding"
+)
+
+// namedCodec overrides the name of the wrapped codec.
+type namedCodec struct {
+	encoding.Codec
+	name string
+}
+
+// Name returns the name of the codec used for content-subtype negotiation.
+func (c namedCodec) Name() string {
+	return c.name
+}
+
+// registerCodecs registers custom codecs in gRPC.
+func (s *Service) registerCodecs() {
+	for name, codec := range s.codecs {
+		if codec.Name() != name {
+			codec = namedCodec{Codec: codec, name: name}
+		}
+
+		encoding.RegisterCodec(codec)
+	}
+}
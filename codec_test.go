@@ -0,0 +1,48 @@
+package grpcsrv
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// countingCodec proto codec, which counts marshalled messages.
+type countingCodec struct {
+	encoding.Codec
+	marshaled atomic.Int32
+}
+
+func (c *countingCodec) Marshal(v any) ([]byte, error) {
+	c.marshaled.Add(1)
+	return c.Codec.Marshal(v)
+}
+
+func TestCodec(t *testing.T) {
+	const name = "grpcsrv-test-proto"
+	codec := &countingCodec{Codec: encoding.GetCodec("proto")}
+
+	s := startTestService(t, WithCodec(name, codec))
+
+	if c := encoding.GetCodec(name); c == nil || c.Name() != name {
+		t.Fatalf("codec must be registered under name %q", name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := grpc_health_v1.NewHealthClient(dialTestService(t, s)).Check(ctx,
+		&grpc_health_v1.HealthCheckRequest{}, grpc.CallContentSubtype(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// request is marshalled by the client and response by the server
+	if n := codec.marshaled.Load(); n < 2 {
+		t.Fatalf("expected codec to be used by client and server, got %d calls", n)
+	}
+}
@@ -0,0 +1,282 @@
+package grpcsrv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const (
+	connectProtocolVersionHeader = "Connect-Protocol-Version"
+	connectTimeoutHeader         = "Connect-Timeout-Ms"
+	connectTrailerPrefix         = "Trailer-"
+
+	connectContentTypeJSON  = "application/json"
+	connectContentTypeProto = "application/proto"
+)
+
+// connectMethod unary method available via Connect protocol.
+type connectMethod struct {
+	input  protoreflect.MessageType
+	output protoreflect.MessageType
+}
+
+// connectHandler serves unary calls of Connect protocol (https://connectrpc.com/docs/protocol)
+// by forwarding them to gRPC server over the gateway connection.
+type connectHandler struct {
+	s       *Service
+	conn    *grpc.ClientConn
+	methods map[string]connectMethod // full method name -> types
+}
+
+// newConnectHandler collects unary methods of services registered in gRPC server.
+// Message types are resolved from the global protobuf registry.
+func (s *Service) newConnectHandler(ctx context.Context, conn *grpc.ClientConn) *connectHandler {
+	h := &connectHandler{
+		s:       s,
+		conn:    conn,
+		methods: make(map[string]connectMethod),
+	}
+
+	for serviceName, info := range s.grpcServer.GetServiceInfo() {
+		desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(serviceName))
+		if err != nil {
+			s.logger.Warn(ctx, "connect protocol: service descriptor not found", "service", serviceName, "error", err)
+			continue
+		}
+		serviceDesc, ok := desc.(protoreflect.ServiceDescriptor)
+		if !ok {
+			continue
+		}
+
+		for _, m := range info.Methods {
+			if m.IsClientStream || m.IsServerStream {
+				continue // only unary calls are supported
+			}
+
+			methodDesc := serviceDesc.Methods().ByName(protoreflect.Name(m.Name))
+			if methodDesc == nil {
+				continue
+			}
+
+			input, errIn := protoregistry.GlobalTypes.FindMessageByName(methodDesc.Input().FullName())
+			output, errOut := protoregistry.GlobalTypes.FindMessageByName(methodDesc.Output().FullName())
+			if errIn != nil || errOut != nil {
+				continue
+			}
+
+			h.methods["/"+serviceName+"/"+m.Name] = connectMethod{input: input, output: output}
+		}
+	}
+
+	return h
+}
+
+// setConnectHTTPMiddleware serves POST requests to "/package.Service/Method" paths of unary methods
+// with Connect protocol. Other requests are passed to the gateway.
+func (s *Service) setConnectHTTPMiddleware(ctx context.Context, next http.Handler, conn *grpc.ClientConn) http.Handler {
+	if !s.connectProtocol {
+		return next
+	}
+
+	h := s.newConnectHandler(ctx, conn)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		method, ok := h.methods[r.URL.Path]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h.serve(w, r, r.URL.Path, method)
+	})
+}
+
+// serve handles unary Connect call.
+func (h *connectHandler) serve(w http.ResponseWriter, r *http.Request, fullMethod string, method connectMethod) {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != connectContentTypeJSON && contentType != connectContentTypeProto {
+		http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	if v := r.Header.Get(connectProtocolVersionHeader); v != "" && v != "1" {
+		h.writeError(w, status.Errorf(codes.InvalidArgument, "unsupported connect protocol version %s", v))
+		return
+	}
+
+	if enc := r.Header.Get("Content-Encoding"); enc != "" && enc != "identity" {
+		h.writeError(w, status.Errorf(codes.Unimplemented, "unsupported content encoding %s", enc))
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.writeError(w, status.Errorf(codes.InvalidArgument, "failed to read request body: %v", err))
+		return
+	}
+
+	req := method.input.New().Interface()
+	if contentType == connectContentTypeJSON {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, req)
+	} else {
+		err = proto.Unmarshal(body, req)
+	}
+	if err != nil {
+		h.writeError(w, status.Errorf(codes.InvalidArgument, "failed to unmarshal request: %v", err))
+		return
+	}
+
+	ctx := r.Context()
+	if v := r.Header.Get(connectTimeoutHeader); v != "" {
+		timeoutMs, errParse := strconv.ParseInt(v, 10, 64)
+		if errParse != nil || timeoutMs <= 0 {
+			h.writeError(w, status.Errorf(codes.InvalidArgument, "invalid %s header: %s", connectTimeoutHeader, v))
+			return
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeoutMs)*time.Millisecond)
+		defer cancel()
+	}
+
+	ctx = metadata.NewOutgoingContext(ctx, connectRequestMetadata(r))
+
+	var header, trailer metadata.MD
+	resp := method.output.New().Interface()
+	err = h.conn.Invoke(ctx, fullMethod, req, resp, grpc.Header(&header), grpc.Trailer(&trailer))
+
+	for k, vals := range header {
+		for _, v := range vals {
+			w.Header().Add(k, v)
+		}
+	}
+	for k, vals := range trailer {
+		for _, v := range vals {
+			w.Header().Add(connectTrailerPrefix+k, v)
+		}
+	}
+
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	var data []byte
+	if contentType == connectContentTypeJSON {
+		data, err = protojson.Marshal(resp)
+	} else {
+		data, err = proto.Marshal(resp)
+	}
+	if err != nil {
+		h.writeError(w, status.Errorf(codes.Internal, "failed to marshal response: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+// connectRequestMetadata converts request headers to gRPC metadata, skipping protocol-specific ones.
+func connectRequestMetadata(r *http.Request) metadata.MD {
+	md := make(metadata.MD, len(r.Header))
+	for k, vals := range r.Header {
+		switch k {
+		case "Content-Type", "Content-Length", "Content-Encoding", "Accept-Encoding", "Connection",
+			connectProtocolVersionHeader, connectTimeoutHeader:
+			continue
+		}
+		md.Append(strings.ToLower(k), vals...)
+	}
+
+	return md
+}
+
+// connectError error in Connect protocol JSON format.
+type connectError struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// writeError writes gRPC error in Connect protocol format.
+func (h *connectHandler) writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+
+	data, errMarshal := json.Marshal(connectError{
+		Code:    connectCode(st.Code()),
+		Message: st.Message(),
+	})
+	if errMarshal != nil {
+		http.Error(w, fmt.Sprintf("failed to marshal error: %v", errMarshal), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", connectContentTypeJSON)
+	w.WriteHeader(connectHTTPStatus(st.Code()))
+	_, _ = w.Write(data)
+}
+
+// connectCode returns Connect protocol name of gRPC code, e.g. "not_found".
+func connectCode(code codes.Code) string {
+	var b strings.Builder
+	for i, c := range code.String() {
+		if c >= 'A' && c <= 'Z' {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			c += 'a' - 'A'
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+// connectHTTPStatus returns HTTP status of the error according to Connect protocol.
+func connectHTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.Canceled:
+		return 499 //nolint:mnd // client closed request
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,126 @@
+package grpcsrv
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	api "github.com/n-r-w/grpcsrv/example/protogen"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestConnectProtocol(t *testing.T) {
+	greeter := &testGreeter{sayHello: func(ctx context.Context, req *api.HelloRequest) (*api.HelloResponse, error) {
+		if req.GetName() == "" {
+			return nil, status.Error(codes.InvalidArgument, "name is required")
+		}
+
+		_ = grpc.SetTrailer(ctx, metadata.Pairs("x-request-cost", "1"))
+		user := metadata.ValueFromIncomingContext(ctx, "x-user")
+
+		return &api.HelloResponse{Message: "hello " + req.GetName() + " from " + strings.Join(user, ",")}, nil
+	}}
+	_, baseURL := startGatewayTestService(t, greeter, WithConnectProtocol())
+	url := baseURL + "/" + api.Greeter_ServiceDesc.ServiceName + "/SayHello"
+	jsonHeader := http.Header{"Content-Type": {"application/json"}, "X-User": {"alice"}}
+
+	t.Run("json", func(t *testing.T) {
+		resp, body := doHTTP(t, http.MethodPost, url, `{"name":"bob"}`, jsonHeader)
+		if resp.StatusCode != http.StatusOK || !strings.Contains(body, "hello bob from alice") {
+			t.Fatalf("unexpected response: %d %s", resp.StatusCode, body)
+		}
+		if resp.Header.Get("Trailer-X-Request-Cost") != "1" {
+			t.Fatalf("trailers must be sent as Trailer- headers, got %v", resp.Header)
+		}
+	})
+
+	t.Run("proto", func(t *testing.T) {
+		reqData, err := proto.Marshal(&api.HelloRequest{Name: "bob"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, body := doHTTP(t, http.MethodPost, url, string(reqData),
+			http.Header{"Content-Type": {"application/proto"}})
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/proto" {
+			t.Fatalf("unexpected response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+
+		var out api.HelloResponse
+		if err = proto.Unmarshal([]byte(body), &out); err != nil || !strings.HasPrefix(out.GetMessage(), "hello bob") {
+			t.Fatalf("unexpected proto response %q: %v", out.GetMessage(), err)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		url    string
+		body   string
+		header http.Header
+		status int
+		want   string
+	}{
+		{
+			name: "handler error", url: url, body: `{}`, header: jsonHeader,
+			status: http.StatusBadRequest, want: `"code":"invalid_argument"`,
+		},
+		{
+			name: "unsupported content type", url: url, body: `name=bob`,
+			header: http.Header{"Content-Type": {"text/plain"}}, status: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "unsupported version", url: url, body: `{"name":"bob"}`,
+			header: http.Header{"Content-Type": {"application/json"}, "Connect-Protocol-Version": {"2"}},
+			status: http.StatusBadRequest, want: "protocol version",
+		},
+		{
+			name: "invalid timeout", url: url, body: `{"name":"bob"}`,
+			header: http.Header{"Content-Type": {"application/json"}, "Connect-Timeout-Ms": {"-1"}},
+			status: http.StatusBadRequest, want: "Connect-Timeout-Ms",
+		},
+		{
+			name: "invalid body", url: url, body: `{"name":`, header: jsonHeader,
+			status: http.StatusBadRequest, want: "failed to unmarshal",
+		},
+		{
+			// streaming methods are passed to the gateway, which has no such route
+			name: "streaming method", url: baseURL + "/" + api.Greeter_ServiceDesc.ServiceName + "/SayManyHellos",
+			body: `{"name":"bob"}`, header: jsonHeader, status: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := doHTTP(t, http.MethodPost, tt.url, tt.body, tt.header)
+			if resp.StatusCode != tt.status || !strings.Contains(body, tt.want) {
+				t.Fatalf("expected %d with %q, got %d %s", tt.status, tt.want, resp.StatusCode, body)
+			}
+		})
+	}
+
+	// gateway routes are still served
+	resp, body := doHTTP(t, http.MethodPost, baseURL+"/v1/greeter:SayHello", `{"name":"bob"}`, nil)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "hello bob") {
+		t.Fatalf("unexpected gateway response: %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestConnectCode(t *testing.T) {
+	tests := map[codes.Code]string{
+		codes.NotFound:          "not_found",
+		codes.InvalidArgument:   "invalid_argument",
+		codes.DeadlineExceeded:  "deadline_exceeded",
+		codes.ResourceExhausted: "resource_exhausted",
+	}
+
+	for code, want := range tests {
+		if got := connectCode(code); got != want {
+			t.Fatalf("expected %q for %s, got %q", want, code, got)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package grpcsrv
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// setContentNegotiationHTTPMiddleware replaces Accept and Content-Type headers with the registered
+// content type of the gateway marshallers, because grpc-gateway looks for the marshaller by exact header value.
+// Accept header may contain several media types with parameters and quality values.
+func (s *Service) setContentNegotiationHTTPMiddleware(next http.Handler) http.Handler {
+	if s.gatewayMarshalerMatcher == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept, acceptOK := s.negotiateAccept(r.Header.Values("Accept"))
+		contentType, contentTypeOK := s.registeredContentType(r.Header.Get("Content-Type"))
+
+		if acceptOK || contentTypeOK {
+			r = r.Clone(r.Context())
+			if acceptOK {
+				r.Header.Set("Accept", accept)
+			}
+			if contentTypeOK {
+				r.Header.Set("Content-Type", contentType)
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// negotiateAccept returns registered content type with the highest quality from Accept header values.
+func (s *Service) negotiateAccept(values []string) (string, bool) {
+	type candidate struct {
+		contentType string
+		quality     float64
+	}
+
+	var candidates []candidate
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+
+			quality := 1.0
+			if q, ok := params["q"]; ok {
+				if quality, err = strconv.ParseFloat(q, 64); err != nil || quality <= 0 {
+					continue
+				}
+			}
+
+			if _, ok := s.httpContentTypes[mediaType]; ok {
+				candidates = append(candidates, candidate{contentType: mediaType, quality: quality})
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	return candidates[0].contentType, true
+}
+
+// registeredContentType returns media type of Content-Type header without parameters if it is registered.
+func (s *Service) registeredContentType(value string) (string, bool) {
+	if value == "" {
+		return "", false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return "", false
+	}
+
+	if _, ok := s.httpContentTypes[mediaType]; !ok {
+		return "", false
+	}
+
+	return mediaType, true
+}
@@ -0,0 +1,103 @@
+package grpcsrv
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	api "github.com/n-r-w/grpcsrv/example/protogen"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	protobufContentType = "application/x-protobuf"
+	jsonMediaType       = "application/json"
+)
+
+func protobufMatcher(contentType string) (runtime.Marshaler, bool) {
+	if contentType == protobufContentType {
+		return &runtime.ProtoMarshaller{}, true
+	}
+	return nil, false
+}
+
+func TestNegotiateAccept(t *testing.T) {
+	s := New(context.Background(), nil, WithGatewayMarshalerMatcher(protobufMatcher, protobufContentType))
+	s.httpContentTypes = map[string]struct{}{jsonMediaType: {}, protobufContentType: {}}
+
+	tests := []struct {
+		name   string
+		values []string
+		want   string
+		ok     bool
+	}{
+		{name: "single", values: []string{protobufContentType}, want: protobufContentType, ok: true},
+		{
+			name: "quality", values: []string{"application/json;q=0.5, application/x-protobuf;q=0.9"},
+			want: protobufContentType, ok: true,
+		},
+		{
+			name: "order for equal quality", values: []string{"application/json", "application/x-protobuf"},
+			want: jsonMediaType, ok: true,
+		},
+		{
+			name: "unregistered skipped", values: []string{"text/html, application/json;q=0.1"},
+			want: jsonMediaType, ok: true,
+		},
+		{name: "zero quality", values: []string{"application/x-protobuf;q=0"}},
+		{name: "invalid quality", values: []string{"application/x-protobuf;q=high"}},
+		{name: "not registered", values: []string{"text/html"}},
+		{name: "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := s.negotiateAccept(tt.values)
+			if got != tt.want || ok != tt.ok {
+				t.Fatalf("expected %q %v, got %q %v", tt.want, tt.ok, got, ok)
+			}
+		})
+	}
+}
+
+func TestContentNegotiationHTTPMiddleware(t *testing.T) {
+	s := New(context.Background(), nil, WithGatewayMarshalerMatcher(protobufMatcher, protobufContentType))
+	s.httpContentTypes = map[string]struct{}{jsonMediaType: {}, protobufContentType: {}}
+
+	var got http.Header
+	handler := s.setContentNegotiationHTTPMiddleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/items", nil)
+	r.Header.Set("Accept", "text/html, application/x-protobuf;q=0.8")
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if got.Get("Accept") != protobufContentType || got.Get("Content-Type") != jsonMediaType {
+		t.Fatalf("headers are not normalized: %v", got)
+	}
+	if r.Header.Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Fatal("original request must not be modified")
+	}
+}
+
+func TestContentNegotiationGateway(t *testing.T) {
+	_, baseURL := startGatewayTestService(t, &testGreeter{},
+		WithGatewayMarshalerMatcher(protobufMatcher, protobufContentType))
+
+	resp, body := doHTTP(t, http.MethodPost, baseURL+"/v1/greeter:SayHello", `{"name":"bob"}`, http.Header{
+		"Content-Type": {"application/json; charset=utf-8"},
+		"Accept":       {"text/html;q=0.9, application/x-protobuf"},
+	})
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != protobufContentType {
+		t.Fatalf("expected protobuf response, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var out api.HelloResponse
+	if err := proto.Unmarshal([]byte(body), &out); err != nil || out.GetMessage() != "hello bob" {
+		t.Fatalf("unexpected protobuf response %q: %v", out.GetMessage(), err)
+	}
+}
@@ -0,0 +1,125 @@
+package grpcsrv
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/n-r-w/ctxlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type correlationIDKey struct{}
+
+// CorrelationIDFromContext returns correlation ID from context.
+// Correlation ID is available only if WithCorrelationIDHeader option is set.
+func CorrelationIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(correlationIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// generates new correlation ID.
+func newCorrelationID() string {
+	const size = 16
+	b := make([]byte, size)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// maxCorrelationIDLength is the maximum length of correlation ID accepted from clients.
+const maxCorrelationIDLength = 128
+
+// checks that correlation ID from client is not too long and contains only [A-Za-z0-9._-],
+// so it is safe to log and echo in headers.
+func validCorrelationID(id string) bool {
+	if id == "" || len(id) > maxCorrelationIDLength {
+		return false
+	}
+
+	for i := range len(id) {
+		c := id[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// puts correlation ID into context and logger fields.
+func (s *Service) correlationIDToContext(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, correlationIDKey{}, id)
+	if ctxlog.InContext(ctx) {
+		ctx = ctxlog.With(ctx, "correlation-id", id)
+	}
+
+	return ctx
+}
+
+// extracts correlation ID from incoming gRPC metadata or generates a new one if it is absent or invalid.
+func (s *Service) correlationIDFromMetadata(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(s.correlationIDHeader); len(v) > 0 && validCorrelationID(v[0]) {
+			return v[0]
+		}
+	}
+
+	return newCorrelationID()
+}
+
+// interceptor for adding correlation ID to unary request context and response header.
+func (s *Service) correlationIDUnaryInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	id := s.correlationIDFromMetadata(ctx)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(s.correlationIDHeader, id))
+
+	return handler(s.correlationIDToContext(ctx, id), req)
+}
+
+// interceptor for adding correlation ID to stream context and response header.
+func (s *Service) correlationIDStreamInterceptor(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx := ss.Context()
+	id := s.correlationIDFromMetadata(ctx)
+	_ = ss.SetHeader(metadata.Pairs(s.correlationIDHeader, id))
+
+	return handler(srv, newStreamWithContext(s.correlationIDToContext(ctx, id), ss))
+}
+
+// setCorrelationIDHTTPMiddleware adds correlation ID to HTTP request context and response header.
+func (s *Service) setCorrelationIDHTTPMiddleware(next http.Handler) http.Handler {
+	if s.correlationIDHeader == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(s.correlationIDHeader)
+		if !validCorrelationID(id) {
+			id = newCorrelationID()
+		}
+		w.Header().Set(s.correlationIDHeader, id)
+
+		next.ServeHTTP(w, r.WithContext(s.correlationIDToContext(r.Context(), id)))
+	})
+}
+
+// propagateCorrelationID propagates correlation ID from grpc-gateway to grpc.
+func (s *Service) propagateCorrelationID(ctx context.Context, _ *http.Request) metadata.MD {
+	if id, ok := CorrelationIDFromContext(ctx); ok {
+		return metadata.Pairs(s.correlationIDHeader, id)
+	}
+
+	return nil
+}
+
+// normalizes header name for use as gRPC metadata key.
+func correlationIDHeaderKey(name string) string {
+	return strings.ToLower(name)
+}
@@ -0,0 +1,99 @@
+package grpcsrv
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestValidCorrelationID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{id: "abc-123_DEF.4", want: true},
+		{id: strings.Repeat("a", maxCorrelationIDLength), want: true},
+		{id: strings.Repeat("a", maxCorrelationIDLength+1), want: false},
+		{id: "", want: false},
+		{id: "with space", want: false},
+		{id: "line\nbreak", want: false},
+		{id: "quote\"", want: false},
+		{id: "юникод", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := validCorrelationID(tt.id); got != tt.want {
+			t.Errorf("%q: expected %v, got %v", tt.id, tt.want, got)
+		}
+	}
+}
+
+func TestCorrelationIDUnaryInterceptor(t *testing.T) {
+	s := New(context.Background(), nil, WithCorrelationIDHeader("X-Correlation-ID"))
+
+	tests := []struct {
+		name     string
+		id       string
+		keepSent bool
+	}{
+		{name: "valid id is kept", id: "req-1", keepSent: true},
+		{name: "invalid id is replaced", id: "bad id\r\n"},
+		{name: "too long id is replaced", id: strings.Repeat("a", maxCorrelationIDLength+1)},
+		{name: "missing id is generated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.id != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-correlation-id", tt.id))
+			}
+
+			var got string
+			_, err := s.correlationIDUnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{},
+				func(ctx context.Context, _ any) (any, error) {
+					got, _ = CorrelationIDFromContext(ctx)
+					return nil, nil
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.keepSent {
+				if got != tt.id {
+					t.Fatalf("expected %q, got %q", tt.id, got)
+				}
+				return
+			}
+			if got == tt.id || !validCorrelationID(got) {
+				t.Fatalf("expected new valid id, got %q", got)
+			}
+		})
+	}
+}
+
+func TestCorrelationIDHTTPMiddleware(t *testing.T) {
+	s := New(context.Background(), nil, WithCorrelationIDHeader("X-Correlation-ID"))
+
+	var got string
+	handler := s.setCorrelationIDHTTPMiddleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got, _ = CorrelationIDFromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Correlation-ID", "<script>")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+
+	if got == "<script>" || !validCorrelationID(got) {
+		t.Fatalf("expected new valid id, got %q", got)
+	}
+	if h := rec.Header().Get("X-Correlation-ID"); h != got {
+		t.Fatalf("expected response header %q, got %q", got, h)
+	}
+}
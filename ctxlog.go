@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/n-r-w/ctxlog"
+	"go.opentelemetry.io/otel/baggage"
 	"google.golang.org/grpc"
 )
 
@@ -53,7 +54,32 @@ func GetCtxLogOptions(ctx context.Context) ([]Option, error) {
 		return injectLoggerToContext(ctxRequest, "http", r.RequestURI, r.RemoteAddr, traceID)
 	}
 
-	opts = append(opts, WithContextModifiers(unaryRequestModifier, streamRequestModifier, httpRequestModifier))
+	// appended to modifiers set by other options, so user modifiers added later see the logger in context
+	opts = append(opts, WithAppendContextModifiers(unaryRequestModifier, streamRequestModifier, httpRequestModifier))
 
 	return opts, nil
 }
+
+// enrichLogger adds static fields from loggerFields and baggage members from baggageLogKeys
+// to ctxlog logger fields.
+func (s *Service) enrichLogger(ctx context.Context) context.Context {
+	if (len(s.loggerFields) == 0 && len(s.baggageLogKeys) == 0) || !ctxlog.InContext(ctx) {
+		return ctx
+	}
+
+	attrs := make([]any, 0, len(s.loggerFields)+len(s.baggageLogKeys)*2) //nolint:mnd // ok
+	attrs = append(attrs, s.loggerFields...)
+
+	bag := baggage.FromContext(ctx)
+	for _, key := range s.baggageLogKeys {
+		if member := bag.Member(key); member.Key() != "" {
+			attrs = append(attrs, key, member.Value())
+		}
+	}
+
+	if len(attrs) == 0 {
+		return ctx
+	}
+
+	return ctxlog.With(ctx, attrs...)
+}
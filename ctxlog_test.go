@@ -0,0 +1,100 @@
+package grpcsrv
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/n-r-w/ctxlog"
+	"go.opentelemetry.io/otel/baggage"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+)
+
+// testCtxLog returns context with ctxlog logger, which writes to the buffer.
+func testCtxLog(t *testing.T) (context.Context, *zaptest.Buffer) {
+	t.Helper()
+
+	buf := &zaptest.Buffer{}
+	return ctxlog.ToContext(context.Background(), ctxlog.Must(ctxlog.WithTesting(t), ctxlog.WithTestBuffer(buf))), buf
+}
+
+func TestEnrichLoggerBaggage(t *testing.T) {
+	ctx, buf := testCtxLog(t)
+
+	member, err := baggage.NewMember("tenant", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bag, err := baggage.New(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx = baggage.ContextWithBaggage(ctx, bag)
+
+	s := New(context.Background(), nil, WithBaggageLogKeys("tenant", "missing"))
+	ctxlog.Info(s.enrichLogger(ctx), "request")
+
+	out := buf.String()
+	if !strings.Contains(out, "tenant") || !strings.Contains(out, "acme") {
+		t.Fatalf("baggage member is not logged: %q", out)
+	}
+	if strings.Contains(out, "missing") {
+		t.Fatalf("absent baggage member must not be logged: %q", out)
+	}
+}
+
+func TestEnrichLoggerWithoutCtxLog(t *testing.T) {
+	s := New(context.Background(), nil, WithBaggageLogKeys("tenant"))
+
+	ctx := context.Background()
+	if got := s.enrichLogger(ctx); got != ctx {
+		t.Fatal("context without ctxlog logger must not be changed")
+	}
+}
+
+func TestEnrichLoggerFields(t *testing.T) {
+	ctx, buf := testCtxLog(t)
+
+	s := New(context.Background(), nil, WithLoggerFields("version", "1.2.3"), WithLoggerFields("region", "eu"))
+	ctxlog.Info(s.enrichLogger(ctx), "request")
+
+	out := buf.String()
+	for _, want := range []string{"version", "1.2.3", "region", "eu"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in log output: %q", want, out)
+		}
+	}
+}
+
+func TestContextModifiersAppend(t *testing.T) {
+	ctx, _ := testCtxLog(t)
+
+	ctxLogOpts, err := GetCtxLogOptions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loggerInContext bool
+	checkLogger := func(ctx context.Context, _ any, _ *grpc.UnaryServerInfo, _ grpc.UnaryHandler, _, _ string,
+	) context.Context {
+		loggerInContext = ctxlog.InContext(ctx)
+		return ctx
+	}
+
+	// modifiers set before and after ctxlog options are kept
+	opts := []Option{WithContextModifiers(orderUnaryModifier("before"), nil, orderHTTPModifier("before"))}
+	opts = append(opts, ctxLogOpts...)
+	opts = append(opts,
+		WithContextModifiers(checkLogger, nil, nil),
+		WithContextModifiers(orderUnaryModifier("after"), nil, orderHTTPModifier("after")))
+
+	unary, httpMods := appliedModifiers(t, New(context.Background(), nil, opts...))
+	if !slices.Equal(unary, []string{"before", "after"}) || !slices.Equal(httpMods, []string{"before", "after"}) {
+		t.Fatalf("modifiers must be appended, got unary %v, HTTP %v", unary, httpMods)
+	}
+	if !loggerInContext {
+		t.Fatal("modifier added after ctxlog options must see the logger in context")
+	}
+}
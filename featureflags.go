@@ -0,0 +1,77 @@
+package grpcsrv
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// FeatureFlags request-scoped feature flags: flag name (without prefix) -> value.
+type FeatureFlags map[string]string
+
+// Enabled returns true if the flag is set to "on", "true" or "1".
+func (f FeatureFlags) Enabled(name string) bool {
+	switch strings.ToLower(f[strings.ToLower(name)]) {
+	case "on", "true", "1":
+		return true
+	default:
+		return false
+	}
+}
+
+type featureFlagsKey struct{}
+
+// FeatureFlagsFromContext returns request-scoped feature flags from context.
+// Feature flags are available only if WithFeatureFlagMetadata option is set.
+func FeatureFlagsFromContext(ctx context.Context) FeatureFlags {
+	flags, _ := ctx.Value(featureFlagsKey{}).(FeatureFlags)
+	return flags
+}
+
+// collects feature flags from incoming metadata into context. Flags not in the allowlist are ignored.
+func (s *Service) featureFlagsToContext(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	var flags FeatureFlags
+	for key, vals := range md {
+		name, found := strings.CutPrefix(key, s.featureFlagPrefix)
+		if !found || name == "" || len(vals) == 0 {
+			continue
+		}
+		if s.featureFlagAllowed != nil {
+			if _, ok := s.featureFlagAllowed[name]; !ok {
+				continue
+			}
+		}
+
+		if flags == nil {
+			flags = make(FeatureFlags)
+		}
+		flags[name] = vals[0]
+	}
+
+	if flags == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, featureFlagsKey{}, flags)
+}
+
+// interceptor for adding feature flags to unary request context.
+func (s *Service) featureFlagsUnaryInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	return handler(s.featureFlagsToContext(ctx), req)
+}
+
+// interceptor for adding feature flags to stream context.
+func (s *Service) featureFlagsStreamInterceptor(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	return handler(srv, newStreamWithContext(s.featureFlagsToContext(ss.Context()), ss))
+}
@@ -0,0 +1,57 @@
+package grpcsrv
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestFeatureFlagsUnaryInterceptor(t *testing.T) {
+	md := metadata.Pairs(
+		"x-feature-newalgo", "on",
+		"x-feature-admin", "true",
+		"x-other", "1",
+	)
+
+	tests := []struct {
+		name string
+		opt  Option
+		want map[string]bool
+	}{
+		{
+			name: "all flags accepted",
+			opt:  WithFeatureFlagMetadata("X-Feature-"),
+			want: map[string]bool{"newalgo": true, "admin": true, "other": false},
+		},
+		{
+			name: "allowlist",
+			opt:  WithFeatureFlagMetadata("x-feature-", "NewAlgo"),
+			want: map[string]bool{"newalgo": true, "admin": false, "other": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(context.Background(), nil, tt.opt)
+			ctx := metadata.NewIncomingContext(context.Background(), md)
+
+			var flags FeatureFlags
+			_, err := s.featureFlagsUnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{},
+				func(ctx context.Context, _ any) (any, error) {
+					flags = FeatureFlagsFromContext(ctx)
+					return nil, nil
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for name, want := range tt.want {
+				if got := flags.Enabled(name); got != want {
+					t.Fatalf("flag %s: expected %v, got %v", name, want, got)
+				}
+			}
+		})
+	}
+}
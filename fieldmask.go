@@ -0,0 +1,84 @@
+package grpcsrv
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// fieldMaskValidationInterceptor checks that field mask paths in request are valid for the target message.
+// Target message is the only non-FieldMask message field of the request (e.g. resource in Update requests).
+// If there is no such field or there are several of them, the request message itself is used.
+func (s *Service) fieldMaskValidationInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if _, ok := s.fieldMaskMethods[info.FullMethod]; !ok {
+		return handler(ctx, req)
+	}
+
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	if err := validateFieldMasks(msg.ProtoReflect()); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// validateFieldMasks validates all FieldMask fields of the message.
+func validateFieldMasks(m protoreflect.Message) error {
+	var (
+		masks   []*fieldmaskpb.FieldMask
+		targets []proto.Message
+	)
+
+	fields := m.Descriptor().Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		if fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+			continue
+		}
+
+		if fd.Message().FullName() == fieldMaskFullName {
+			if !m.Has(fd) {
+				continue
+			}
+
+			if mask, ok := m.Get(fd).Message().Interface().(*fieldmaskpb.FieldMask); ok {
+				masks = append(masks, mask)
+			}
+			continue
+		}
+
+		targets = append(targets, m.Get(fd).Message().Interface())
+	}
+
+	if len(masks) == 0 {
+		return nil
+	}
+
+	target := m.Interface()
+	if len(targets) == 1 {
+		target = targets[0]
+	}
+
+	for _, mask := range masks {
+		if !mask.IsValid(target) {
+			return status.Errorf(codes.InvalidArgument, "invalid field mask paths: %s",
+				strings.Join(mask.GetPaths(), ","))
+		}
+	}
+
+	return nil
+}
+
+const fieldMaskFullName protoreflect.FullName = "google.protobuf.FieldMask"
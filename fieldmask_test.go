@@ -0,0 +1,110 @@
+package grpcsrv
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// testUpdateRequestDescriptor returns descriptor of
+// message UpdateRequest { Resource resource = 1; google.protobuf.FieldMask update_mask = 2; },
+// message Resource { string name = 1; int32 size = 2; }.
+func testUpdateRequestDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("grpcsrv_fieldmask_test.proto"),
+		Package:    proto.String("grpcsrv.test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/field_mask.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Resource"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name: proto.String("name"), Number: proto.Int32(1), JsonName: proto.String("name"),
+						Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:  descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					},
+					{
+						Name: proto.String("size"), Number: proto.Int32(2), JsonName: proto.String("size"),
+						Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:  descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+					},
+				},
+			},
+			{
+				Name: proto.String("UpdateRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name: proto.String("resource"), Number: proto.Int32(1), JsonName: proto.String("resource"),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".grpcsrv.test.Resource"),
+					},
+					{
+						Name: proto.String("update_mask"), Number: proto.Int32(2), JsonName: proto.String("updateMask"),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".google.protobuf.FieldMask"),
+					},
+				},
+			},
+		},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fd.Messages().ByName("UpdateRequest")
+}
+
+func TestFieldMaskValidationInterceptor(t *testing.T) {
+	const method = "/test.Service/Update"
+	desc := testUpdateRequestDescriptor(t)
+	maskField := desc.Fields().ByName("update_mask")
+
+	newRequest := func(paths ...string) proto.Message {
+		req := dynamicpb.NewMessage(desc)
+		if paths != nil {
+			req.Set(maskField, protoreflect.ValueOfMessage((&fieldmaskpb.FieldMask{Paths: paths}).ProtoReflect()))
+		}
+		return req
+	}
+
+	s := New(context.Background(), nil, WithFieldMaskValidation(method))
+	handler := func(context.Context, any) (any, error) { return nil, nil }
+
+	tests := []struct {
+		name   string
+		method string
+		req    any
+		code   codes.Code
+	}{
+		{name: "valid paths", method: method, req: newRequest("name", "size"), code: codes.OK},
+		{name: "invalid path", method: method, req: newRequest("name", "unknown"), code: codes.InvalidArgument},
+		{name: "no mask", method: method, req: newRequest(), code: codes.OK},
+		{name: "not configured method", method: "/test.Service/Other", req: newRequest("unknown"), code: codes.OK},
+		{name: "not a proto message", method: method, req: "request", code: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.fieldMaskValidationInterceptor(context.Background(), tt.req,
+				&grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if status.Code(err) != tt.code {
+				t.Fatalf("expected %s, got %v", tt.code, err)
+			}
+		})
+	}
+}
@@ -2,8 +2,12 @@ package grpcsrv
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"mime"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
@@ -12,39 +16,106 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
 
 // propagateTraceContext propagate trace from grpc-gateway to grpc. Without this magic, it doesn't work.
-func propagateTraceContext(ctx context.Context, _ *http.Request) metadata.MD {
+func (s *Service) propagateTraceContext(ctx context.Context, _ *http.Request) metadata.MD {
 	carrier := propagation.MapCarrier{}
-	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	s.getPropagator().Inject(ctx, carrier)
 	return metadata.New(carrier)
 }
 
+// getPropagator returns propagator set by WithPropagator or the global one.
+func (s *Service) getPropagator() propagation.TextMapPropagator {
+	if s.propagator != nil {
+		return s.propagator
+	}
+
+	return otel.GetTextMapPropagator()
+}
+
 func (s *Service) startHTTPGateway(ctx context.Context) error {
+	if s.lazyGateway {
+		handler, err := newLazyGatewayHandler(context.WithoutCancel(ctx), s)
+		if err != nil {
+			return err
+		}
+		s.httpHandler = handler
+	} else {
+		handler, err := s.buildHTTPHandler(ctx)
+		if err != nil {
+			return err
+		}
+		s.httpHandler = handler
+	}
+
+	s.httpMu.Lock()
+	defer s.httpMu.Unlock()
+
+	return s.serveHTTPGateway(ctx, s.endpoint.HTTP)
+}
+
+// buildHTTPHandler creates gRPC gateway multiplexer, connection to gRPC server and HTTP middlewares.
+func (s *Service) buildHTTPHandler(ctx context.Context) (http.Handler, error) {
 	muxOptList := []runtime.ServeMuxOption{
-		runtime.WithMetadata(propagateTraceContext),
+		runtime.WithErrorHandler(s.httpErrorHandler),
+	}
+
+	if s.httpRoutingErrorHandler != nil {
+		muxOptList = append(muxOptList, runtime.WithRoutingErrorHandler(s.httpRoutingErrorHandler))
+	} else {
+		muxOptList = append(muxOptList, runtime.WithRoutingErrorHandler(s.routingErrorHandler))
 	}
 
-	if len(s.httpHeadersFromMetadata) > 0 {
-		muxOptList = append(muxOptList, runtime.WithForwardResponseOption(s.responseHTTPHeaderMatcher))
+	if !s.withoutOTel {
+		muxOptList = append(muxOptList, runtime.WithMetadata(s.propagateTraceContext))
+	}
+
+	muxOptList = append(muxOptList, runtime.WithForwardResponseOption(s.responseHTTPHeaderMatcher))
+
+	for _, modifier := range s.gatewayResponseModifiers {
+		muxOptList = append(muxOptList, runtime.WithForwardResponseOption(modifier))
+	}
+
+	if len(s.streamJSONArrayMethods) > 0 {
+		muxOptList = append(muxOptList, runtime.WithForwardResponseOption(s.streamJSONArrayModifier))
+	}
+
+	if s.gatewayResponseRewriter != nil {
+		muxOptList = append(muxOptList, runtime.WithForwardResponseRewriter(s.gatewayResponseRewriter))
+	}
+
+	if s.correlationIDHeader != "" {
+		muxOptList = append(muxOptList, runtime.WithMetadata(s.propagateCorrelationID))
+	}
+
+	if s.peerLimiter != nil {
+		muxOptList = append(muxOptList, runtime.WithMetadata(s.propagateGatewayPeer))
 	}
 
 	// Whether to use default JSON marshaller
 	jsonMarshallers, err := s.getJSONMarshallers()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	muxOptList = append(muxOptList, jsonMarshallers...)
 
 	var dialOpts []grpc.DialOption
 
 	// telemetry
-	dialOpts = append(dialOpts, grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
+	if !s.withoutOTel {
+		dialOpts = append(dialOpts, grpc.WithStatsHandler(
+			otelgrpc.NewClientHandler(
+				otelgrpc.WithTracerProvider(s.getTracerProvider()),
+				otelgrpc.WithPropagators(s.getPropagator()),
+			)))
+	}
 
 	if len(s.httpDialOptions) > 0 {
 		dialOpts = append(dialOpts, s.httpDialOptions...)
@@ -52,12 +123,26 @@ func (s *Service) startHTTPGateway(ctx context.Context) error {
 		dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	}
 
+	// buffer sizes
+	dialOpts = append(dialOpts, s.httpDialBufferOptions...)
+
+	// gateway to backend latency
+	if s.metricsEndpoint != "" {
+		dialOpts = append(dialOpts,
+			grpc.WithChainUnaryInterceptor(s.gatewayBackendUnaryInterceptor),
+			grpc.WithChainStreamInterceptor(s.gatewayBackendStreamInterceptor),
+		)
+	}
+
+	target, targetOpts := s.gatewayTarget()
+	dialOpts = append(dialOpts, targetOpts...)
+
 	// Create gRPC client for gRPC gateway
-	conn, err := grpc.NewClient(s.endpoint.GRPC, dialOpts...)
+	conn, err := grpc.NewClient(target, dialOpts...)
 	if err != nil {
-		return fmt.Errorf("grpc gateway: failed to create grpc client: %w", err)
+		return nil, fmt.Errorf("grpc gateway: failed to create grpc client: %w", err)
 	}
-	s.grpcGatewayConn = conn
+	s.setGatewayConn(conn)
 
 	// Create gRPC multiplexer for gRPC gateway
 	mux := runtime.NewServeMux(muxOptList...)
@@ -66,52 +151,103 @@ func (s *Service) startHTTPGateway(ctx context.Context) error {
 	for _, i := range s.grpcInitializers {
 		if i.GetOptions().HTTPHandlerRequired {
 			if err = i.RegisterHTTPHandler(ctx, mux, conn); err != nil {
-				return fmt.Errorf("%s. failed to register gRPC gateway: %w", s.name, err)
+				return nil, fmt.Errorf("%s. failed to register gRPC gateway: %w", s.name, err)
+			}
+		}
+
+		if r, ok := i.(IExtraHTTPRegistrar); ok {
+			if err = r.RegisterExtraHTTP(mux); err != nil {
+				return nil, fmt.Errorf("%s. failed to register extra HTTP routes: %w", s.name, err)
 			}
 		}
 	}
 
-	var targetHandlers http.Handler = mux
+	chain := s.newHTTPChain(mux)
+
+	// Server streams as JSON arrays
+	chain.use("stream-json-array", len(s.streamJSONArrayMethods) > 0, s.setStreamJSONArrayHTTPMiddleware)
+
+	// HEAD requests are served by GET routes
+	chain.use("head", true, setHeadHTTPMiddleware)
+
+	// Content negotiation for marshallers selected by the matcher
+	chain.use("content-negotiation", s.gatewayMarshalerMatcher != nil, s.setContentNegotiationHTTPMiddleware)
+
+	// Connect protocol clients are served alongside the gateway
+	chain.use("connect", s.connectProtocol, func(next http.Handler) http.Handler {
+		return s.setConnectHTTPMiddleware(ctx, next, conn)
+	})
+
+	// Mount gateway under path prefix. Health check and additional endpoints are registered in mux,
+	// so they are served under the prefix as well.
+	chain.use("strip-prefix", s.httpPathPrefix != "", func(next http.Handler) http.Handler {
+		return http.StripPrefix(s.httpPathPrefix, next)
+	})
 
 	// Panic recovery support
-	if s.recoverEnabled {
-		targetHandlers = s.recoverHTTP(targetHandlers)
-	}
+	chain.use("recover", s.recoverEnabled, s.recoverHTTP)
+
+	// Response compression
+	chain.use("gzip", s.httpGzipMinSize >= 0, s.setGzipHTTPMiddleware)
+
+	// Limit of concurrent requests
+	chain.use("concurrency-limit", s.httpConcurrencyLimit > 0, s.setConcurrencyLimitHTTPMiddleware)
 
 	// Support for logging, tracing and metrics
-	targetHandlers = s.setTraceRouteHTTPMiddleware(targetHandlers)
-	targetHandlers = s.setCtxModifierHTTPMiddleware(targetHandlers)
-	targetHandlers = s.setCORSMiddleware(targetHandlers)
+	chain.use("trace-route", true, s.setTraceRouteHTTPMiddleware)
+	chain.use("correlation-id", s.correlationIDHeader != "", s.setCorrelationIDHTTPMiddleware)
+	chain.use("ctx-modifier", true, s.setCtxModifierHTTPMiddleware)
+	chain.use("cors", s.corsOptions.IsSome(), s.setCORSMiddleware)
 
 	// Health check support
 	if err = s.registerHealthCheckEndpoints(ctx, mux); err != nil {
-		return err
+		return nil, err
+	}
+
+	if err = s.registerGatewayHealthForwarding(ctx, mux, conn); err != nil {
+		return nil, err
 	}
 
 	// Register additional HTTP endpoints
 	if err = s.registerHTTPEndpoints(ctx, mux); err != nil {
-		return err
+		return nil, err
 	}
 
 	// add tracing support to grpc-gateway
-	grpcgw := otelhttp.NewMiddleware("grpc-gateway", otelhttp.WithFilter(
-		func(r *http.Request) bool {
-			// ignore requests from prometheus otherwise they spam
-			return r.URL.Path != "/metrics"
-		},
-	))
+	chain.use("otel", !s.withoutOTel, otelhttp.NewMiddleware("grpc-gateway",
+		otelhttp.WithTracerProvider(s.getTracerProvider()),
+		otelhttp.WithPropagators(s.getPropagator()),
+		otelhttp.WithFilter(
+			func(r *http.Request) bool {
+				// ignore requests from prometheus otherwise they spam
+				return r.URL.Path != "/metrics"
+			},
+		)))
+
+	return chain.handler(ctx), nil
+}
+
+// serveHTTPGateway starts HTTP server of the gateway on the endpoint. Must be called with httpMu locked.
+func (s *Service) serveHTTPGateway(ctx context.Context, endpoint string) error {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return fmt.Errorf("%s. failed to start HTTP gateway listener: %w", s.name, err)
+	}
+	listener = newLimitListener(ctx, listener, s.httpMaxConnections, s.logger, "http")
 
 	// Start HTTP server
-	s.httpServer = &http.Server{
-		Addr:              s.endpoint.HTTP,
-		Handler:           grpcgw(targetHandlers),
+	server := &http.Server{
+		Addr:              endpoint,
+		Handler:           s.httpHandler,
 		ReadHeaderTimeout: s.httpReadHeaderTimeout,
+		MaxHeaderBytes:    s.httpMaxHeaderBytes,
 	}
+	s.httpServer = server
 
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		if errListener := s.httpServer.ListenAndServe(); errListener != nil && errListener != http.ErrServerClosed {
+		if errListener := server.Serve(listener); errListener != nil && errListener != http.ErrServerClosed {
 			panic(s.name + ". failed to listen and serve HTTP server: " + errListener.Error())
 		}
 	}()
@@ -119,41 +255,117 @@ func (s *Service) startHTTPGateway(ctx context.Context) error {
 	return nil
 }
 
+// RestartHTTPGateway gracefully shuts down HTTP gateway server and starts it on the new endpoint.
+// gRPC server and the gateway connection to it are not affected.
+// If the gateway can't be started on the new endpoint, it is restarted on the previous one.
+// If in-flight requests are not finished until ctx is done, their connections are closed.
+func (s *Service) RestartHTTPGateway(ctx context.Context, endpoint string) error {
+	s.httpMu.Lock()
+	defer s.httpMu.Unlock()
+
+	if s.httpServer == nil {
+		return fmt.Errorf("%s. HTTP gateway is not started", s.name)
+	}
+
+	s.logger.Info(ctx, "restarting http gateway", "old", s.endpoint.HTTP, "new", endpoint)
+
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		// the gateway must not stay down: drop the remaining connections and continue the restart
+		s.logger.Warn(ctx, "failed to stop http gateway gracefully, closing connections", "error", err)
+		_ = s.httpServer.Close()
+	}
+
+	serveCtx := context.WithoutCancel(ctx)
+	if err := s.serveHTTPGateway(serveCtx, endpoint); err != nil {
+		if errRestore := s.serveHTTPGateway(serveCtx, s.endpoint.HTTP); errRestore != nil {
+			return errors.Join(err, errRestore)
+		}
+		return err
+	}
+	s.endpoint.HTTP = endpoint
+
+	s.logger.Info(ctx, "http gateway restarted", "http", endpoint)
+
+	return nil
+}
+
 // get marshallers for gRPC gateway.
-func (s *Service) getJSONMarshallers() ([]runtime.ServeMuxOption, error) { //nolint:unparam // ok
+func (s *Service) getJSONMarshallers() ([]runtime.ServeMuxOption, error) {
 	var marshallers []runtime.ServeMuxOption
 
 	needDefaultJSONMarshaller := true
 	const (
 		jsonContentType = "application/json"
 	)
-	if len(s.httpMarshallers) > 0 {
-		for contentType, marshaler := range s.httpMarshallers {
-			marshallers = append(marshallers, runtime.WithMarshalerOption(contentType, marshaler))
+	normalized := make(map[string]string, len(s.httpMarshallers)) // normalized -> original
+	for contentType, marshaler := range s.httpMarshallers {
+		key := strings.ToLower(strings.TrimSpace(contentType))
+		if key == "" {
+			return nil, fmt.Errorf("%s. empty content-type in HTTP marshallers", s.name)
 		}
+		if key != runtime.MIMEWildcard {
+			if _, _, err := mime.ParseMediaType(key); err != nil {
+				return nil, fmt.Errorf("%s. invalid content-type %q in HTTP marshallers: %w", s.name, contentType, err)
+			}
+		}
+		if prev, ok := normalized[key]; ok {
+			return nil, fmt.Errorf("%s. duplicate content-type in HTTP marshallers: %q and %q",
+				s.name, prev, contentType)
+		}
+		normalized[key] = contentType
+
+		marshallers = append(marshallers, runtime.WithMarshalerOption(contentType, marshaler))
+	}
 
-		if _, ok := s.httpMarshallers[jsonContentType]; ok {
-			needDefaultJSONMarshaller = false
+	// marshallers selected by the matcher
+	if s.gatewayMarshalerMatcher != nil {
+		for _, contentType := range s.gatewayMatcherContentTypes {
+			key := strings.ToLower(strings.TrimSpace(contentType))
+			if _, ok := normalized[key]; ok {
+				continue // explicitly set by WithHTTPMarshallers
+			}
+
+			if marshaler, ok := s.gatewayMarshalerMatcher(key); ok {
+				normalized[key] = contentType
+				marshallers = append(marshallers, runtime.WithMarshalerOption(key, marshaler))
+			}
 		}
 	}
 
+	if _, ok := normalized[jsonContentType]; ok {
+		needDefaultJSONMarshaller = false
+	}
+
+	s.httpContentTypes = make(map[string]struct{}, len(normalized)+1)
+	for key := range normalized {
+		s.httpContentTypes[key] = struct{}{}
+	}
+	s.httpContentTypes[jsonContentType] = struct{}{}
+
 	if needDefaultJSONMarshaller {
-		marshallers = append(marshallers,
-			runtime.WithMarshalerOption(jsonContentType,
-				&runtime.JSONPb{
-					MarshalOptions: protojson.MarshalOptions{
-						UseEnumNumbers:    false,
-						AllowPartial:      false,
-						EmitUnpopulated:   true,
-						EmitDefaultValues: false,
-					},
-					UnmarshalOptions: protojson.UnmarshalOptions{
-						DiscardUnknown: false,
-						AllowPartial:   false,
-					},
-				},
-			),
-		)
+		jsonMarshaller := &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{
+				UseEnumNumbers:    false,
+				AllowPartial:      false,
+				EmitUnpopulated:   true,
+				EmitDefaultValues: false,
+			},
+			UnmarshalOptions: protojson.UnmarshalOptions{
+				DiscardUnknown: false,
+				AllowPartial:   false,
+			},
+		}
+
+		var marshaler runtime.Marshaler = jsonMarshaller
+		if s.httpStrictJSON || s.httpCaseInsensitiveEnums {
+			marshaler = &customJSONPb{
+				JSONPb:               jsonMarshaller,
+				strict:               s.httpStrictJSON,
+				caseInsensitiveEnums: s.httpCaseInsensitiveEnums,
+			}
+		}
+
+		marshallers = append(marshallers, runtime.WithMarshalerOption(jsonContentType, marshaler))
 	}
 
 	return marshallers, nil
@@ -172,5 +384,99 @@ func (s *Service) responseHTTPHeaderMatcher(ctx context.Context, w http.Response
 		}
 	}
 
+	setRetryAfterHeader(w, md)
+
+	if s.serverTimeHeader {
+		if vals := md.TrailerMD.Get(ServerTimeKey); len(vals) > 0 {
+			w.Header().Set(ServerTimeKey, vals[0])
+		}
+	}
+
+	if s.grpcStatusHeader {
+		setGRPCStatusHeaders(w, status.New(codes.OK, ""))
+	}
+
 	return nil
 }
+
+// setGRPCStatusHeaders sets HTTP headers with gRPC status code and message.
+func setGRPCStatusHeaders(w http.ResponseWriter, st *status.Status) {
+	w.Header().Set(GRPCStatusCodeHeader, strconv.Itoa(int(st.Code())))
+	if st.Message() != "" {
+		w.Header().Set(GRPCStatusMessageHeader, st.Message())
+	}
+}
+
+// setRetryAfterHeader sets Retry-After HTTP header if handler has set retry-after metadata.
+func setRetryAfterHeader(w http.ResponseWriter, md runtime.ServerMetadata) {
+	vals := md.TrailerMD.Get(RetryAfterKey)
+	if len(vals) == 0 {
+		vals = md.HeaderMD.Get(RetryAfterKey)
+	}
+
+	if len(vals) > 0 {
+		w.Header().Set("Retry-After", vals[0])
+	}
+}
+
+// httpErrorHandler handles gRPC errors in gateway, applying custom HTTP status mapping.
+func (s *Service) httpErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler,
+	w http.ResponseWriter, r *http.Request, err error,
+) {
+	if s.grpcStatusHeader {
+		setGRPCStatusHeaders(w, status.Convert(err))
+	}
+
+	if st, ok := status.FromError(err); ok {
+		if st.Code() == codes.ResourceExhausted {
+			if md, ok := runtime.ServerMetadataFromContext(ctx); ok {
+				setRetryAfterHeader(w, md)
+			}
+		}
+
+		if httpStatus, ok := s.httpStatusMapping[st.Code()]; ok {
+			w = &statusOverrideWriter{ResponseWriter: w, status: httpStatus}
+		}
+	}
+
+	runtime.DefaultHTTPErrorHandler(ctx, mux, marshaler, w, r, err)
+}
+
+// routingErrorHandler handles requests that don't match any gateway route.
+// Returns error in the same JSON format as gRPC errors with 404 or 405 HTTP status instead of 501 for wrong methods.
+func (s *Service) routingErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler,
+	w http.ResponseWriter, r *http.Request, httpStatus int,
+) {
+	s.logger.Debug(ctx, "http gateway routing error",
+		"http_method", r.Method, "path", r.URL.Path, "status", httpStatus)
+
+	var err error
+	switch httpStatus {
+	case http.StatusNotFound:
+		err = status.Errorf(codes.NotFound, "no route for %s %s", r.Method, r.URL.Path)
+	case http.StatusMethodNotAllowed:
+		err = status.Errorf(codes.Unimplemented, "method %s is not allowed for %s", r.Method, r.URL.Path)
+	case http.StatusBadRequest:
+		err = status.Errorf(codes.InvalidArgument, "bad request %s %s", r.Method, r.URL.Path)
+	default:
+		err = status.Errorf(codes.Internal, "routing error %d for %s %s", httpStatus, r.Method, r.URL.Path)
+	}
+
+	runtime.DefaultHTTPErrorHandler(ctx, mux, marshaler, &statusOverrideWriter{ResponseWriter: w, status: httpStatus}, r, err)
+}
+
+// statusOverrideWriter replaces HTTP status code written by the wrapped handler.
+type statusOverrideWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader sends an HTTP response header with the overridden status code.
+func (w *statusOverrideWriter) WriteHeader(_ int) {
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// Unwrap returns the original http.ResponseWriter.
+func (w *statusOverrideWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
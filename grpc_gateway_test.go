@@ -0,0 +1,622 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	api "github.com/n-r-w/grpcsrv/example/protogen"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// testGreeter api.GreeterServer for tests.
+type testGreeter struct {
+	api.UnimplementedGreeterServer
+
+	sayHello func(ctx context.Context, req *api.HelloRequest) (*api.HelloResponse, error)
+	count    int // number of messages sent by SayManyHellos
+}
+
+func (g *testGreeter) SayHello(ctx context.Context, req *api.HelloRequest) (*api.HelloResponse, error) {
+	if g.sayHello != nil {
+		return g.sayHello(ctx, req)
+	}
+
+	return &api.HelloResponse{Message: "hello " + req.GetName()}, nil
+}
+
+func (g *testGreeter) SayManyHellos(req *api.HelloRequest, stream grpc.ServerStreamingServer[api.HelloResponse]) error {
+	for i := range g.count {
+		if err := stream.Send(&api.HelloResponse{Message: fmt.Sprintf("hello %s %d", req.GetName(), i)}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// greeterInitializer IGRPCInitializer, which registers testGreeter with HTTP gateway.
+type greeterInitializer struct {
+	greeter *testGreeter
+}
+
+func (i *greeterInitializer) RegisterGRPCServer(s *grpc.Server) {
+	api.RegisterGreeterServer(s, i.greeter)
+}
+
+func (i *greeterInitializer) RegisterHTTPHandler(ctx context.Context, mux *runtime.ServeMux,
+	conn *grpc.ClientConn,
+) error {
+	return api.RegisterGreeterHandler(ctx, mux, conn)
+}
+
+func (i *greeterInitializer) GetOptions() InitializeOptions {
+	return InitializeOptions{HTTPHandlerRequired: true}
+}
+
+// startGatewayTestService starts service with testGreeter and HTTP gateway on free ports.
+// Returns base URL of the gateway.
+func startGatewayTestService(t *testing.T, greeter *testGreeter, opts ...Option) (*Service, string) {
+	t.Helper()
+
+	httpAddr := freeAddr(t)
+	opts = append([]Option{WithEndpoint(Endpoint{GRPC: freeAddr(t), HTTP: httpAddr})}, opts...)
+	s := startTestServiceWith(t, []IGRPCInitializer{&greeterInitializer{greeter: greeter}}, opts...)
+
+	return s, "http://" + httpAddr
+}
+
+// doHTTP sends HTTP request and returns response with its body.
+func doHTTP(t *testing.T, method, url, body string, header http.Header) (*http.Response, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body)) //nolint:noctx // test
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp, string(b)
+}
+
+// serveGatewayError calls httpErrorHandler and returns the response.
+func serveGatewayError(ctx context.Context, s *Service, err error) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.httpErrorHandler(ctx, runtime.NewServeMux(), &runtime.JSONPb{}, rec,
+		httptest.NewRequest(http.MethodGet, "/v1/test", nil), err)
+
+	return rec
+}
+
+func TestHTTPStatusMapping(t *testing.T) {
+	s := New(context.Background(), nil, WithHTTPStatusMapping(map[codes.Code]int{
+		codes.FailedPrecondition: http.StatusConflict,
+	}))
+
+	tests := []struct {
+		code codes.Code
+		want int
+	}{
+		{code: codes.FailedPrecondition, want: http.StatusConflict},
+		{code: codes.NotFound, want: http.StatusNotFound},
+		{code: codes.Internal, want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			rec := serveGatewayError(context.Background(), s, status.Error(tt.code, "error"))
+			if rec.Code != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	s := New(context.Background(), nil)
+
+	tests := []struct {
+		name string
+		md   runtime.ServerMetadata
+		code codes.Code
+		want string
+	}{
+		{
+			name: "from trailer",
+			md:   runtime.ServerMetadata{TrailerMD: metadata.Pairs(RetryAfterKey, "5")},
+			code: codes.ResourceExhausted,
+			want: "5",
+		},
+		{
+			name: "from header",
+			md:   runtime.ServerMetadata{HeaderMD: metadata.Pairs(RetryAfterKey, "7")},
+			code: codes.ResourceExhausted,
+			want: "7",
+		},
+		{
+			name: "trailer takes precedence",
+			md: runtime.ServerMetadata{
+				HeaderMD:  metadata.Pairs(RetryAfterKey, "7"),
+				TrailerMD: metadata.Pairs(RetryAfterKey, "5"),
+			},
+			code: codes.ResourceExhausted,
+			want: "5",
+		},
+		{
+			name: "other codes are ignored",
+			md:   runtime.ServerMetadata{TrailerMD: metadata.Pairs(RetryAfterKey, "5")},
+			code: codes.Unavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := runtime.NewServerMetadataContext(context.Background(), tt.md)
+			rec := serveGatewayError(ctx, s, status.Error(tt.code, "error"))
+
+			if got := rec.Header().Get("Retry-After"); got != tt.want {
+				t.Fatalf("expected Retry-After %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHTTPMarshallersValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		marshallers map[string]runtime.Marshaler
+		wantErr     bool
+	}{
+		{name: "default"},
+		{name: "valid", marshallers: map[string]runtime.Marshaler{"application/x-test": &runtime.JSONPb{}}},
+		{name: "wildcard", marshallers: map[string]runtime.Marshaler{runtime.MIMEWildcard: &runtime.JSONPb{}}},
+		{name: "empty", marshallers: map[string]runtime.Marshaler{" ": &runtime.JSONPb{}}, wantErr: true},
+		{name: "invalid", marshallers: map[string]runtime.Marshaler{"application/": &runtime.JSONPb{}}, wantErr: true},
+		{
+			name: "duplicate after normalization",
+			marshallers: map[string]runtime.Marshaler{
+				"application/json":   &runtime.JSONPb{},
+				" Application/JSON ": &runtime.JSONPb{},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(context.Background(), nil, WithHTTPMarshallers(tt.marshallers))
+
+			_, err := s.getJSONMarshallers()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestServerTimeHTTPHeader(t *testing.T) {
+	s := New(context.Background(), nil, WithServerTimeHeader())
+
+	ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{
+		TrailerMD: metadata.Pairs(ServerTimeKey, "2026-01-02T03:04:05Z"),
+	})
+	rec := httptest.NewRecorder()
+	if err := s.responseHTTPHeaderMatcher(ctx, rec, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := rec.Header().Get(ServerTimeKey); got != "2026-01-02T03:04:05Z" {
+		t.Fatalf("unexpected server time header %q", got)
+	}
+}
+
+func TestHTTPPathPrefix(t *testing.T) {
+	_, baseURL := startGatewayTestService(t, &testGreeter{}, WithHTTPPathPrefix("/api"),
+		WithHealthCheck(&testHealther{ready: true}, "/live", "/ready"))
+
+	resp, body := doHTTP(t, http.MethodPost, baseURL+"/api/v1/greeter:SayHello", `{"name":"bob"}`, nil)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "hello bob") {
+		t.Fatalf("unexpected response under prefix: %d %s", resp.StatusCode, body)
+	}
+
+	if resp, _ = doHTTP(t, http.MethodGet, baseURL+"/api/live", "", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("health check must be served under prefix, got %d", resp.StatusCode)
+	}
+
+	resp, _ = doHTTP(t, http.MethodPost, baseURL+"/v1/greeter:SayHello", `{"name":"bob"}`, nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 without prefix, got %d", resp.StatusCode)
+	}
+}
+
+func TestRestartHTTPGateway(t *testing.T) {
+	s, oldURL := startGatewayTestService(t, &testGreeter{})
+
+	if err := New(context.Background(), nil).RestartHTTPGateway(context.Background(), freeAddr(t)); err == nil {
+		t.Fatal("expected error for not started gateway")
+	}
+
+	newAddr := freeAddr(t)
+	if err := s.RestartHTTPGateway(context.Background(), newAddr); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, body := doHTTP(t, http.MethodPost, "http://"+newAddr+"/v1/greeter:SayHello", `{"name":"bob"}`, nil)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "hello bob") {
+		t.Fatalf("unexpected response on the new endpoint: %d %s", resp.StatusCode, body)
+	}
+
+	// failed restart keeps the gateway on the current endpoint
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	if err = s.RestartHTTPGateway(context.Background(), busy.Addr().String()); err == nil {
+		t.Fatal("expected error for busy endpoint")
+	}
+
+	resp, _ = doHTTP(t, http.MethodPost, "http://"+newAddr+"/v1/greeter:SayHello", `{"name":"bob"}`, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("gateway must be restored on the previous endpoint, got %d", resp.StatusCode)
+	}
+
+	if _, err = http.DefaultTransport.RoundTrip(mustRequest(t, oldURL)); err == nil {
+		t.Fatal("old endpoint must be closed")
+	}
+}
+
+func TestRestartHTTPGatewayShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	s, oldURL := startGatewayTestService(t, &testGreeter{
+		sayHello: func(ctx context.Context, _ *api.HelloRequest) (*api.HelloResponse, error) {
+			close(started)
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return &api.HelloResponse{}, nil
+		},
+	})
+
+	go func() {
+		resp, err := http.Post(oldURL+"/v1/greeter:SayHello", "application/json", //nolint:noctx // test
+			strings.NewReader(`{"name":"bob"}`))
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+	<-started
+
+	// the in-flight request is not finished until the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	newAddr := freeAddr(t)
+	if err := s.RestartHTTPGateway(ctx, newAddr); err != nil {
+		t.Fatalf("expected restart after shutdown timeout, got %v", err)
+	}
+
+	if code := httpGetStatus(t, "http://"+newAddr+"/unknown"); code != http.StatusNotFound {
+		t.Fatalf("gateway must serve on the new endpoint, got %d", code)
+	}
+	if _, err := http.DefaultTransport.RoundTrip(mustRequest(t, oldURL)); err == nil {
+		t.Fatal("old endpoint must be closed")
+	}
+}
+
+// mustRequest creates GET request for the URL.
+func mustRequest(t *testing.T, url string) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil) //nolint:noctx // test
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return req
+}
+
+func TestGatewayResponseModifierAndRewriter(t *testing.T) {
+	greeter := &testGreeter{sayHello: func(ctx context.Context, req *api.HelloRequest) (*api.HelloResponse, error) {
+		_ = grpc.SetTrailer(ctx, metadata.Pairs("x-version", "1"))
+		return &api.HelloResponse{Message: "hello " + req.GetName()}, nil
+	}}
+
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	modifier := func(_ context.Context, w http.ResponseWriter, _ proto.Message) error {
+		mu.Lock()
+		order = append(order, "modifier:"+w.Header().Get("X-Version"))
+		mu.Unlock()
+		w.Header().Set("X-Version", "2")
+		return nil
+	}
+	rewriter := func(_ context.Context, resp proto.Message) (any, error) {
+		if r, ok := resp.(*api.HelloResponse); ok {
+			return &api.HelloResponse{Message: strings.ToUpper(r.GetMessage())}, nil
+		}
+		return resp, nil
+	}
+
+	_, baseURL := startGatewayTestService(t, greeter,
+		WithHTTPHeadersFromMetadata("X-Version"),
+		WithGatewayResponseModifier(modifier),
+		WithGatewayResponseRewriter(rewriter),
+	)
+
+	resp, body := doHTTP(t, http.MethodPost, baseURL+"/v1/greeter:SayHello", `{"name":"bob"}`, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+
+	// modifier is called after headers from metadata are set and overrides them
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) != 1 || order[0] != "modifier:1" {
+		t.Fatalf("unexpected modifier calls %v", order)
+	}
+	if got := resp.Header.Get("X-Version"); got != "2" {
+		t.Fatalf("expected header overridden by modifier, got %q", got)
+	}
+	if !strings.Contains(body, "HELLO BOB") {
+		t.Fatalf("response is not rewritten: %s", body)
+	}
+}
+
+// waitSpan waits until the recorder has an ended span of the kind with the name suffix.
+func waitSpan(t *testing.T, rec *tracetest.SpanRecorder, kind trace.SpanKind, suffix string) sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, span := range rec.Ended() {
+			if span.SpanKind() == kind && strings.HasSuffix(span.Name(), suffix) {
+				return span
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("%s span %q is not recorded", kind, suffix)
+	return nil
+}
+
+func TestGatewayTracerProvider(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	_, baseURL := startGatewayTestService(t, &testGreeter{},
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))))
+
+	resp, _ := doHTTP(t, http.MethodPost, baseURL+"/v1/greeter:SayHello", `{"name":"bob"}`, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	// HTTP server, gateway client and gRPC server spans are created by the configured provider
+	waitSpan(t, rec, trace.SpanKindServer, "grpc-gateway")
+	waitSpan(t, rec, trace.SpanKindClient, "SayHello")
+	waitSpan(t, rec, trace.SpanKindServer, "SayHello")
+}
+
+func TestGatewayPropagator(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	_, baseURL := startGatewayTestService(t, &testGreeter{},
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))),
+		WithPropagator(propagation.TraceContext{}))
+
+	const traceID = "0af7651916cd43dd8448eb211c80319c"
+	header := http.Header{"Traceparent": {"00-" + traceID + "-b7ad6b7169203331-01"}}
+	resp, _ := doHTTP(t, http.MethodPost, baseURL+"/v1/greeter:SayHello", `{"name":"bob"}`, header)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	// incoming trace is continued through the gateway up to the gRPC server
+	for _, span := range []sdktrace.ReadOnlySpan{
+		waitSpan(t, rec, trace.SpanKindServer, "grpc-gateway"),
+		waitSpan(t, rec, trace.SpanKindServer, "SayHello"),
+	} {
+		if got := span.SpanContext().TraceID().String(); got != traceID {
+			t.Fatalf("expected trace ID %s in span %q, got %s", traceID, span.Name(), got)
+		}
+	}
+}
+
+func TestWithoutOTelInstrumentation(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	_, baseURL := startGatewayTestService(t, &testGreeter{}, WithoutOTelInstrumentation(),
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))))
+
+	header := http.Header{TraceDebugKey: {TraceDebugKeyValue}}
+	resp, _ := doHTTP(t, http.MethodPost, baseURL+"/v1/greeter:SayHello", `{"name":"bob"}`, header)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	// spans are ended asynchronously after the response is sent
+	time.Sleep(100 * time.Millisecond)
+	if spans := rec.Ended(); len(spans) != 0 {
+		t.Fatalf("expected no spans, got %d", len(spans))
+	}
+}
+
+// extraHTTPInitializer healthInitializer with hand-written HTTP route.
+type extraHTTPInitializer struct {
+	*healthInitializer
+	err error
+}
+
+func (i *extraHTTPInitializer) RegisterExtraHTTP(mux *runtime.ServeMux) error {
+	if i.err != nil {
+		return i.err
+	}
+
+	return mux.HandlePath(http.MethodGet, "/v1/files/{name}",
+		func(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+			_, _ = w.Write([]byte("file " + params["name"]))
+		})
+}
+
+func TestRegisterExtraHTTP(t *testing.T) {
+	// HTTPHandlerRequired is not set, extra routes are registered anyway
+	httpAddr := freeAddr(t)
+	startTestServiceWith(t, []IGRPCInitializer{&extraHTTPInitializer{healthInitializer: newHealthInitializer()}},
+		WithEndpoint(Endpoint{GRPC: freeAddr(t), HTTP: httpAddr}))
+
+	resp, body := doHTTP(t, http.MethodGet, "http://"+httpAddr+"/v1/files/report.pdf", "", nil)
+	if resp.StatusCode != http.StatusOK || body != "file report.pdf" {
+		t.Fatalf("unexpected response of extra route: %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestRegisterExtraHTTPError(t *testing.T) {
+	initializer := &extraHTTPInitializer{healthInitializer: newHealthInitializer(), err: errors.New("bad route")}
+	s := New(context.Background(), []IGRPCInitializer{initializer},
+		WithEndpoint(Endpoint{GRPC: freeAddr(t), HTTP: freeAddr(t)}))
+
+	err := s.Start(context.Background())
+	if err == nil {
+		_ = s.Stop(context.Background())
+		t.Fatal("expected Start error")
+	}
+	if !strings.Contains(err.Error(), "bad route") {
+		t.Fatalf("expected registration error, got %v", err)
+	}
+}
+
+func TestRoutingErrors(t *testing.T) {
+	_, baseURL := startGatewayTestService(t, &testGreeter{})
+
+	tests := []struct {
+		method, path string
+		status       int
+		message      string
+	}{
+		{method: http.MethodGet, path: "/v1/unknown", status: http.StatusNotFound, message: "no route for GET /v1/unknown"},
+		{
+			method: http.MethodDelete, path: "/v1/greeter:SayHello", status: http.StatusMethodNotAllowed,
+			message: "method DELETE is not allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			resp, body := doHTTP(t, tt.method, baseURL+tt.path, "", nil)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("expected %d, got %d", tt.status, resp.StatusCode)
+			}
+			if !strings.Contains(body, tt.message) || !strings.Contains(body, `"code"`) {
+				t.Fatalf("expected JSON error with %q, got %s", tt.message, body)
+			}
+		})
+	}
+}
+
+func TestCustomRoutingErrorHandler(t *testing.T) {
+	_, baseURL := startGatewayTestService(t, &testGreeter{}, WithRoutingErrorHandler(
+		func(_ context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, _ *http.Request,
+			httpStatus int,
+		) {
+			w.WriteHeader(http.StatusTeapot)
+			_, _ = fmt.Fprintf(w, "custom %d", httpStatus)
+		}))
+
+	resp, body := doHTTP(t, http.MethodGet, baseURL+"/v1/unknown", "", nil)
+	if resp.StatusCode != http.StatusTeapot || body != "custom 404" {
+		t.Fatalf("expected custom handler response, got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestGRPCStatusHeader(t *testing.T) {
+	greeter := &testGreeter{sayHello: func(_ context.Context, req *api.HelloRequest) (*api.HelloResponse, error) {
+		if req.GetName() == "" {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return &api.HelloResponse{Message: "hello " + req.GetName()}, nil
+	}}
+	_, baseURL := startGatewayTestService(t, greeter, WithGRPCStatusHeader())
+
+	resp, _ := doHTTP(t, http.MethodPost, baseURL+"/v1/greeter:SayHello", `{"name":"bob"}`, nil)
+	if resp.Header.Get(GRPCStatusCodeHeader) != "0" || resp.Header.Get(GRPCStatusMessageHeader) != "" {
+		t.Fatalf("expected OK status headers, got %v", resp.Header)
+	}
+
+	resp, _ = doHTTP(t, http.MethodPost, baseURL+"/v1/greeter:SayHello", `{}`, nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get(GRPCStatusCodeHeader) != "5" || resp.Header.Get(GRPCStatusMessageHeader) != "user not found" {
+		t.Fatalf("expected NotFound status headers, got %v", resp.Header)
+	}
+
+	_, baseURL = startGatewayTestService(t, greeter)
+	resp, _ = doHTTP(t, http.MethodPost, baseURL+"/v1/greeter:SayHello", `{}`, nil)
+	if resp.Header.Get(GRPCStatusCodeHeader) != "" {
+		t.Fatal("status headers must not be set without the option")
+	}
+}
+
+func TestInprocessGateway(t *testing.T) {
+	var network string
+	greeter := &testGreeter{sayHello: func(ctx context.Context, req *api.HelloRequest) (*api.HelloResponse, error) {
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			network = p.Addr.Network()
+		}
+		return &api.HelloResponse{Message: "hello " + req.GetName()}, nil
+	}}
+
+	s, baseURL := startGatewayTestService(t, greeter, WithInprocessGateway())
+	if target, _ := s.gatewayTarget(); target != inprocessTarget {
+		t.Fatalf("expected target %s, got %s", inprocessTarget, target)
+	}
+
+	resp, body := doHTTP(t, http.MethodPost, baseURL+"/v1/greeter:SayHello", `{"name":"bob"}`, nil)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "hello bob") {
+		t.Fatalf("unexpected response: %d %s", resp.StatusCode, body)
+	}
+	if network != inprocessNetwork {
+		t.Fatalf("expected request over in-memory connection, got %q", network)
+	}
+
+	s, _ = startGatewayTestService(t, &testGreeter{})
+	if target, opts := s.gatewayTarget(); target != s.endpoint.GRPC || len(opts) != 0 {
+		t.Fatalf("expected TCP target without the option, got %s", target)
+	}
+}
@@ -0,0 +1,169 @@
+package grpcdial
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// Cache - storage of cached gRPC responses. Implementations must be safe for concurrent use.
+// Stored messages must not be modified.
+type Cache interface {
+	// Get returns cached response. Returns false if there is no response or it has expired.
+	Get(key string) (proto.Message, bool)
+	// Set stores response for ttl.
+	Set(key string, value proto.Message, ttl time.Duration)
+	// Delete removes response from cache.
+	Delete(key string)
+}
+
+// CacheKeyFunc returns cache key of the request. The key must include the method and everything
+// that affects the response, e.g. user identity from outgoing metadata of ctx: responses cached
+// for one user are returned to every caller with the same key. Empty key disables caching of the request.
+type CacheKeyFunc func(ctx context.Context, method string, req any) string
+
+// MemoryCache - in-process LRU cache with TTL. Implements Cache interface.
+type MemoryCache struct {
+	mu      sync.Mutex
+	maxSize int
+	items   map[string]*list.Element
+	lru     *list.List // front is the most recently used
+}
+
+type memoryCacheItem struct {
+	key     string
+	value   proto.Message
+	expires time.Time
+}
+
+// NewMemoryCache creates a new MemoryCache. maxSize - maximum number of responses, 0 means unlimited.
+func NewMemoryCache(maxSize int) *MemoryCache {
+	return &MemoryCache{
+		maxSize: maxSize,
+		items:   make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Get returns cached response. Returns false if there is no response or it has expired.
+func (c *MemoryCache) Get(key string) (proto.Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	item, _ := e.Value.(*memoryCacheItem)
+	if time.Now().After(item.expires) {
+		c.removeElement(e)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(e)
+
+	return item.value, true
+}
+
+// Set stores response for ttl. The least recently used response is evicted if the cache is full.
+func (c *MemoryCache) Set(key string, value proto.Message, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		item, _ := e.Value.(*memoryCacheItem)
+		item.value = value
+		item.expires = time.Now().Add(ttl)
+		c.lru.MoveToFront(e)
+		return
+	}
+
+	c.items[key] = c.lru.PushFront(&memoryCacheItem{
+		key:     key,
+		value:   value,
+		expires: time.Now().Add(ttl),
+	})
+
+	if c.maxSize > 0 && c.lru.Len() > c.maxSize {
+		c.removeElement(c.lru.Back())
+	}
+}
+
+// Delete removes response from cache.
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.removeElement(e)
+	}
+}
+
+// Purge removes all responses from cache.
+func (c *MemoryCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+// Len returns number of responses in cache, including expired ones that have not been removed yet.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+func (c *MemoryCache) removeElement(e *list.Element) {
+	item, _ := c.lru.Remove(e).(*memoryCacheItem)
+	delete(c.items, item.key)
+}
+
+// cacheInterceptor returns cached responses of unary calls.
+// Only the response message is cached: on cache hit grpc.Header and grpc.Trailer call options are not filled.
+type cacheInterceptor struct {
+	cache   Cache
+	keyFunc CacheKeyFunc
+	ttl     time.Duration
+}
+
+func (i *cacheInterceptor) unary(
+	ctx context.Context,
+	method string,
+	req, reply any,
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	replyMsg, ok := reply.(proto.Message)
+	if !ok {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	key := i.keyFunc(ctx, method, req)
+	if key == "" {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	if cached, found := i.cache.Get(key); found {
+		proto.Reset(replyMsg)
+		proto.Merge(replyMsg, cached)
+		return nil
+	}
+
+	if err := invoker(ctx, method, req, reply, cc, opts...); err != nil {
+		return err
+	}
+
+	// store a copy, so the caller can modify its response
+	i.cache.Set(key, proto.Clone(replyMsg), i.ttl)
+
+	return nil
+}
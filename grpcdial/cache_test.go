@@ -0,0 +1,90 @@
+package grpcdial
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestMemoryCacheLRU(t *testing.T) {
+	c := NewMemoryCache(2)
+	c.Set("a", wrapperspb.String("a"), time.Minute)
+	c.Set("b", wrapperspb.String("b"), time.Minute)
+
+	// "a" becomes the most recently used, "b" is evicted
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a in cache")
+	}
+	c.Set("c", wrapperspb.String("c"), time.Minute)
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+	if c.Len() != 2 {
+		t.Fatalf("expected 2 items, got %d", c.Len())
+	}
+}
+
+func TestMemoryCacheTTL(t *testing.T) {
+	c := NewMemoryCache(0)
+	c.Set("a", wrapperspb.String("a"), 10*time.Millisecond)
+
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expected expired item to be missing")
+	}
+	if c.Len() != 0 {
+		t.Fatalf("expected expired item to be removed, got %d items", c.Len())
+	}
+}
+
+func TestCacheInterceptorSeparatesUsers(t *testing.T) {
+	i := &cacheInterceptor{cache: NewMemoryCache(0), keyFunc: userKeyFunc, ttl: time.Minute}
+
+	var calls atomic.Int32
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		opts ...grpc.CallOption,
+	) error {
+		calls.Add(1)
+		return slowInvoker(&atomic.Int32{})(ctx, method, req, reply, cc, opts...)
+	}
+
+	for _, user := range []string{"alice", "bob", "alice"} {
+		reply := &wrapperspb.StringValue{}
+		if err := i.unary(userCtx(user), testMethod, wrapperspb.String("req"), reply, nil, invoker); err != nil {
+			t.Fatal(err)
+		}
+		if reply.GetValue() != user {
+			t.Fatalf("user %s got response of %s", user, reply.GetValue())
+		}
+	}
+
+	if calls.Load() != 2 {
+		t.Fatalf("expected 2 calls to server, got %d", calls.Load())
+	}
+}
+
+func TestCacheInterceptorEmptyKey(t *testing.T) {
+	i := &cacheInterceptor{cache: NewMemoryCache(0), keyFunc: userKeyFunc, ttl: time.Minute}
+
+	var calls atomic.Int32
+	invoker := func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		calls.Add(1)
+		return nil
+	}
+
+	for range 2 {
+		if err := i.unary(context.Background(), testMethod, wrapperspb.String("req"),
+			&wrapperspb.StringValue{}, nil, invoker); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if calls.Load() != 2 {
+		t.Fatalf("expected requests with empty key not to be cached, got %d calls", calls.Load())
+	}
+}
@@ -0,0 +1,92 @@
+package grpcdial
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// withDeadlineBudget reduces deadline of the context by budget.
+// Returns false if there is no time left for the call.
+func withDeadlineBudget(ctx context.Context, budget time.Duration) (context.Context, context.CancelFunc, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx, func() {}, true
+	}
+
+	deadline = deadline.Add(-budget)
+	if !time.Now().Before(deadline) {
+		return ctx, func() {}, false
+	}
+
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+
+	return ctx, cancel, true
+}
+
+func deadlineBudgetUnaryInterceptor(budget time.Duration) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		ctx, cancel, ok := withDeadlineBudget(ctx, budget)
+		defer cancel()
+
+		if !ok {
+			return status.Errorf(codes.DeadlineExceeded, "not enough time left for %s", method)
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func deadlineBudgetStreamInterceptor(budget time.Duration) grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		ctx, cancel, ok := withDeadlineBudget(ctx, budget)
+		if !ok {
+			cancel()
+			return nil, status.Errorf(codes.DeadlineExceeded, "not enough time left for %s", method)
+		}
+
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		// the stream lives after the interceptor returns, the derived context is released when it is finished
+		return &deadlineClientStream{ClientStream: stream, desc: desc, cancel: cancel}, nil
+	}
+}
+
+// deadlineClientStream releases the context with reduced deadline when the stream is finished:
+// on the first receive error (including io.EOF) or after the single response of a non-server-streaming call.
+type deadlineClientStream struct {
+	grpc.ClientStream
+	desc   *grpc.StreamDesc
+	cancel context.CancelFunc
+}
+
+// RecvMsg implements grpc.ClientStream.
+func (s *deadlineClientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil || !s.desc.ServerStreams {
+		s.cancel()
+	}
+
+	return err
+}
@@ -0,0 +1,143 @@
+package grpcdial
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type testClientStream struct {
+	grpc.ClientStream
+
+	ctx  context.Context //nolint:containedctx // ok
+	recv []error
+}
+
+func (s *testClientStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *testClientStream) RecvMsg(any) error {
+	if len(s.recv) == 0 {
+		return io.EOF
+	}
+	err := s.recv[0]
+	s.recv = s.recv[1:]
+
+	return err
+}
+
+func TestDeadlineBudgetUnaryInterceptor(t *testing.T) {
+	interceptor := deadlineBudgetUnaryInterceptor(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	called := false
+	err := interceptor(ctx, testMethod, nil, nil, nil,
+		func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+			called = true
+			return nil
+		})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	if called {
+		t.Fatal("invoker must not be called without time left")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	outer, _ := ctx.Deadline()
+
+	err = interceptor(ctx, testMethod, nil, nil, nil,
+		func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+			d, ok := ctx.Deadline()
+			if !ok || !d.Before(outer.Add(-time.Second+time.Millisecond)) {
+				t.Errorf("expected deadline reduced by budget, got %v (outer %v)", d, outer)
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeadlineBudgetStreamInterceptorCancel(t *testing.T) {
+	tests := []struct {
+		name string
+		desc *grpc.StreamDesc
+		recv []error
+	}{
+		{name: "server stream finished by EOF", desc: &grpc.StreamDesc{ServerStreams: true}, recv: []error{nil, nil}},
+		{
+			name: "server stream finished by error", desc: &grpc.StreamDesc{ServerStreams: true},
+			recv: []error{nil, errors.New("broken")},
+		},
+		{name: "client stream finished by response", desc: &grpc.StreamDesc{ClientStreams: true}, recv: []error{nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interceptor := deadlineBudgetStreamInterceptor(time.Second)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			var streamCtx context.Context
+			cs, err := interceptor(ctx, tt.desc, nil, testMethod,
+				func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string,
+					_ ...grpc.CallOption,
+				) (grpc.ClientStream, error) {
+					streamCtx = ctx
+					return &testClientStream{ctx: ctx, recv: append([]error{}, tt.recv...)}, nil
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !tt.desc.ServerStreams {
+				_ = cs.RecvMsg(nil)
+			} else {
+				for cs.RecvMsg(nil) == nil {
+					if streamCtx.Err() != nil {
+						t.Fatal("context must not be canceled while the stream is active")
+					}
+				}
+			}
+
+			if !errors.Is(streamCtx.Err(), context.Canceled) {
+				t.Fatalf("expected stream context to be canceled, got %v", streamCtx.Err())
+			}
+		})
+	}
+}
+
+func TestDeadlineBudgetStreamInterceptorStreamerError(t *testing.T) {
+	interceptor := deadlineBudgetStreamInterceptor(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var streamCtx context.Context
+	wantErr := status.Error(codes.Unavailable, "unavailable")
+	_, err := interceptor(ctx, &grpc.StreamDesc{ServerStreams: true}, nil, testMethod,
+		func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string,
+			_ ...grpc.CallOption,
+		) (grpc.ClientStream, error) {
+			streamCtx = ctx
+			return nil, wantErr
+		})
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("expected %v, got %v", wantErr, err)
+	}
+	if !errors.Is(streamCtx.Err(), context.Canceled) {
+		t.Fatalf("expected context to be canceled, got %v", streamCtx.Err())
+	}
+}
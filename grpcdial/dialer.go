@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	grpc_retry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
@@ -43,9 +44,9 @@ func (d *Dialer) DialNoClose(ctx context.Context, target, name string, opts ...O
 	return d.dialHelper(ctx, target, name, false, opts...)
 }
 
-// Dial connects to gRPC server.
+// dialHelper connects to gRPC server. Returns an error if ctx is canceled before the connection is created.
 func (d *Dialer) dialHelper(
-	_ context.Context,
+	ctx context.Context,
 	target, name string,
 	saveCon bool,
 	opts ...Option,
@@ -56,6 +57,10 @@ func (d *Dialer) dialHelper(
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("grpc dial target %s, name %s: %w", target, name, err)
+	}
+
 	const (
 		defaultMaxRetries     = 3
 		defaultRequestTimeout = time.Second * 10
@@ -67,6 +72,7 @@ func (d *Dialer) dialHelper(
 		requestTimeout: defaultRequestTimeout,
 		retryTimeout:   defaultRetryTimeout,
 		logger:         ctxlog.NewStubWrapper(),
+		logLevel:       slog.LevelDebug,
 	}
 
 	for _, opt := range d.opts {
@@ -86,8 +92,9 @@ func (d *Dialer) dialHelper(
 			grpc_retry.WithMax(uint(t.maxRetries)), //nolint:gosec // ok
 			grpc_retry.WithCodes(append(grpc_retry.DefaultRetriableCodes, codes.Unknown, codes.Internal)...),
 			grpc_retry.WithPerRetryTimeout(t.requestTimeout),
-			grpc_retry.WithBackoffContext(func(ctx context.Context, attempt uint) time.Duration {
-				t.logger.Warn(ctx, "grpc client retry",
+			grpc_retry.WithBackoffContext(func(callCtx context.Context, attempt uint) time.Duration {
+				// call context carries request-scoped fields of the call being retried
+				t.logger.Warn(callCtx, "grpc client retry",
 					"target", name,
 					"attempt", attempt)
 				return t.retryTimeout
@@ -103,27 +110,58 @@ func (d *Dialer) dialHelper(
 
 	if t.unaryInterceptors == nil {
 		t.unaryInterceptors = []grpc.UnaryClientInterceptor{
-			d.getClientInterceptor(t.logger),
+			d.getClientInterceptor(t.logger, t.logLevel),
 			grpc_retry.UnaryClientInterceptor(t.retryOpts...),
 		}
 	}
 
 	if t.streamInterceptors == nil {
 		t.streamInterceptors = []grpc.StreamClientInterceptor{
-			d.getStreamClientInterceptor(t.logger),
+			d.getStreamClientInterceptor(t.logger, t.logLevel, t.logStreamErrors),
 			grpc_retry.StreamClientInterceptor(t.retryOpts...),
 		}
 	}
 
+	if t.deadlineBudget > 0 {
+		t.unaryInterceptors = append(
+			[]grpc.UnaryClientInterceptor{deadlineBudgetUnaryInterceptor(t.deadlineBudget)}, t.unaryInterceptors...)
+		t.streamInterceptors = append(
+			[]grpc.StreamClientInterceptor{deadlineBudgetStreamInterceptor(t.deadlineBudget)}, t.streamInterceptors...)
+	}
+
+	// coalescing is done before retries, so one call with retries is shared by all callers
+	if t.singleflight != nil {
+		t.unaryInterceptors = append([]grpc.UnaryClientInterceptor{t.singleflight.unary}, t.unaryInterceptors...)
+	}
+
+	// cache is checked before coalescing, so only cache misses are coalesced
+	if t.cache != nil {
+		t.unaryInterceptors = append([]grpc.UnaryClientInterceptor{t.cache.unary}, t.unaryInterceptors...)
+	}
+
 	conn, err := grpc.NewClient(target,
 		grpc.WithTransportCredentials(t.creds),
 		grpc.WithStatsHandler(statWrapper),
 		grpc.WithChainUnaryInterceptor(t.unaryInterceptors...),
 		grpc.WithChainStreamInterceptor(t.streamInterceptors...))
 	if err != nil {
+		t.logger.Error(ctx, "grpc dial failed", "target", target, "name", name, "error", err)
+		return nil, fmt.Errorf("grpc dial target %s, name %s: %w", target, name, err)
+	}
+
+	// grpc.NewClient doesn't connect, so cancellation during setup is checked after it
+	if err = ctx.Err(); err != nil {
+		_ = conn.Close()
 		return nil, fmt.Errorf("grpc dial target %s, name %s: %w", target, name, err)
 	}
 
+	if t.healthProbe != nil {
+		if err = t.healthProbe.check(ctx, conn); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("grpc dial target %s, name %s: %w", target, name, err)
+		}
+	}
+
 	if saveCon {
 		d.connections[target] = conn
 	}
@@ -0,0 +1,33 @@
+package grpcdial
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDialCanceledContext(t *testing.T) {
+	d := New(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := d.Dial(ctx, "localhost:1", "test"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(d.connections) != 0 {
+		t.Fatal("connection must not be saved on error")
+	}
+
+	// the target can be dialed after the failed attempt
+	conn, err := d.Dial(context.Background(), "localhost:1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.connections["localhost:1"] != conn {
+		t.Fatal("expected connection to be saved")
+	}
+	if err = d.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,36 @@
+package grpcdial
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// healthProbe checks that the server reports SERVING status of the service after dial.
+type healthProbe struct {
+	serviceName string
+	timeout     time.Duration
+}
+
+func (p *healthProbe) check(ctx context.Context, conn *grpc.ClientConn) error {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx,
+		&grpc_health_v1.HealthCheckRequest{Service: p.serviceName})
+	if err != nil {
+		return fmt.Errorf("health check of service %q: %w", p.serviceName, err)
+	}
+
+	if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("health check of service %q: status %s", p.serviceName, resp.GetStatus())
+	}
+
+	return nil
+}
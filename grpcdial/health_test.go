@@ -0,0 +1,81 @@
+package grpcdial
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// startHealthServer starts gRPC server with health service on a free port and returns its address.
+func startHealthServer(t *testing.T) (string, *health.Server) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hs := health.NewServer()
+	srv := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(srv, hs)
+	go func() { _ = srv.Serve(l) }()
+	t.Cleanup(srv.Stop)
+
+	return l.Addr().String(), hs
+}
+
+func TestDialHealthProbe(t *testing.T) {
+	addr, hs := startHealthServer(t)
+	hs.SetServingStatus("serving", grpc_health_v1.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("stopped", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	tests := []struct {
+		service string
+		wantErr bool
+	}{
+		{service: "", wantErr: false},
+		{service: "serving", wantErr: false},
+		{service: "stopped", wantErr: true},
+		{service: "unknown", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			d := New(context.Background())
+			t.Cleanup(func() { _ = d.Stop(context.Background()) })
+
+			_, err := d.Dial(context.Background(), addr, "test", WithDialHealthProbe(tt.service, 5*time.Second))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr && len(d.connections) != 0 {
+				t.Fatal("connection must not be saved if health check fails")
+			}
+		})
+	}
+}
+
+func TestDialHealthProbeTimeout(t *testing.T) {
+	// nothing listens on the address, so the check fails by timeout
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+
+	start := time.Now()
+	_, err = New(context.Background()).DialNoClose(context.Background(), addr, "test",
+		WithDialHealthProbe("", 100*time.Millisecond))
+	if err == nil {
+		t.Fatal("expected error for unavailable server")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("health check must be limited by timeout, took %s", elapsed)
+	}
+}
@@ -2,12 +2,17 @@ package grpcdial
 
 import (
 	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
 
 	"github.com/n-r-w/ctxlog"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
 )
 
-func (d *Dialer) getClientInterceptor(logger ctxlog.ILogger) grpc.UnaryClientInterceptor {
+func (d *Dialer) getClientInterceptor(logger ctxlog.ILogger, level slog.Level) grpc.UnaryClientInterceptor {
 	return func(
 		ctx context.Context,
 		method string,
@@ -18,11 +23,12 @@ func (d *Dialer) getClientInterceptor(logger ctxlog.ILogger) grpc.UnaryClientInt
 	) error {
 		err := invoker(ctx, method, req, reply, cc, opts...)
 		if err != nil {
-			logger.Debug(ctx,
+			logAtLevel(ctx, logger, level,
 				"grpc client error",
 				"grpc_client", "unary",
 				"grpc_method", method,
 				"grpc_target", cc.Target(),
+				"grpc_code", status.Code(err).String(),
 				"error", err)
 		}
 
@@ -30,7 +36,9 @@ func (d *Dialer) getClientInterceptor(logger ctxlog.ILogger) grpc.UnaryClientInt
 	}
 }
 
-func (d *Dialer) getStreamClientInterceptor(logger ctxlog.ILogger) grpc.StreamClientInterceptor {
+func (d *Dialer) getStreamClientInterceptor(
+	logger ctxlog.ILogger, level slog.Level, logStreamErrors bool,
+) grpc.StreamClientInterceptor {
 	return func(
 		ctx context.Context,
 		desc *grpc.StreamDesc,
@@ -41,14 +49,93 @@ func (d *Dialer) getStreamClientInterceptor(logger ctxlog.ILogger) grpc.StreamCl
 	) (grpc.ClientStream, error) {
 		stream, err := streamer(ctx, desc, cc, method, opts...)
 		if err != nil {
-			logger.Debug(ctx,
+			logAtLevel(ctx, logger, level,
 				"grpc client error",
-				"grpc_client", "unary",
+				"grpc_client", "stream",
+				"grpc_client_stream", desc.ClientStreams,
+				"grpc_server_stream", desc.ServerStreams,
 				"grpc_method", method,
 				"grpc_target", cc.Target(),
+				"grpc_code", status.Code(err).String(),
 				"error", err)
+
+			return stream, err
+		}
+
+		if logStreamErrors {
+			stream = &loggingClientStream{
+				ClientStream: stream,
+				ctx:          ctx,
+				logger:       logger,
+				level:        level,
+				method:       method,
+				target:       cc.Target(),
+			}
 		}
 
-		return stream, err
+		return stream, nil
+	}
+}
+
+// loggingClientStream logs errors that occur during streaming. Only the first error is logged,
+// since the stream returns the same error on subsequent calls. io.EOF is the normal end of stream.
+type loggingClientStream struct {
+	grpc.ClientStream
+
+	ctx    context.Context //nolint:containedctx // ok
+	logger ctxlog.ILogger
+	level  slog.Level
+	method string
+	target string
+	logged atomic.Bool
+}
+
+func (s *loggingClientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	s.logError("send", err)
+
+	return err
+}
+
+func (s *loggingClientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	s.logError("recv", err)
+
+	return err
+}
+
+func (s *loggingClientStream) CloseSend() error {
+	err := s.ClientStream.CloseSend()
+	s.logError("close_send", err)
+
+	return err
+}
+
+func (s *loggingClientStream) logError(op string, err error) {
+	if err == nil || errors.Is(err, io.EOF) || !s.logged.CompareAndSwap(false, true) {
+		return
+	}
+
+	logAtLevel(s.ctx, s.logger, s.level,
+		"grpc client stream error",
+		"grpc_client", "stream",
+		"grpc_stream_op", op,
+		"grpc_method", s.method,
+		"grpc_target", s.target,
+		"grpc_code", status.Code(err).String(),
+		"error", err)
+}
+
+// logAtLevel logs message with the method of logger corresponding to level.
+func logAtLevel(ctx context.Context, logger ctxlog.ILogger, level slog.Level, msg string, args ...any) {
+	switch {
+	case level >= slog.LevelError:
+		logger.Error(ctx, msg, args...)
+	case level >= slog.LevelWarn:
+		logger.Warn(ctx, msg, args...)
+	case level >= slog.LevelInfo:
+		logger.Info(ctx, msg, args...)
+	default:
+		logger.Debug(ctx, msg, args...)
 	}
 }
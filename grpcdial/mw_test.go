@@ -0,0 +1,177 @@
+package grpcdial
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// testLogEntry log record of testLogger.
+type testLogEntry struct {
+	level string
+	msg   string
+	args  []any
+}
+
+// arg returns value of the key from log record arguments.
+func (e testLogEntry) arg(key string) any {
+	for i := 0; i+1 < len(e.args); i += 2 {
+		if e.args[i] == key {
+			return e.args[i+1]
+		}
+	}
+
+	return nil
+}
+
+// testLogger ctxlog.ILogger, which records log entries.
+type testLogger struct {
+	mu      sync.Mutex
+	entries []testLogEntry
+}
+
+func (l *testLogger) log(level, msg string, args []any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, testLogEntry{level: level, msg: msg, args: args})
+}
+
+func (l *testLogger) Debug(_ context.Context, msg string, args ...any) { l.log("debug", msg, args) }
+func (l *testLogger) Info(_ context.Context, msg string, args ...any)  { l.log("info", msg, args) }
+func (l *testLogger) Warn(_ context.Context, msg string, args ...any)  { l.log("warn", msg, args) }
+func (l *testLogger) Error(_ context.Context, msg string, args ...any) { l.log("error", msg, args) }
+
+// testConn creates client connection, which is never used for calls.
+func testConn(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+
+	conn, err := grpc.NewClient("passthrough:///test", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func TestClientLogLevel(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  string
+	}{
+		{level: slog.LevelDebug, want: "debug"},
+		{level: slog.LevelInfo, want: "info"},
+		{level: slog.LevelWarn, want: "warn"},
+		{level: slog.LevelError, want: "error"},
+		{level: slog.LevelError + 4, want: "error"},
+	}
+
+	conn := testConn(t)
+	failing := func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		return status.Error(codes.NotFound, "not found")
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			logger := &testLogger{}
+			interceptor := (&Dialer{}).getClientInterceptor(logger, tt.level)
+
+			if err := interceptor(context.Background(), testMethod, nil, nil, conn, failing); err == nil {
+				t.Fatal("expected error")
+			}
+			if len(logger.entries) != 1 || logger.entries[0].level != tt.want {
+				t.Fatalf("expected one %s entry, got %v", tt.want, logger.entries)
+			}
+			if code := logger.entries[0].arg("grpc_code"); code != codes.NotFound.String() {
+				t.Fatalf("expected code NotFound, got %v", code)
+			}
+		})
+	}
+
+	// successful calls are not logged
+	logger := &testLogger{}
+	err := (&Dialer{}).getClientInterceptor(logger, slog.LevelError)(context.Background(), testMethod, nil, nil,
+		conn, func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error { return nil })
+	if err != nil || len(logger.entries) != 0 {
+		t.Fatalf("expected no log entries for success, got %v, %v", err, logger.entries)
+	}
+}
+
+func TestStreamClientInterceptorLabels(t *testing.T) {
+	conn := testConn(t)
+	logger := &testLogger{}
+	interceptor := (&Dialer{}).getStreamClientInterceptor(logger, slog.LevelDebug, false)
+
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	_, err := interceptor(context.Background(), desc, conn, testMethod,
+		func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+			return nil, status.Error(codes.Unavailable, "unavailable")
+		})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected Unavailable, got %v", err)
+	}
+
+	if len(logger.entries) != 1 {
+		t.Fatalf("expected one log entry, got %d", len(logger.entries))
+	}
+	e := logger.entries[0]
+	if e.arg("grpc_client") != "stream" {
+		t.Fatalf("expected stream label, got %v", e.arg("grpc_client"))
+	}
+	if e.arg("grpc_client_stream") != false || e.arg("grpc_server_stream") != true {
+		t.Fatalf("expected stream direction labels, got %v", e.args)
+	}
+}
+
+func TestStreamErrorLogging(t *testing.T) {
+	conn := testConn(t)
+	streamer := func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string,
+		_ ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return &testClientStream{ctx: ctx, recv: []error{
+			nil, status.Error(codes.Internal, "broken"), status.Error(codes.Internal, "broken"),
+		}}, nil
+	}
+
+	logger := &testLogger{}
+	interceptor := (&Dialer{}).getStreamClientInterceptor(logger, slog.LevelWarn, true)
+	stream, err := interceptor(context.Background(), &grpc.StreamDesc{ServerStreams: true}, conn, testMethod, streamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 4 {
+		_ = stream.RecvMsg(nil)
+	}
+
+	// only the first error is logged, io.EOF is not an error
+	if len(logger.entries) != 1 {
+		t.Fatalf("expected one log entry, got %v", logger.entries)
+	}
+	e := logger.entries[0]
+	if e.level != "warn" || e.msg != "grpc client stream error" || e.arg("grpc_stream_op") != "recv" {
+		t.Fatalf("unexpected log entry %v", e)
+	}
+
+	// without the option the stream is not wrapped
+	logger = &testLogger{}
+	interceptor = (&Dialer{}).getStreamClientInterceptor(logger, slog.LevelWarn, false)
+	if stream, err = interceptor(context.Background(), &grpc.StreamDesc{}, conn, testMethod, streamer); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stream.(*loggingClientStream); ok {
+		t.Fatal("stream must not be wrapped without the option")
+	}
+	for range 3 {
+		_ = stream.RecvMsg(nil)
+	}
+	if len(logger.entries) != 0 {
+		t.Fatalf("expected no log entries, got %v", logger.entries)
+	}
+}
@@ -1,6 +1,7 @@
 package grpcdial
 
 import (
+	"log/slog"
 	"time"
 
 	grpc_retry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
@@ -19,6 +20,21 @@ func WithLogger(logger ctxlog.ILogger) Option {
 	}
 }
 
+// WithClientLogLevel sets level at which errors of client calls are logged. Default is slog.LevelDebug.
+func WithClientLogLevel(level slog.Level) Option {
+	return func(g *targetInfo) {
+		g.logLevel = level
+	}
+}
+
+// WithStreamErrorLogging enables logging of errors that occur during streaming (SendMsg/RecvMsg),
+// not only errors of establishing the stream. Errors are logged at the level set by WithClientLogLevel.
+func WithStreamErrorLogging() Option {
+	return func(g *targetInfo) {
+		g.logStreamErrors = true
+	}
+}
+
 // WithCredentials sets TLS configuration for connecting to gRPC server.
 // If not set, insecure.NewCredentials() is used.
 func WithCredentials(creds credentials.TransportCredentials) Option {
@@ -66,6 +82,53 @@ func WithDefaultRetryOptions(maxRetries int, requestTimeout, retryTimeout time.D
 	}
 }
 
+// WithSingleflight coalesces concurrent identical calls of the given idempotent methods into one call to the server.
+// All callers get a copy of the same response. Methods are specified in full form, e.g. "/package.Service/Method".
+// Only unary calls with protobuf responses are coalesced.
+// The call is made with credentials of the first caller, so keyFunc must separate calls of different users
+// (see SingleflightKeyFunc), otherwise a response for one user is returned to another.
+func WithSingleflight(keyFunc SingleflightKeyFunc, idempotentMethods ...string) Option {
+	return func(g *targetInfo) {
+		g.singleflight = newSingleflightInterceptor(keyFunc, idempotentMethods)
+	}
+}
+
+// WithResponseCache enables caching of successful unary responses for ttl.
+// Requests for which keyFunc returns an empty key are not cached.
+// Use cache methods to invalidate responses, e.g. NewMemoryCache for in-process LRU cache with max size.
+// Only response messages are cached, response header and trailer (grpc.Header, grpc.Trailer call options)
+// are not available for responses returned from the cache.
+func WithResponseCache(cache Cache, keyFunc CacheKeyFunc, ttl time.Duration) Option {
+	return func(g *targetInfo) {
+		g.cache = &cacheInterceptor{
+			cache:   cache,
+			keyFunc: keyFunc,
+			ttl:     ttl,
+		}
+	}
+}
+
+// WithDeadlineBudget reduces deadline inherited from the incoming request context by the budget
+// before calling the server, so the call completes before the upstream deadline
+// and the caller has time to process the response. Calls without deadline are not affected.
+func WithDeadlineBudget(budget time.Duration) Option {
+	return func(g *targetInfo) {
+		g.deadlineBudget = budget
+	}
+}
+
+// WithDialHealthProbe calls gRPC health Check for the service after dial.
+// Dial returns an error if the service is not SERVING or the check fails within timeout (0 - no timeout).
+// Empty serviceName checks overall server health.
+func WithDialHealthProbe(serviceName string, timeout time.Duration) Option {
+	return func(g *targetInfo) {
+		g.healthProbe = &healthProbe{
+			serviceName: serviceName,
+			timeout:     timeout,
+		}
+	}
+}
+
 type targetInfo struct {
 	creds              credentials.TransportCredentials
 	unaryInterceptors  []grpc.UnaryClientInterceptor
@@ -76,4 +139,12 @@ type targetInfo struct {
 	requestTimeout time.Duration
 	retryTimeout   time.Duration
 	logger         ctxlog.ILogger
+	logLevel       slog.Level
+
+	logStreamErrors bool
+
+	singleflight   *singleflightInterceptor
+	cache          *cacheInterceptor
+	deadlineBudget time.Duration
+	healthProbe    *healthProbe
 }
@@ -0,0 +1,85 @@
+package grpcdial
+
+import (
+	"context"
+
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// SingleflightKeyFunc returns key of the request. Concurrent calls of the same method with equal keys
+// are coalesced into one call to the server, which is made with the context (including outgoing metadata
+// and credentials) of the first caller. The key must include everything that affects the response,
+// e.g. user identity from outgoing metadata of ctx: calls of different users must never get equal keys.
+// Empty key disables coalescing of the call.
+type SingleflightKeyFunc func(ctx context.Context, method string, req any) string
+
+// singleflightInterceptor deduplicates concurrent identical calls of idempotent methods.
+type singleflightInterceptor struct {
+	keyFunc SingleflightKeyFunc
+	methods map[string]struct{}
+	group   singleflight.Group
+}
+
+func newSingleflightInterceptor(keyFunc SingleflightKeyFunc, methods []string) *singleflightInterceptor {
+	m := make(map[string]struct{}, len(methods))
+	for _, method := range methods {
+		m[method] = struct{}{}
+	}
+
+	return &singleflightInterceptor{
+		keyFunc: keyFunc,
+		methods: m,
+	}
+}
+
+func (i *singleflightInterceptor) unary(
+	ctx context.Context,
+	method string,
+	req, reply any,
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	if _, ok := i.methods[method]; !ok {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	replyMsg, ok := reply.(proto.Message)
+	if !ok {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	key := i.keyFunc(ctx, method, req)
+	if key == "" {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	key = method + "\x00" + key
+
+	// The shared response is never returned to callers directly, each caller gets its own copy.
+	// The call is executed with the context of the first caller, so its cancellation affects all callers.
+	ch := i.group.DoChan(key, func() (any, error) {
+		shared := replyMsg.ProtoReflect().New().Interface()
+		if err := invoker(ctx, method, req, shared, cc, opts...); err != nil {
+			return nil, err
+		}
+		return shared, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return res.Err
+		}
+
+		shared, _ := res.Val.(proto.Message)
+		proto.Reset(replyMsg)
+		proto.Merge(replyMsg, shared)
+
+		return nil
+	}
+}
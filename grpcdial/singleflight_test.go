@@ -0,0 +1,152 @@
+package grpcdial
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const testMethod = "/test.Service/Get"
+
+// userKeyFunc separates calls by "user" outgoing metadata.
+func userKeyFunc(ctx context.Context, _ string, req any) string {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	users := md.Get("user")
+	if len(users) == 0 {
+		return ""
+	}
+	return users[0] + "|" + req.(*wrapperspb.StringValue).GetValue() //nolint:forcetypeassert // test
+}
+
+func userCtx(user string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "user", user)
+}
+
+// slowInvoker replies with the user of the call after a delay and counts calls.
+func slowInvoker(calls *atomic.Int32) grpc.UnaryInvoker {
+	return func(ctx context.Context, _ string, _, reply any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		calls.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		md, _ := metadata.FromOutgoingContext(ctx)
+		reply.(*wrapperspb.StringValue).Value = md.Get("user")[0] //nolint:forcetypeassert // test
+		return nil
+	}
+}
+
+func TestSingleflightCoalescesSameKey(t *testing.T) {
+	i := newSingleflightInterceptor(userKeyFunc, []string{testMethod})
+
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reply := &wrapperspb.StringValue{}
+			err := i.unary(userCtx("alice"), testMethod, wrapperspb.String("req"), reply, nil, slowInvoker(&calls))
+			if err != nil || reply.GetValue() != "alice" {
+				t.Errorf("unexpected reply %q, error %v", reply.GetValue(), err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 call, got %d", calls.Load())
+	}
+}
+
+func TestSingleflightSeparatesUsers(t *testing.T) {
+	i := newSingleflightInterceptor(userKeyFunc, []string{testMethod})
+
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	for _, user := range []string{"alice", "bob"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reply := &wrapperspb.StringValue{}
+			err := i.unary(userCtx(user), testMethod, wrapperspb.String("req"), reply, nil, slowInvoker(&calls))
+			if err != nil || reply.GetValue() != user {
+				t.Errorf("user %s got reply %q, error %v", user, reply.GetValue(), err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls.Load() != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls.Load())
+	}
+}
+
+func TestSingleflightEmptyKeyNotCoalesced(t *testing.T) {
+	i := newSingleflightInterceptor(userKeyFunc, []string{testMethod})
+
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// no user metadata - empty key
+			reply := &wrapperspb.StringValue{}
+			_ = i.unary(context.Background(), testMethod, wrapperspb.String("req"), reply, nil,
+				func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+					calls.Add(1)
+					time.Sleep(20 * time.Millisecond)
+					return nil
+				})
+		}()
+	}
+	wg.Wait()
+
+	if calls.Load() != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls.Load())
+	}
+}
+
+func TestSingleflightWaiterContextDone(t *testing.T) {
+	i := newSingleflightInterceptor(userKeyFunc, []string{testMethod})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	invoker := func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		close(started)
+		<-release
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- i.unary(userCtx("alice"), testMethod, wrapperspb.String("req"), &wrapperspb.StringValue{}, nil, invoker)
+	}()
+	<-started
+
+	canceledCtx, cancel := context.WithCancel(userCtx("alice"))
+	cancel()
+	deadlineCtx, cancelDeadline := context.WithTimeout(userCtx("alice"), 10*time.Millisecond)
+	defer cancelDeadline()
+
+	for ctx, want := range map[context.Context]codes.Code{
+		canceledCtx: codes.Canceled,
+		deadlineCtx: codes.DeadlineExceeded,
+	} {
+		err := i.unary(ctx, testMethod, wrapperspb.String("req"), &wrapperspb.StringValue{}, nil, invoker)
+		if status.Code(err) != want {
+			t.Fatalf("expected %s for waiting caller, got %v", want, err)
+		}
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("first caller must get the response, got %v", err)
+	}
+}
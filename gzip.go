@@ -0,0 +1,179 @@
+package grpcsrv
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// setGzipHTTPMiddleware compresses responses with gzip if the client supports it
+// and the response body is not smaller than httpGzipMinSize.
+func (s *Service) setGzipHTTPMiddleware(next http.Handler) http.Handler {
+	if s.httpGzipMinSize < 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if !acceptsGzip(r.Header.Values("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, minSize: s.httpGzipMinSize}
+		defer func() {
+			if err := gw.close(); err != nil {
+				s.logger.Debug(r.Context(), "failed to write gzip response", "error", err)
+			}
+		}()
+
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip checks whether Accept-Encoding header values allow gzip (RFC 9110, section 12.5.3).
+// Codings with q=0 are not acceptable. Explicit gzip takes precedence over "*".
+func acceptsGzip(values []string) bool {
+	gzipQ, anyQ := -1.0, -1.0
+	for _, v := range values {
+		for _, coding := range strings.Split(v, ",") {
+			name, params, _ := strings.Cut(coding, ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+
+			q := 1.0
+			for _, p := range strings.Split(params, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(p), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(key), "q") {
+					continue
+				}
+				f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil {
+					f = 0
+				}
+				q = f
+			}
+
+			switch name {
+			case "gzip", "x-gzip":
+				gzipQ = max(gzipQ, q)
+			case "*":
+				anyQ = max(anyQ, q)
+			}
+		}
+	}
+
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+
+	return anyQ > 0
+}
+
+// gzipResponseWriter buffers response until minSize is reached and then decides whether to compress it.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize int
+
+	status  int
+	buf     []byte
+	gz      *gzip.Writer
+	decided bool // encoding is chosen and header is written
+}
+
+// WriteHeader delays sending the header until the encoding is chosen.
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	w.status = status
+}
+
+// Write writes data to the response, buffering it until the encoding is chosen.
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize {
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(b), nil
+}
+
+// Flush sends buffered data to the client.
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		_ = w.decide()
+	}
+
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the original http.ResponseWriter.
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide chooses the encoding, writes the header and buffered data.
+func (w *gzipResponseWriter) decide() error {
+	w.decided = true
+
+	h := w.Header()
+	if len(w.buf) > 0 && len(w.buf) >= w.minSize && h.Get("Content-Encoding") == "" &&
+		w.status != http.StatusNoContent && w.status != http.StatusNotModified {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+
+	return err
+}
+
+// close writes the rest of the response.
+func (w *gzipResponseWriter) close() error {
+	if !w.decided {
+		if err := w.decide(); err != nil {
+			return err
+		}
+	}
+
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+
+	return nil
+}
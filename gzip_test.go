@@ -0,0 +1,92 @@
+package grpcsrv
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "", want: false},
+		{header: "gzip", want: true},
+		{header: "GZIP", want: true},
+		{header: "deflate, gzip;q=0.5", want: true},
+		{header: "gzip;q=0", want: false},
+		{header: "gzip; q=0.000", want: false},
+		{header: "br, gzip ; q=0", want: false},
+		{header: "x-gzip", want: true},
+		{header: "*", want: true},
+		{header: "*;q=0", want: false},
+		{header: "*, gzip;q=0", want: false},
+		{header: "gzip;q=1, *;q=0", want: true},
+		{header: "notgzip", want: false},
+		{header: "identity", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := acceptsGzip([]string{tt.header}); got != tt.want {
+				t.Fatalf("expected %v for %q, got %v", tt.want, tt.header, got)
+			}
+		})
+	}
+}
+
+func TestGzipHTTPMiddleware(t *testing.T) {
+	s := New(context.Background(), nil, WithHTTPGzip(10))
+	body := strings.Repeat("a", 100)
+	handler := s.setGzipHTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, body)
+	}))
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		wantGzip       bool
+	}{
+		{name: "gzip accepted", acceptEncoding: "gzip, deflate", wantGzip: true},
+		{name: "gzip refused", acceptEncoding: "gzip;q=0, deflate"},
+		{name: "no header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			got := rec.Body.String()
+			if tt.wantGzip {
+				if rec.Header().Get("Content-Encoding") != "gzip" {
+					t.Fatal("expected gzip response")
+				}
+				zr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				b, err := io.ReadAll(zr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = string(b)
+			} else if rec.Header().Get("Content-Encoding") != "" {
+				t.Fatal("unexpected Content-Encoding")
+			}
+
+			if got != body {
+				t.Fatalf("unexpected body %q", got)
+			}
+		})
+	}
+}
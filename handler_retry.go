@@ -0,0 +1,80 @@
+package grpcsrv
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// RetryPolicy parameters of server-side handler retries.
+type RetryPolicy struct {
+	// MaxAttempts maximum number of handler calls, including the first one.
+	MaxAttempts int
+	// InitialBackoff delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff maximum delay between retries. Zero means no limit.
+	MaxBackoff time.Duration
+	// Multiplier of the delay after each retry. Values less than 1 mean constant delay.
+	Multiplier float64
+}
+
+// backoff returns delay before the retry with the given number (starting from 1).
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for i := 1; i < retry && p.Multiplier > 1; i++ {
+		d *= p.Multiplier
+		if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+			return p.MaxBackoff
+		}
+	}
+
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		return p.MaxBackoff
+	}
+
+	return time.Duration(d)
+}
+
+// interceptor for retrying handlers of idempotent methods on transient errors.
+func (s *Service) handlerRetryUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if _, ok := s.handlerRetryMethods[info.FullMethod]; !ok {
+		return handler(ctx, req)
+	}
+
+	// Each attempt gets its own copy of the request, so modifications of the handler don't affect
+	// the next attempt and the request seen by outer interceptors (e.g. payload logging).
+	ctx, original := withOriginalRequest(ctx, req)
+	attemptReq := func() any {
+		if original == nil {
+			return req
+		}
+		return proto.Clone(original)
+	}
+
+	resp, err := handler(ctx, attemptReq())
+	for attempt := 1; attempt < s.handlerRetryPolicy.MaxAttempts; attempt++ {
+		if err == nil || !s.handlerRetryIsRetriable(err) {
+			break
+		}
+
+		delay := s.handlerRetryPolicy.backoff(attempt)
+		s.logger.Warn(ctx, "retrying grpc handler",
+			"grpc_method", info.FullMethod, "attempt", attempt, "delay", delay, "error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, err // the last handler error is more informative than context error
+		case <-timer.C:
+		}
+
+		resp, err = handler(ctx, attemptReq())
+	}
+
+	return resp, err
+}
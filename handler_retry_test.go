@@ -0,0 +1,133 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		want   []time.Duration // for retries 1, 2, 3...
+	}{
+		{
+			name:   "constant",
+			policy: RetryPolicy{InitialBackoff: 10 * time.Millisecond},
+			want:   []time.Duration{10 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond},
+		},
+		{
+			name:   "exponential",
+			policy: RetryPolicy{InitialBackoff: 10 * time.Millisecond, Multiplier: 2},
+			want:   []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond},
+		},
+		{
+			name:   "max backoff",
+			policy: RetryPolicy{InitialBackoff: 10 * time.Millisecond, Multiplier: 3, MaxBackoff: 50 * time.Millisecond},
+			want:   []time.Duration{10 * time.Millisecond, 30 * time.Millisecond, 50 * time.Millisecond},
+		},
+		{
+			name:   "initial over max",
+			policy: RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 50 * time.Millisecond},
+			want:   []time.Duration{50 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.want {
+				if got := tt.policy.backoff(i + 1); got != want {
+					t.Fatalf("retry %d: expected %s, got %s", i+1, want, got)
+				}
+			}
+		})
+	}
+}
+
+var errTransient = errors.New("transient")
+
+func TestHandlerRetryUnaryInterceptor(t *testing.T) {
+	const method = "/test.Retry/Call"
+	s := New(context.Background(), nil, WithHandlerRetry([]string{method},
+		RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+		func(err error) bool { return errors.Is(err, errTransient) }))
+
+	tests := []struct {
+		name     string
+		method   string
+		errs     []error // handler results by attempt, nil after the end
+		attempts int
+		wantErr  error
+	}{
+		{name: "success after retries", method: method, errs: []error{errTransient, errTransient}, attempts: 3},
+		{
+			name: "attempts exhausted", method: method, errs: []error{errTransient, errTransient, errTransient},
+			attempts: 3, wantErr: errTransient,
+		},
+		{name: "not retriable", method: method, errs: []error{errors.New("fatal")}, attempts: 1},
+		{name: "not configured method", method: "/test.Retry/Other", errs: []error{errTransient}, attempts: 1,
+			wantErr: errTransient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			_, err := s.handlerRetryUnaryInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: tt.method},
+				func(context.Context, any) (any, error) {
+					attempts++
+					if attempts <= len(tt.errs) {
+						return nil, tt.errs[attempts-1]
+					}
+					return "ok", nil
+				})
+
+			if attempts != tt.attempts {
+				t.Fatalf("expected %d attempts, got %d", tt.attempts, attempts)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHandlerRetryContextDone(t *testing.T) {
+	const method = "/test.Retry/Call"
+	s := New(context.Background(), nil, WithHandlerRetry([]string{method},
+		RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour},
+		func(error) bool { return true }))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	handlerErr := status.Error(codes.Unavailable, "database is unavailable")
+	start := time.Now()
+	_, err := s.handlerRetryUnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method},
+		func(context.Context, any) (any, error) { return nil, handlerErr })
+
+	if time.Since(start) > time.Second {
+		t.Fatal("retry must stop waiting when context is done")
+	}
+	if !errors.Is(err, handlerErr) {
+		t.Fatalf("expected the last handler error, got %v", err)
+	}
+}
+
+func TestHandlerRetryDisabled(t *testing.T) {
+	s := New(context.Background(), nil,
+		WithHandlerRetry([]string{"/test.Retry/Call"}, RetryPolicy{MaxAttempts: 1}, func(error) bool { return true }))
+	if s.handlerRetryMethods != nil {
+		t.Fatal("retry must be disabled for single attempt")
+	}
+
+	s = New(context.Background(), nil, WithHandlerRetry([]string{"/test.Retry/Call"}, RetryPolicy{MaxAttempts: 3}, nil))
+	if s.handlerRetryMethods != nil {
+		t.Fatal("retry must be disabled without isRetriable")
+	}
+}
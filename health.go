@@ -0,0 +1,196 @@
+package grpcsrv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// readinessDebouncer wraps IHealther and debounces readiness transitions:
+// not ready is reported only after the check has been failing for failureGrace,
+// ready is reported only after the check has been successful for recoveryGrace.
+type readinessDebouncer struct {
+	IHealther
+
+	failureGrace  time.Duration
+	recoveryGrace time.Duration
+
+	mu          sync.Mutex
+	initialized bool            // the first check result has been reported
+	ready       bool            // reported state
+	changedAt   time.Time       // when the actual state started to differ from the reported one
+	lastReport  *healthResponse // last response matching the reported state
+}
+
+func newReadinessDebouncer(h IHealther, failureGrace, recoveryGrace time.Duration) *readinessDebouncer {
+	return &readinessDebouncer{
+		IHealther:     h,
+		failureGrace:  failureGrace,
+		recoveryGrace: recoveryGrace,
+	}
+}
+
+// ReadyEndpoint is an HTTP handler for the readiness endpoint with debouncing.
+func (d *readinessDebouncer) ReadyEndpoint(w http.ResponseWriter, r *http.Request) {
+	rec := newHealthResponse()
+	d.IHealther.ReadyEndpoint(rec, r)
+
+	actualReady := rec.status < http.StatusBadRequest
+
+	d.mu.Lock()
+	now := time.Now()
+	switch {
+	case !d.initialized:
+		// the first result is reported as is, there is nothing to debounce yet
+		d.initialized = true
+		d.ready = actualReady
+		d.lastReport = rec
+	case actualReady == d.ready:
+		d.changedAt = time.Time{}
+		d.lastReport = rec
+	case d.changedAt.IsZero():
+		d.changedAt = now
+	}
+
+	grace := d.failureGrace
+	if actualReady {
+		grace = d.recoveryGrace
+	}
+
+	if actualReady != d.ready && now.Sub(d.changedAt) >= grace {
+		d.ready = actualReady
+		d.changedAt = time.Time{}
+		d.lastReport = rec
+	}
+
+	report := d.lastReport
+	d.mu.Unlock()
+
+	report.writeTo(w)
+}
+
+// healthResponse records response of the health check handler.
+type healthResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newHealthResponse() *healthResponse {
+	return &healthResponse{
+		header: http.Header{},
+		status: http.StatusOK,
+	}
+}
+
+// Header returns the header map of the response.
+func (h *healthResponse) Header() http.Header {
+	return h.header
+}
+
+// Write writes data to the response body.
+func (h *healthResponse) Write(b []byte) (int, error) {
+	return h.body.Write(b)
+}
+
+// WriteHeader records the status code.
+func (h *healthResponse) WriteHeader(status int) {
+	h.status = status
+}
+
+// writeTo writes recorded response to w.
+func (h *healthResponse) writeTo(w http.ResponseWriter) {
+	for k, v := range h.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(h.status)
+	_, _ = w.Write(h.body.Bytes())
+}
+
+// healthResponseWriter wraps w to set content type of health check responses if configured.
+func (s *Service) healthResponseWriter(w http.ResponseWriter) http.ResponseWriter {
+	if s.healthContentType == "" {
+		return w
+	}
+
+	return &contentTypeWriter{ResponseWriter: w, contentType: s.healthContentType}
+}
+
+// contentTypeWriter sets Content-Type header before the response header is written.
+type contentTypeWriter struct {
+	http.ResponseWriter
+	contentType string
+	wroteHeader bool
+}
+
+// WriteHeader sends an HTTP response header with the configured content type.
+func (w *contentTypeWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.Header().Set("Content-Type", w.contentType)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes data to the response.
+func (w *contentTypeWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the original http.ResponseWriter.
+func (w *contentTypeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// registerGatewayHealthForwarding registers HTTP endpoint that reports status of gRPC health service
+// called over the gateway connection: SERVING - 200, otherwise - 503.
+func (s *Service) registerGatewayHealthForwarding(
+	ctx context.Context, mux *runtime.ServeMux, conn *grpc.ClientConn,
+) error {
+	if s.gatewayHealthPath == "" {
+		return nil
+	}
+
+	client := grpc_health_v1.NewHealthClient(conn)
+
+	handler := func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		w = s.healthResponseWriter(w)
+		if reason := s.notReadyReason(); reason != "" {
+			http.Error(w, reason, http.StatusServiceUnavailable)
+			return
+		}
+
+		resp, err := client.Check(r.Context(), &grpc_health_v1.HealthCheckRequest{Service: s.gatewayHealthService})
+		if err != nil {
+			s.logger.Debug(r.Context(), "grpc health check failed", "error", err)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+			http.Error(w, resp.GetStatus().String(), http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(resp.GetStatus().String()))
+	}
+
+	if err := mux.HandlePath(http.MethodGet, s.gatewayHealthPath, handler); err != nil {
+		return fmt.Errorf("%s. failed to register gRPC health forwarding handler %s: %w", s.name, s.gatewayHealthPath, err)
+	}
+
+	s.logger.Info(ctx, "grpc health forwarding endpoint registered", "path", s.gatewayHealthPath)
+
+	return nil
+}
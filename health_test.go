@@ -0,0 +1,167 @@
+package grpcsrv
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type testHealther struct {
+	ready bool
+}
+
+func (h *testHealther) LiveEndpoint(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *testHealther) ReadyEndpoint(w http.ResponseWriter, _ *http.Request) {
+	if !h.ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func readyStatus(d *readinessDebouncer) int {
+	rec := httptest.NewRecorder()
+	d.ReadyEndpoint(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	return rec.Code
+}
+
+func TestReadinessDebouncerFirstResult(t *testing.T) {
+	h := &testHealther{ready: false}
+	d := newReadinessDebouncer(h, time.Hour, time.Hour)
+
+	if code := readyStatus(d); code != http.StatusServiceUnavailable {
+		t.Fatalf("failing check must be reported at startup, got %d", code)
+	}
+
+	// recovery is debounced after the first result
+	h.ready = true
+	if code := readyStatus(d); code != http.StatusServiceUnavailable {
+		t.Fatalf("recovery must be debounced, got %d", code)
+	}
+}
+
+func TestReadinessDebouncerGrace(t *testing.T) {
+	h := &testHealther{ready: true}
+	d := newReadinessDebouncer(h, 50*time.Millisecond, 0)
+
+	if code := readyStatus(d); code != http.StatusOK {
+		t.Fatalf("expected ready, got %d", code)
+	}
+
+	h.ready = false
+	if code := readyStatus(d); code != http.StatusOK {
+		t.Fatalf("failure must be reported after grace period, got %d", code)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if code := readyStatus(d); code != http.StatusServiceUnavailable {
+		t.Fatalf("failure must be reported after grace period, got %d", code)
+	}
+
+	h.ready = true
+	if code := readyStatus(d); code != http.StatusOK {
+		t.Fatalf("recovery without grace must be reported immediately, got %d", code)
+	}
+}
+
+// textHealther IHealther, which writes text responses.
+type textHealther struct{}
+
+func (textHealther) LiveEndpoint(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = w.Write([]byte(`{"status":"ok"}`))
+}
+
+func (textHealther) ReadyEndpoint(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusServiceUnavailable)
+}
+
+func TestHealthContentType(t *testing.T) {
+	s := New(context.Background(), nil, WithHealthCheck(textHealther{}, "/live", "/ready"),
+		WithHealthContentType("application/json"))
+
+	rec := serveHealth(t, s, "/live")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected 200 application/json, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	rec = serveHealth(t, s, "/ready")
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected 503 application/json, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	// content type of IHealther is kept without the option
+	s = New(context.Background(), nil, WithHealthCheck(textHealther{}, "/live", "/ready"))
+	rec = serveHealth(t, s, "/live")
+	if rec.Header().Get("Content-Type") != "text/plain" {
+		t.Fatalf("expected text/plain, got %q", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestHealthPaths(t *testing.T) {
+	_, baseURL := startGatewayTestService(t, &testGreeter{},
+		WithHealthCheck(&testHealther{ready: false}, "/live", "/ready"),
+		WithHealthPaths([]HealthPath{
+			{Path: "/livez"},
+			{Path: "/readyz", Readiness: true},
+			{Path: "/ready", Readiness: true}, // duplicate of the main path
+		}))
+
+	for path, want := range map[string]int{
+		"/live":   http.StatusOK,
+		"/livez":  http.StatusOK,
+		"/ready":  http.StatusServiceUnavailable,
+		"/readyz": http.StatusServiceUnavailable,
+	} {
+		if code := httpGetStatus(t, baseURL+path); code != want {
+			t.Fatalf("expected %d for %s, got %d", want, path, code)
+		}
+	}
+}
+
+func TestHealthPathsConflict(t *testing.T) {
+	s := New(context.Background(), nil, WithHealthCheck(&testHealther{}, "/live", "/ready"),
+		WithHealthPaths([]HealthPath{{Path: "/live", Readiness: true}}))
+
+	if err := s.registerHealthCheckEndpoints(context.Background(), runtime.NewServeMux()); err == nil {
+		t.Fatal("expected error for path used for both liveness and readiness")
+	}
+}
+
+func TestGatewayHealthForwarding(t *testing.T) {
+	initializer := newHealthInitializer()
+	httpAddr := freeAddr(t)
+	startTestServiceWith(t, []IGRPCInitializer{initializer},
+		WithEndpoint(Endpoint{GRPC: freeAddr(t), HTTP: httpAddr}),
+		WithGatewayHealthForwarding("/grpc-health", ""))
+	url := "http://" + httpAddr + "/grpc-health"
+
+	if code := httpGetStatus(t, url); code != http.StatusOK {
+		t.Fatalf("expected 200 for SERVING, got %d", code)
+	}
+
+	initializer.health.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	if code := httpGetStatus(t, url); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 for NOT_SERVING, got %d", code)
+	}
+}
+
+func TestGatewayHealthForwardingUnknownService(t *testing.T) {
+	httpAddr := freeAddr(t)
+	startTestServiceWith(t, []IGRPCInitializer{newHealthInitializer()},
+		WithEndpoint(Endpoint{GRPC: freeAddr(t), HTTP: httpAddr}),
+		WithGatewayHealthForwarding("/grpc-health", "missing.Service"))
+
+	if code := httpGetStatus(t, "http://"+httpAddr+"/grpc-health"); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 for unknown service, got %d", code)
+	}
+}
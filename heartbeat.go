@@ -0,0 +1,191 @@
+package grpcsrv
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// heartbeatServerStream serializes all writes of the handler (messages, header, trailer) and heartbeats,
+// and tracks the time of the last sent message.
+type heartbeatServerStream struct {
+	grpc.ServerStream
+
+	mu         sync.Mutex
+	lastSend   time.Time
+	headerSent bool // by the handler, explicitly or with the first message
+}
+
+// SendMsg implements grpc.ServerStream.
+func (h *heartbeatServerStream) SendMsg(m any) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	err := h.ServerStream.SendMsg(m)
+	h.lastSend = time.Now()
+	h.headerSent = true
+
+	return err
+}
+
+// RecvMsg implements grpc.ServerStream. Heartbeats are sent only to server streams without client streaming,
+// so the request is received once and doesn't block heartbeats for long.
+func (h *heartbeatServerStream) RecvMsg(m any) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.ServerStream.RecvMsg(m)
+}
+
+// SetHeader implements grpc.ServerStream.
+func (h *heartbeatServerStream) SetHeader(md metadata.MD) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.ServerStream.SetHeader(md)
+}
+
+// SendHeader implements grpc.ServerStream.
+func (h *heartbeatServerStream) SendHeader(md metadata.MD) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	err := h.ServerStream.SendHeader(md)
+	if err == nil {
+		h.headerSent = true
+	}
+
+	return err
+}
+
+// SetTrailer implements grpc.ServerStream.
+func (h *heartbeatServerStream) SetTrailer(md metadata.MD) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.ServerStream.SetTrailer(md)
+}
+
+// sendHeartbeat sends heartbeat message if no messages were sent during the interval.
+// Returns time until the next heartbeat. Heartbeats are not sent until the handler sends the header,
+// otherwise the heartbeat would send it and the subsequent SetHeader and SendHeader of the handler would fail.
+func (h *heartbeatServerStream) sendHeartbeat(interval time.Duration, msgFactory func() proto.Message,
+) (time.Duration, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.headerSent {
+		return interval, nil
+	}
+	if idle := time.Since(h.lastSend); idle < interval {
+		return interval - idle, nil
+	}
+
+	err := h.ServerStream.SendMsg(msgFactory())
+	h.lastSend = time.Now()
+
+	return interval, err
+}
+
+// heartbeatCompatible reports whether heartbeat message has the response type of the method.
+// Methods are resolved in the global protobuf registry, the result is cached.
+func (s *Service) heartbeatCompatible(ctx context.Context, fullMethod string) bool {
+	if v, ok := s.heartbeatCompat.Load(fullMethod); ok {
+		compatible, _ := v.(bool)
+		return compatible
+	}
+
+	heartbeatType := s.heartbeatMsgFactory().ProtoReflect().Descriptor().FullName()
+	outputType, err := methodOutputType(fullMethod)
+	compatible := err == nil && outputType == heartbeatType
+	if !compatible {
+		s.logger.Warn(ctx, "stream heartbeats are disabled for method: response type doesn't match heartbeat message",
+			"grpc_method", fullMethod, "heartbeat_type", heartbeatType, "response_type", outputType, "error", err)
+	}
+
+	s.heartbeatCompat.Store(fullMethod, compatible)
+
+	return compatible
+}
+
+// methodOutputType returns response type of the method from the global protobuf registry.
+func methodOutputType(fullMethod string) (protoreflect.FullName, error) {
+	service, method := splitFullMethod(fullMethod)
+
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return "", fmt.Errorf("service descriptor not found: %w", err)
+	}
+
+	serviceDesc, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return "", fmt.Errorf("%s is not a service", service)
+	}
+
+	methodDesc := serviceDesc.Methods().ByName(protoreflect.Name(method))
+	if methodDesc == nil {
+		return "", fmt.Errorf("method %s not found", fullMethod)
+	}
+
+	return methodDesc.Output().FullName(), nil
+}
+
+// interceptor for sending heartbeats to idle server streams.
+func (s *Service) heartbeatStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if !info.IsServerStream || info.IsClientStream {
+		return handler(srv, ss)
+	}
+	if _, ok := s.heartbeatMethods[info.FullMethod]; !ok {
+		return handler(srv, ss)
+	}
+	if !s.heartbeatCompatible(ss.Context(), info.FullMethod) {
+		return handler(srv, ss)
+	}
+
+	hs := &heartbeatServerStream{ServerStream: ss, lastSend: time.Now()}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		// the timer fires when the interval elapses since the last sent message
+		timer := time.NewTimer(s.heartbeatInterval)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ss.Context().Done():
+				return
+			case <-timer.C:
+				next, err := hs.sendHeartbeat(s.heartbeatInterval, s.heartbeatMsgFactory)
+				if err != nil {
+					s.logger.Debug(ss.Context(), "failed to send stream heartbeat",
+						"grpc_method", info.FullMethod, "error", err)
+					return
+				}
+				timer.Reset(next)
+			}
+		}
+	}()
+
+	err := handler(srv, hs)
+
+	// no heartbeats after the handler returns
+	close(done)
+	wg.Wait()
+
+	return err
+}
@@ -0,0 +1,159 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const healthWatchMethod = "/grpc.health.v1.Health/Watch"
+
+func heartbeatCount(t *testing.T, msgFactory func() proto.Message, info *grpc.StreamServerInfo) int {
+	t.Helper()
+
+	s := New(context.Background(), nil, WithStreamHeartbeat(20*time.Millisecond, msgFactory, info.FullMethod))
+	ss := &testServerStream{}
+
+	handler := func(_ any, stream grpc.ServerStream) error {
+		// header and trailer writes are serialized with heartbeats
+		for range 5 {
+			_ = stream.SendHeader(metadata.Pairs("k", "v"))
+			stream.SetTrailer(metadata.Pairs("k", "v"))
+			time.Sleep(15 * time.Millisecond)
+		}
+		return nil
+	}
+
+	if err := s.heartbeatStreamInterceptor(nil, ss, info, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	return len(ss.sent)
+}
+
+func TestStreamHeartbeatCompatibleMethod(t *testing.T) {
+	n := heartbeatCount(t,
+		func() proto.Message { return &grpc_health_v1.HealthCheckResponse{} },
+		&grpc.StreamServerInfo{FullMethod: healthWatchMethod, IsServerStream: true})
+	if n == 0 {
+		t.Fatal("expected heartbeats for idle stream")
+	}
+}
+
+func TestStreamHeartbeatIncompatibleType(t *testing.T) {
+	n := heartbeatCount(t,
+		func() proto.Message { return wrapperspb.String("ping") },
+		&grpc.StreamServerInfo{FullMethod: healthWatchMethod, IsServerStream: true})
+	if n != 0 {
+		t.Fatalf("expected no heartbeats for response type mismatch, got %d", n)
+	}
+}
+
+func TestStreamHeartbeatBidiStream(t *testing.T) {
+	n := heartbeatCount(t,
+		func() proto.Message { return &grpc_health_v1.HealthCheckResponse{} },
+		&grpc.StreamServerInfo{FullMethod: healthWatchMethod, IsServerStream: true, IsClientStream: true})
+	if n != 0 {
+		t.Fatalf("expected no heartbeats for bidirectional stream, got %d", n)
+	}
+}
+
+func TestStreamHeartbeatInterval(t *testing.T) {
+	const interval = 100 * time.Millisecond
+	s := New(context.Background(), nil, WithStreamHeartbeat(interval,
+		func() proto.Message { return &grpc_health_v1.HealthCheckResponse{} }, healthWatchMethod))
+
+	var sendTimes []time.Time
+	ss := &testServerStream{send: func(any) error {
+		sendTimes = append(sendTimes, time.Now())
+		return nil
+	}}
+
+	handler := func(_ any, stream grpc.ServerStream) error {
+		if err := stream.SendMsg(&grpc_health_v1.HealthCheckResponse{}); err != nil {
+			return err
+		}
+		time.Sleep(4*interval + interval/2)
+		return nil
+	}
+
+	err := s.heartbeatStreamInterceptor(nil, ss,
+		&grpc.StreamServerInfo{FullMethod: healthWatchMethod, IsServerStream: true}, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sendTimes) < 4 {
+		t.Fatalf("expected message and at least 3 heartbeats, got %d", len(sendTimes))
+	}
+	// idle time is close to the interval
+	for i := 1; i < len(sendTimes); i++ {
+		if gap := sendTimes[i].Sub(sendTimes[i-1]); gap > interval+interval/4 {
+			t.Fatalf("idle gap %s exceeds interval %s", gap, interval)
+		}
+	}
+}
+
+// heartbeatHeaderStream testServerStream, which fails SetHeader and SendHeader after the header is sent.
+type heartbeatHeaderStream struct {
+	testServerStream
+	headerSent bool
+}
+
+func (s *heartbeatHeaderStream) SetHeader(metadata.MD) error {
+	if s.headerSent {
+		return errors.New("header already sent")
+	}
+	return nil
+}
+
+func (s *heartbeatHeaderStream) SendHeader(metadata.MD) error {
+	if s.headerSent {
+		return errors.New("header already sent")
+	}
+	s.headerSent = true
+	return nil
+}
+
+func (s *heartbeatHeaderStream) SendMsg(m any) error {
+	s.headerSent = true
+	return s.testServerStream.SendMsg(m)
+}
+
+func TestStreamHeartbeatWaitsForHeader(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	s := New(context.Background(), nil, WithStreamHeartbeat(interval,
+		func() proto.Message { return &grpc_health_v1.HealthCheckResponse{} }, healthWatchMethod))
+
+	ss := &heartbeatHeaderStream{}
+	handler := func(_ any, stream grpc.ServerStream) error {
+		// header is set after several intervals
+		time.Sleep(3 * interval)
+		if err := stream.SetHeader(metadata.Pairs("k", "v")); err != nil {
+			t.Fatalf("SetHeader must not fail because of heartbeats: %v", err)
+		}
+		if err := stream.SendHeader(nil); err != nil {
+			t.Fatalf("SendHeader must not fail because of heartbeats: %v", err)
+		}
+
+		time.Sleep(3 * interval)
+		return nil
+	}
+
+	err := s.heartbeatStreamInterceptor(nil, ss,
+		&grpc.StreamServerInfo{FullMethod: healthWatchMethod, IsServerStream: true}, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ss.sent) == 0 {
+		t.Fatal("expected heartbeats after the header is sent")
+	}
+}
@@ -0,0 +1,53 @@
+package grpcsrv
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// httpChain builds HTTP gateway handler from middlewares and records their names for debugging.
+type httpChain struct {
+	s      *Service
+	h      http.Handler
+	layers []string // from inner to outer
+}
+
+func (s *Service) newHTTPChain(h http.Handler) *httpChain {
+	return &httpChain{s: s, h: h, layers: []string{"mux"}}
+}
+
+// use wraps the handler with middleware if it is enabled.
+func (c *httpChain) use(name string, enabled bool, mw func(http.Handler) http.Handler) {
+	if !enabled {
+		return
+	}
+
+	c.h = mw(c.h)
+	if c.s.httpChainTiming {
+		c.h = c.s.timingHTTPMiddleware(name, c.h)
+	}
+	c.layers = append(c.layers, name)
+}
+
+// handler returns composed handler and logs the chain if inspector is enabled.
+func (c *httpChain) handler(ctx context.Context) http.Handler {
+	if c.s.httpChainInspector {
+		outerToInner := slices.Clone(c.layers)
+		slices.Reverse(outerToInner)
+		c.s.logger.Info(ctx, "http handler chain", "layers", outerToInner)
+	}
+
+	return c.h
+}
+
+// timingHTTPMiddleware logs time spent in the layer including all inner layers.
+func (s *Service) timingHTTPMiddleware(name string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		s.logger.Debug(r.Context(), "http handler layer",
+			"layer", name, "path", r.URL.Path, "duration", time.Since(start))
+	})
+}
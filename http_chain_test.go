@@ -0,0 +1,76 @@
+package grpcsrv
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestHTTPChain(t *testing.T) {
+	logger := &testLogger{}
+	s := New(context.Background(), nil, WithLogger(logger), WithHTTPHandlerChainInspector(true))
+
+	var calls []string
+	layer := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	chain := s.newHTTPChain(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		calls = append(calls, "mux")
+	}))
+	chain.use("inner", true, layer("inner"))
+	chain.use("disabled", false, layer("disabled"))
+	chain.use("outer", true, layer("outer"))
+
+	chain.handler(context.Background()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !slices.Equal(calls, []string{"outer", "inner", "mux"}) {
+		t.Fatalf("unexpected call order %v", calls)
+	}
+
+	e, ok := logger.find("http handler chain")
+	if !ok {
+		t.Fatal("chain is not logged")
+	}
+	if layers, _ := e.arg("layers"); !slices.Equal(toStrings(layers), []string{"outer", "inner", "mux"}) {
+		t.Fatalf("unexpected logged layers %v", layers)
+	}
+
+	timing := 0
+	for _, e := range logger.entries {
+		if e.msg == "http handler layer" {
+			timing++
+		}
+	}
+	if timing != 2 {
+		t.Fatalf("expected timing of 2 layers, got %d", timing)
+	}
+}
+
+func toStrings(v any) []string {
+	s, _ := v.([]string)
+	return s
+}
+
+func TestHTTPChainWithoutInspector(t *testing.T) {
+	logger := &testLogger{}
+	s := New(context.Background(), nil, WithLogger(logger))
+
+	chain := s.newHTTPChain(http.NotFoundHandler())
+	chain.use("layer", true, func(next http.Handler) http.Handler { return next })
+	chain.handler(context.Background()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if _, ok := logger.find("http handler chain"); ok {
+		t.Fatal("chain must not be logged without inspector")
+	}
+	if _, ok := logger.find("http handler layer"); ok {
+		t.Fatal("timing must not be logged without inspector")
+	}
+}
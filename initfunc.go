@@ -0,0 +1,66 @@
+package grpcsrv
+
+import (
+	"context"
+	"fmt"
+)
+
+// runInitFunc runs the function set by WithInitFunc. Called by Start before listeners start.
+func (s *Service) runInitFunc(ctx context.Context) error {
+	if s.initFunc == nil {
+		return nil
+	}
+
+	s.logger.Info(ctx, "running init function")
+	if err := s.initFunc(ctx); err != nil {
+		return fmt.Errorf("%s. init function failed: %w", s.name, err)
+	}
+	s.logger.Info(ctx, "init function completed")
+
+	return nil
+}
+
+// startAsyncInitFunc runs the function set by WithAsyncInitFunc in background.
+// Readiness endpoints report not ready until the function completes successfully.
+// The function context is canceled by Stop.
+func (s *Service) startAsyncInitFunc(ctx context.Context) {
+	if s.asyncInitFunc == nil {
+		return
+	}
+
+	ctx, s.asyncInitCancel = context.WithCancel(ctx)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		s.logger.Info(ctx, "running async init function")
+		if err := s.asyncInitFunc(ctx); err != nil {
+			// service stays not ready
+			s.logger.Error(ctx, "async init function failed", "error", err)
+			return
+		}
+
+		s.initializing.Store(false)
+		s.logger.Info(ctx, "async init function completed")
+	}()
+}
+
+// stopAsyncInitFunc cancels the function set by WithAsyncInitFunc if it is still running.
+func (s *Service) stopAsyncInitFunc() {
+	if s.asyncInitCancel != nil {
+		s.asyncInitCancel()
+	}
+}
+
+// notReadyReason returns the reason why readiness endpoints must report not ready, empty if there is no such reason.
+func (s *Service) notReadyReason() string {
+	switch {
+	case s.draining.Load():
+		return "draining"
+	case s.initializing.Load():
+		return "initializing"
+	default:
+		return ""
+	}
+}
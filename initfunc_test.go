@@ -0,0 +1,149 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestInitFunc(t *testing.T) {
+	grpcAddr := freeAddr(t)
+	var bound bool
+	s := New(context.Background(), []IGRPCInitializer{newHealthInitializer()},
+		WithEndpoint(Endpoint{GRPC: grpcAddr}),
+		WithInitFunc(func(context.Context) error {
+			// listeners are not started yet, so the port is free
+			l, err := net.Listen("tcp", grpcAddr)
+			if err != nil {
+				bound = true
+				return nil
+			}
+			return l.Close()
+		}))
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Stop(context.Background()) })
+
+	if bound {
+		t.Fatal("init function must run before listeners start")
+	}
+
+	errInit := errors.New("migration failed")
+	s = New(context.Background(), []IGRPCInitializer{newHealthInitializer()},
+		WithEndpoint(Endpoint{GRPC: freeAddr(t)}),
+		WithInitFunc(func(context.Context) error { return errInit }))
+	if err := s.Start(context.Background()); !errors.Is(err, errInit) {
+		t.Fatalf("expected init error, got %v", err)
+	}
+}
+
+func TestInitFuncStartTimeout(t *testing.T) {
+	s := New(context.Background(), []IGRPCInitializer{newHealthInitializer()},
+		WithEndpoint(Endpoint{GRPC: freeAddr(t)}),
+		WithInitFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	errStart := make(chan error, 1)
+	go func() { errStart <- s.Start(ctx) }()
+
+	select {
+	case err := <-errStart:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected deadline exceeded, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start must return when its context is done during init")
+	}
+}
+
+func TestAsyncInitFunc(t *testing.T) {
+	release := make(chan error)
+	s := startTestService(t, WithHealthCheck(&testHealther{ready: true}, "/live", "/ready"),
+		WithAsyncInitFunc(func(ctx context.Context) error {
+			select {
+			case err := <-release:
+				return err
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}))
+
+	if code := healthStatus(t, s, "/ready"); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected not ready while initializing, got %d", code)
+	}
+	if code := healthStatus(t, s, "/live"); code != http.StatusOK {
+		t.Fatalf("liveness must not be affected by initialization, got %d", code)
+	}
+
+	release <- nil
+	waitReadiness(t, s, http.StatusOK)
+}
+
+func TestAsyncInitFuncError(t *testing.T) {
+	logger := &testLogger{}
+	s := startTestService(t, WithLogger(logger), WithHealthCheck(&testHealther{ready: true}, "/live", "/ready"),
+		WithAsyncInitFunc(func(context.Context) error { return errors.New("warm-up failed") }))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := logger.find("async init function failed"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected async init failure to be logged")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if code := healthStatus(t, s, "/ready"); code != http.StatusServiceUnavailable {
+		t.Fatalf("service must stay not ready after failed init, got %d", code)
+	}
+}
+
+func TestAsyncInitFuncCanceledByStop(t *testing.T) {
+	canceled := make(chan struct{})
+	s := New(context.Background(), []IGRPCInitializer{newHealthInitializer()},
+		WithEndpoint(Endpoint{GRPC: freeAddr(t)}),
+		WithAsyncInitFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			close(canceled)
+			return ctx.Err()
+		}))
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-canceled:
+	default:
+		t.Fatal("Stop must cancel and wait for async init function")
+	}
+}
+
+// waitReadiness waits until readiness endpoint returns the code.
+func waitReadiness(t *testing.T, s *Service, code int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for healthStatus(t, s, "/ready") != code {
+		if time.Now().After(deadline) {
+			t.Fatalf("readiness did not return %d", code)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,118 @@
+package grpcsrv
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// inprocessTarget target of the gateway connection to in-process gRPC server.
+const inprocessTarget = "passthrough:///inprocess"
+
+// inprocessNetwork network name of in-process connections, returned by their addresses.
+const inprocessNetwork = "pipe"
+
+// pipeListener is in-memory listener, connections are created by DialContext with net.Pipe.
+type pipeListener struct {
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+// Accept waits for and returns the next connection to the listener.
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close closes the listener. Connections that are already accepted are not closed.
+func (l *pipeListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return nil
+}
+
+// Addr returns the listener's network address.
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+// DialContext creates connection to the listener, it waits until the connection is accepted.
+func (l *pipeListener) DialContext(ctx context.Context) (net.Conn, error) {
+	serverConn, clientConn := net.Pipe()
+
+	select {
+	case l.conns <- &pipeConn{Conn: serverConn}:
+		return &pipeConn{Conn: clientConn}, nil
+	case <-l.done:
+		_ = serverConn.Close()
+		_ = clientConn.Close()
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		_ = serverConn.Close()
+		_ = clientConn.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// pipeConn reports in-process addresses instead of the addresses of net.Pipe.
+type pipeConn struct {
+	net.Conn
+}
+
+// LocalAddr returns the local network address.
+func (c *pipeConn) LocalAddr() net.Addr {
+	return pipeAddr{}
+}
+
+// RemoteAddr returns the remote network address.
+func (c *pipeConn) RemoteAddr() net.Addr {
+	return pipeAddr{}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return inprocessNetwork }
+func (pipeAddr) String() string  { return "inprocess" }
+
+// startInprocessListener serves gRPC server on in-memory listener for the gateway.
+func (s *Service) startInprocessListener(_ context.Context) {
+	if !s.inprocessGateway {
+		return
+	}
+
+	s.inprocessListener = newPipeListener()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if errServe := s.grpcServer.Serve(s.inprocessListener); errServe != nil {
+			panic(s.name + ". failed to serve in-process gRPC server: " + errServe.Error())
+		}
+	}()
+}
+
+// gatewayTarget returns target and dial options for the gateway connection to gRPC server.
+func (s *Service) gatewayTarget() (string, []grpc.DialOption) {
+	if s.inprocessListener == nil {
+		return s.endpoint.GRPC, nil
+	}
+
+	return inprocessTarget, []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.inprocessListener.DialContext(ctx)
+		}),
+	}
+}
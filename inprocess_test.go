@@ -0,0 +1,83 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPipeListener(t *testing.T) {
+	l := newPipeListener()
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	client, err := l.DialContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	server := <-accepted
+	defer server.Close()
+
+	if client.RemoteAddr().Network() != inprocessNetwork || server.LocalAddr().Network() != inprocessNetwork ||
+		l.Addr().Network() != inprocessNetwork {
+		t.Fatal("unexpected network of in-process addresses")
+	}
+
+	go func() { _, _ = client.Write([]byte("ping")) }()
+
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(server, buf); err != nil || string(buf) != "ping" {
+		t.Fatalf("unexpected data %q: %v", buf, err)
+	}
+}
+
+func TestPipeListenerDialContextDone(t *testing.T) {
+	l := newPipeListener()
+	defer l.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// nobody accepts the connection
+	if _, err := l.DialContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestPipeListenerClose(t *testing.T) {
+	l := newPipeListener()
+
+	acceptErr := make(chan error, 1)
+	go func() {
+		_, err := l.Accept()
+		acceptErr <- err
+	}()
+
+	_ = l.Close()
+	_ = l.Close() // double close must not panic
+
+	select {
+	case err := <-acceptErr:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Fatalf("expected net.ErrClosed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Accept must return after Close")
+	}
+
+	if _, err := l.DialContext(context.Background()); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("expected net.ErrClosed, got %v", err)
+	}
+}
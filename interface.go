@@ -5,9 +5,11 @@ package grpcsrv
 import (
 	"context"
 	"net/http"
+	"time"
 
 	grpc_runtime "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 )
 
 // InitializeOptions options for gRPC server initialization.
@@ -30,6 +32,13 @@ type IGRPCInitializer interface {
 	GetOptions() InitializeOptions
 }
 
+// IExtraHTTPRegistrar can be optionally implemented by IGRPCInitializer to register
+// hand-written HTTP routes. Called regardless of InitializeOptions.HTTPHandlerRequired.
+type IExtraHTTPRegistrar interface {
+	// RegisterExtraHTTP registers additional HTTP routes.
+	RegisterExtraHTTP(*grpc_runtime.ServeMux) error
+}
+
 // IHealther allows adding liveness and readiness checks.
 type IHealther interface {
 	// LiveEndpoint is an HTTP handler only for the /liveness endpoint, which
@@ -40,3 +49,19 @@ type IHealther interface {
 	// is useful if you need to add it to your own HTTP handler tree.
 	ReadyEndpoint(http.ResponseWriter, *http.Request)
 }
+
+// AuditRecord audit log record of a gRPC call.
+type AuditRecord struct {
+	Method     string     // full gRPC method name
+	Principal  string     // caller identity
+	RemoteAddr string     // caller IP address
+	TraceID    string     // trace ID of the call
+	Time       time.Time  // call start time
+	Code       codes.Code // call result
+}
+
+// IAuditSink receives audit log records.
+type IAuditSink interface {
+	// Audit saves audit log record.
+	Audit(context.Context, AuditRecord)
+}
@@ -82,6 +82,43 @@ func (mr *MockIGRPCInitializerMockRecorder) RegisterHTTPHandler(arg0, arg1, arg2
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterHTTPHandler", reflect.TypeOf((*MockIGRPCInitializer)(nil).RegisterHTTPHandler), arg0, arg1, arg2)
 }
 
+// MockIExtraHTTPRegistrar is a mock of IExtraHTTPRegistrar interface.
+type MockIExtraHTTPRegistrar struct {
+	ctrl     *gomock.Controller
+	recorder *MockIExtraHTTPRegistrarMockRecorder
+}
+
+// MockIExtraHTTPRegistrarMockRecorder is the mock recorder for MockIExtraHTTPRegistrar.
+type MockIExtraHTTPRegistrarMockRecorder struct {
+	mock *MockIExtraHTTPRegistrar
+}
+
+// NewMockIExtraHTTPRegistrar creates a new mock instance.
+func NewMockIExtraHTTPRegistrar(ctrl *gomock.Controller) *MockIExtraHTTPRegistrar {
+	mock := &MockIExtraHTTPRegistrar{ctrl: ctrl}
+	mock.recorder = &MockIExtraHTTPRegistrarMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIExtraHTTPRegistrar) EXPECT() *MockIExtraHTTPRegistrarMockRecorder {
+	return m.recorder
+}
+
+// RegisterExtraHTTP mocks base method.
+func (m *MockIExtraHTTPRegistrar) RegisterExtraHTTP(arg0 *runtime.ServeMux) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterExtraHTTP", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RegisterExtraHTTP indicates an expected call of RegisterExtraHTTP.
+func (mr *MockIExtraHTTPRegistrarMockRecorder) RegisterExtraHTTP(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterExtraHTTP", reflect.TypeOf((*MockIExtraHTTPRegistrar)(nil).RegisterExtraHTTP), arg0)
+}
+
 // MockIHealther is a mock of IHealther interface.
 type MockIHealther struct {
 	ctrl     *gomock.Controller
@@ -128,3 +165,38 @@ func (mr *MockIHealtherMockRecorder) ReadyEndpoint(arg0, arg1 any) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadyEndpoint", reflect.TypeOf((*MockIHealther)(nil).ReadyEndpoint), arg0, arg1)
 }
+
+// MockIAuditSink is a mock of IAuditSink interface.
+type MockIAuditSink struct {
+	ctrl     *gomock.Controller
+	recorder *MockIAuditSinkMockRecorder
+}
+
+// MockIAuditSinkMockRecorder is the mock recorder for MockIAuditSink.
+type MockIAuditSinkMockRecorder struct {
+	mock *MockIAuditSink
+}
+
+// NewMockIAuditSink creates a new mock instance.
+func NewMockIAuditSink(ctrl *gomock.Controller) *MockIAuditSink {
+	mock := &MockIAuditSink{ctrl: ctrl}
+	mock.recorder = &MockIAuditSinkMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIAuditSink) EXPECT() *MockIAuditSinkMockRecorder {
+	return m.recorder
+}
+
+// Audit mocks base method.
+func (m *MockIAuditSink) Audit(arg0 context.Context, arg1 AuditRecord) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Audit", arg0, arg1)
+}
+
+// Audit indicates an expected call of Audit.
+func (mr *MockIAuditSinkMockRecorder) Audit(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Audit", reflect.TypeOf((*MockIAuditSink)(nil).Audit), arg0, arg1)
+}
@@ -0,0 +1,104 @@
+package grpcsrv
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/proto"
+)
+
+// jsonArrayWriter converts delimited messages of server stream written by grpc-gateway
+// into a JSON array. Each message is written by grpc-gateway in two calls: the message and the delimiter.
+type jsonArrayWriter struct {
+	http.ResponseWriter
+
+	enabled         bool // set by streamJSONArrayModifier for configured methods
+	started         bool
+	expectDelimiter bool
+}
+
+// Write writes message as array element and drops delimiters.
+func (w *jsonArrayWriter) Write(b []byte) (int, error) {
+	if !w.enabled {
+		return w.ResponseWriter.Write(b)
+	}
+
+	if w.expectDelimiter {
+		w.expectDelimiter = false
+		return len(b), nil
+	}
+
+	prefix := ","
+	if !w.started {
+		prefix = "["
+		w.started = true
+	}
+	if _, err := w.ResponseWriter.Write([]byte(prefix)); err != nil {
+		return 0, err
+	}
+
+	w.expectDelimiter = true
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher, required by grpc-gateway for streaming.
+func (w *jsonArrayWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the original http.ResponseWriter.
+func (w *jsonArrayWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish closes JSON array.
+func (w *jsonArrayWriter) finish() {
+	if !w.enabled {
+		return
+	}
+
+	if !w.started {
+		_, _ = w.ResponseWriter.Write([]byte("[]"))
+		return
+	}
+
+	_, _ = w.ResponseWriter.Write([]byte("]"))
+}
+
+// setStreamJSONArrayHTTPMiddleware prepares responses for conversion of server streams into JSON arrays.
+func (s *Service) setStreamJSONArrayHTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		aw := &jsonArrayWriter{ResponseWriter: w}
+		next.ServeHTTP(aw, r)
+		aw.finish()
+	})
+}
+
+// streamJSONArrayModifier enables JSON array output for configured streaming methods.
+// Called by grpc-gateway before writing each message.
+func (s *Service) streamJSONArrayModifier(ctx context.Context, w http.ResponseWriter, _ proto.Message) error {
+	method, ok := runtime.RPCMethod(ctx)
+	if !ok {
+		return nil
+	}
+	if _, ok = s.streamJSONArrayMethods[method]; !ok {
+		return nil
+	}
+
+	for {
+		if aw, ok := w.(*jsonArrayWriter); ok {
+			aw.enabled = true
+			return nil
+		}
+
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = u.Unwrap()
+	}
+}
@@ -0,0 +1,68 @@
+package grpcsrv
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	api "github.com/n-r-w/grpcsrv/example/protogen"
+)
+
+func TestJSONArrayWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		enabled  bool
+		messages []string
+		want     string
+	}{
+		{name: "empty stream", enabled: true, want: "[]"},
+		{name: "single message", enabled: true, messages: []string{`{"a":1}`}, want: `[{"a":1}]`},
+		{name: "several messages", enabled: true, messages: []string{`{"a":1}`, `{"a":2}`}, want: `[{"a":1},{"a":2}]`},
+		{name: "disabled", messages: []string{`{"a":1}`}, want: "{\"a\":1}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			w := &jsonArrayWriter{ResponseWriter: rec, enabled: tt.enabled}
+
+			for _, m := range tt.messages {
+				if _, err := w.Write([]byte(m)); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := w.Write([]byte("\n")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			w.finish()
+
+			if got := rec.Body.String(); got != tt.want {
+				t.Fatalf("expected body %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStreamAsJSONArrayGateway(t *testing.T) {
+	for _, count := range []int{0, 3} {
+		t.Run(fmt.Sprintf("%d messages", count), func(t *testing.T) {
+			_, baseURL := startGatewayTestService(t, &testGreeter{count: count},
+				WithStreamAsJSONArray("/"+api.Greeter_ServiceDesc.ServiceName+"/SayManyHellos"))
+
+			resp, body := doHTTP(t, http.MethodPost, baseURL+"/v1/greeter:SayManyHellos", `{"name":"bob"}`, nil)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected 200, got %d %s", resp.StatusCode, body)
+			}
+
+			var items []map[string]any
+			if err := json.Unmarshal([]byte(body), &items); err != nil {
+				t.Fatalf("response is not a JSON array: %v, %q", err, body)
+			}
+			if len(items) != count {
+				t.Fatalf("expected %d elements, got %d", count, len(items))
+			}
+		})
+	}
+}
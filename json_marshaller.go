@@ -0,0 +1,147 @@
+package grpcsrv
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// unknownFieldRe extracts field name from protojson unknown field error.
+var unknownFieldRe = regexp.MustCompile(`unknown field "([^"]+)"`)
+
+// customJSONPb JSON marshaller of HTTP gateway with additional request body processing.
+type customJSONPb struct {
+	*runtime.JSONPb
+
+	// report unknown fields in request body with their names
+	strict bool
+	// accept enum values in any case
+	caseInsensitiveEnums bool
+}
+
+// Unmarshal unmarshals JSON data into v.
+func (m *customJSONPb) Unmarshal(data []byte, v any) error {
+	data, err := m.preprocess(data, v)
+	if err != nil {
+		return err
+	}
+
+	return m.unmarshalError(m.JSONPb.Unmarshal(data, v))
+}
+
+// NewDecoder returns a Decoder which reads JSON stream from r.
+func (m *customJSONPb) NewDecoder(r io.Reader) runtime.Decoder {
+	if !m.caseInsensitiveEnums {
+		dec := m.JSONPb.NewDecoder(r)
+		return runtime.DecoderFunc(func(v any) error {
+			return m.unmarshalError(dec.Decode(v))
+		})
+	}
+
+	// values have to be preprocessed before unmarshalling, so read them as raw JSON
+	dec := json.NewDecoder(r)
+	return runtime.DecoderFunc(func(v any) error {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+
+		return m.Unmarshal(raw, v)
+	})
+}
+
+// preprocess modifies JSON data before unmarshalling.
+func (m *customJSONPb) preprocess(data []byte, v any) ([]byte, error) {
+	if !m.caseInsensitiveEnums {
+		return data, nil
+	}
+
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return data, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // keep numbers as is
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid request body: %w", err)
+	}
+
+	upperEnums(msg.ProtoReflect().Descriptor(), value)
+
+	return json.Marshal(value)
+}
+
+// unmarshalError converts protojson unknown field error to a clear error with the field name.
+func (m *customJSONPb) unmarshalError(err error) error {
+	if !m.strict || err == nil || errors.Is(err, io.EOF) {
+		return err
+	}
+
+	if match := unknownFieldRe.FindStringSubmatch(err.Error()); len(match) == 2 { //nolint:mnd // ok
+		return fmt.Errorf("invalid request body: unknown field %q", match[1])
+	}
+
+	return err
+}
+
+// upperEnums converts enum values of the JSON object to upper case, including nested messages.
+func upperEnums(md protoreflect.MessageDescriptor, value any) {
+	obj, ok := value.(map[string]any)
+	if !ok || strings.HasPrefix(string(md.FullName()), "google.protobuf.") {
+		// well-known types have special JSON representation
+		return
+	}
+
+	fields := md.Fields()
+	for key, val := range obj {
+		fd := fields.ByJSONName(key)
+		if fd == nil {
+			fd = fields.ByName(protoreflect.Name(key))
+		}
+		if fd == nil {
+			continue
+		}
+
+		switch {
+		case fd.IsMap():
+			if m, ok := val.(map[string]any); ok {
+				for k, v := range m {
+					m[k] = upperEnumValue(fd.MapValue(), v)
+				}
+			}
+		case fd.IsList():
+			if l, ok := val.([]any); ok {
+				for i := range l {
+					l[i] = upperEnumValue(fd, l[i])
+				}
+			}
+		default:
+			obj[key] = upperEnumValue(fd, val)
+		}
+	}
+}
+
+// upperEnumValue converts enum value to upper case or processes nested message.
+func upperEnumValue(fd protoreflect.FieldDescriptor, value any) any {
+	switch fd.Kind() { //nolint:exhaustive // other kinds are not modified
+	case protoreflect.EnumKind:
+		if s, ok := value.(string); ok {
+			return strings.ToUpper(s)
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		upperEnums(fd.Message(), value)
+	}
+
+	return value
+}
@@ -0,0 +1,184 @@
+package grpcsrv
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	api "github.com/n-r-w/grpcsrv/example/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestStrictJSON(t *testing.T) {
+	m := &customJSONPb{JSONPb: &runtime.JSONPb{}, strict: true}
+
+	var req api.HelloRequest
+	err := m.Unmarshal([]byte(`{"name":"bob","nmae":"bob"}`), &req)
+	if err == nil || err.Error() != `invalid request body: unknown field "nmae"` {
+		t.Fatalf("expected unknown field error, got %v", err)
+	}
+
+	err = m.NewDecoder(strings.NewReader(`{"extra":1}`)).Decode(&req)
+	if err == nil || !strings.Contains(err.Error(), `unknown field "extra"`) {
+		t.Fatalf("expected unknown field error from decoder, got %v", err)
+	}
+
+	if err = m.Unmarshal([]byte(`{"name":"bob"}`), &req); err != nil || req.GetName() != "bob" {
+		t.Fatalf("expected valid request, got %v", err)
+	}
+
+	// other errors are not modified
+	if err = m.Unmarshal([]byte(`{"name":1}`), &req); err == nil || strings.Contains(err.Error(), "unknown field") {
+		t.Fatalf("expected type error, got %v", err)
+	}
+}
+
+func TestStrictJSONGateway(t *testing.T) {
+	_, baseURL := startGatewayTestService(t, &testGreeter{}, WithStrictJSON())
+
+	resp, body := doHTTP(t, http.MethodPost, baseURL+"/v1/greeter:SayHello", `{"name":"bob","nmae":"bob"}`, nil)
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(body, "nmae") {
+		t.Fatalf("expected 400 with field name, got %d %s", resp.StatusCode, body)
+	}
+
+	resp, body = doHTTP(t, http.MethodPost, baseURL+"/v1/greeter:SayHello", `{"name":"bob"}`, nil)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "hello bob") {
+		t.Fatalf("expected 200, got %d %s", resp.StatusCode, body)
+	}
+}
+
+// testTaskDescriptor returns descriptor of
+// message Task { State state = 1; repeated State history = 2; map<string, State> by_user = 3; Task child = 4; },
+// enum State { STATE_UNSPECIFIED = 0; ACTIVE = 1; }.
+func testTaskDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	enumType := descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum()
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("grpcsrv_json_marshaller_test.proto"),
+		Package: proto.String("grpcsrv.test"),
+		Syntax:  proto.String("proto3"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{
+			{
+				Name: proto.String("State"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					{Name: proto.String("STATE_UNSPECIFIED"), Number: proto.Int32(0)},
+					{Name: proto.String("ACTIVE"), Number: proto.Int32(1)},
+				},
+			},
+		},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Task"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name: proto.String("state"), Number: proto.Int32(1), JsonName: proto.String("state"),
+						Label: optional, Type: enumType, TypeName: proto.String(".grpcsrv.test.State"),
+					},
+					{
+						Name: proto.String("history"), Number: proto.Int32(2), JsonName: proto.String("history"),
+						Label: descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+						Type:  enumType, TypeName: proto.String(".grpcsrv.test.State"),
+					},
+					{
+						Name: proto.String("by_user"), Number: proto.Int32(3), JsonName: proto.String("byUser"),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".grpcsrv.test.Task.ByUserEntry"),
+					},
+					{
+						Name: proto.String("child"), Number: proto.Int32(4), JsonName: proto.String("child"),
+						Label:    optional,
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".grpcsrv.test.Task"),
+					},
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					{
+						Name:    proto.String("ByUserEntry"),
+						Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+						Field: []*descriptorpb.FieldDescriptorProto{
+							{
+								Name: proto.String("key"), Number: proto.Int32(1), JsonName: proto.String("key"),
+								Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+							},
+							{
+								Name: proto.String("value"), Number: proto.Int32(2), JsonName: proto.String("value"),
+								Label: optional, Type: enumType, TypeName: proto.String(".grpcsrv.test.State"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fd.Messages().ByName("Task")
+}
+
+func TestCaseInsensitiveEnums(t *testing.T) {
+	desc := testTaskDescriptor(t)
+	const data = `{"state":"active","history":["Active","state_unspecified"],"byUser":{"bob":"active"},` +
+		`"child":{"state":"aCtIvE"}}`
+
+	check := func(t *testing.T, msg *dynamicpb.Message) {
+		t.Helper()
+
+		fields := desc.Fields()
+		if v := msg.Get(fields.ByName("state")).Enum(); v != 1 {
+			t.Fatalf("expected ACTIVE state, got %d", v)
+		}
+		history := msg.Get(fields.ByName("history")).List()
+		if history.Len() != 2 || history.Get(0).Enum() != 1 || history.Get(1).Enum() != 0 {
+			t.Fatal("unexpected history")
+		}
+		if v := msg.Get(fields.ByName("by_user")).Map().Get(protoreflect.ValueOfString("bob").MapKey()); v.Enum() != 1 {
+			t.Fatalf("expected ACTIVE map value, got %d", v.Enum())
+		}
+		if v := msg.Get(fields.ByName("child")).Message().Get(fields.ByName("state")).Enum(); v != 1 {
+			t.Fatalf("expected ACTIVE nested state, got %d", v)
+		}
+	}
+
+	m := &customJSONPb{JSONPb: &runtime.JSONPb{}, caseInsensitiveEnums: true}
+
+	t.Run("unmarshal", func(t *testing.T) {
+		msg := dynamicpb.NewMessage(desc)
+		if err := m.Unmarshal([]byte(data), msg); err != nil {
+			t.Fatal(err)
+		}
+		check(t, msg)
+	})
+
+	t.Run("decoder", func(t *testing.T) {
+		msg := dynamicpb.NewMessage(desc)
+		if err := m.NewDecoder(strings.NewReader(data)).Decode(msg); err != nil {
+			t.Fatal(err)
+		}
+		check(t, msg)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		plain := &customJSONPb{JSONPb: &runtime.JSONPb{}}
+		if err := plain.Unmarshal([]byte(data), dynamicpb.NewMessage(desc)); err == nil {
+			t.Fatal("expected error for lower case enum without the option")
+		}
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		if err := m.Unmarshal([]byte(`{"state":`), dynamicpb.NewMessage(desc)); err == nil {
+			t.Fatal("expected error for invalid JSON")
+		}
+	})
+}
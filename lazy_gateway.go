@@ -0,0 +1,143 @@
+package grpcsrv
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// lazyGatewayRetryInterval minimal interval between attempts to initialize the gateway after a failure.
+const lazyGatewayRetryInterval = time.Second
+
+// lazyGatewayHandler creates gRPC gateway handler on the first HTTP request.
+// Health check endpoints are served without initializing the gateway, so probes don't trigger it.
+// If initialization fails, requests get 503 and the next request after lazyGatewayRetryInterval retries it.
+type lazyGatewayHandler struct {
+	ctx context.Context //nolint:containedctx // ok
+	s   *Service
+
+	healthRoutes map[string]bool // path -> readiness
+
+	mu          sync.Mutex
+	handler     http.Handler
+	lastErr     error
+	lastAttempt time.Time
+}
+
+// newLazyGatewayHandler validates configuration that doesn't require the gateway connection,
+// so configuration errors are returned by Start.
+func newLazyGatewayHandler(ctx context.Context, s *Service) (*lazyGatewayHandler, error) {
+	if _, err := s.getJSONMarshallers(); err != nil {
+		return nil, err
+	}
+
+	healthRoutes, err := s.healthCheckRoutes()
+	if err != nil {
+		return nil, err
+	}
+
+	return &lazyGatewayHandler{
+		ctx:          ctx,
+		s:            s,
+		healthRoutes: healthRoutes,
+	}, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (h *lazyGatewayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.serveHealth(w, r) {
+		return
+	}
+
+	handler, err := h.getHandler()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// getHandler returns the gateway handler, initializing it if needed.
+// Concurrent requests arriving during initialization wait for it to complete.
+func (h *lazyGatewayHandler) getHandler() (http.Handler, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.handler != nil {
+		return h.handler, nil
+	}
+
+	if h.lastErr != nil && time.Since(h.lastAttempt) < lazyGatewayRetryInterval {
+		return nil, h.lastErr
+	}
+
+	h.s.logger.Info(h.ctx, "initializing http gateway on first request")
+	h.lastAttempt = time.Now()
+
+	handler, err := h.s.buildHTTPHandler(h.ctx)
+	if err != nil {
+		h.s.logger.Error(h.ctx, "failed to initialize http gateway", "error", err)
+		if conn := h.s.getGatewayConn(); conn != nil {
+			_ = conn.Close()
+			h.s.setGatewayConn(nil)
+		}
+		h.lastErr = err
+		return nil, err
+	}
+
+	h.handler = handler
+	h.lastErr = nil
+
+	return handler, nil
+}
+
+// serveHealth serves health check request without initializing the gateway. Returns false for other requests.
+func (h *lazyGatewayHandler) serveHealth(w http.ResponseWriter, r *http.Request) bool {
+	if len(h.healthRoutes) == 0 || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+
+	path := r.URL.Path
+	if h.s.httpPathPrefix != "" {
+		var ok bool
+		if path, ok = strings.CutPrefix(path, h.s.httpPathPrefix); !ok {
+			return false
+		}
+	}
+
+	readiness, ok := h.healthRoutes[path]
+	if !ok {
+		return false
+	}
+
+	if r.Method == http.MethodHead {
+		w = &headResponseWriter{ResponseWriter: w}
+	}
+
+	if readiness {
+		h.s.serveReadiness(w, r)
+	} else {
+		h.s.serveLiveness(w, r)
+	}
+
+	return true
+}
+
+func (s *Service) setGatewayConn(conn *grpc.ClientConn) {
+	s.gatewayConnMu.Lock()
+	defer s.gatewayConnMu.Unlock()
+
+	s.grpcGatewayConn = conn
+}
+
+func (s *Service) getGatewayConn() *grpc.ClientConn {
+	s.gatewayConnMu.Lock()
+	defer s.gatewayConnMu.Unlock()
+
+	return s.grpcGatewayConn
+}
@@ -0,0 +1,93 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+)
+
+func TestLazyGatewayServesHealthWithoutInit(t *testing.T) {
+	s := New(context.Background(), nil,
+		WithLazyGateway(),
+		WithHealthCheck(&testHealther{ready: true}, "/live", "/ready"),
+	)
+
+	h, err := newLazyGatewayHandler(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/live", "/ready"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", path, rec.Code)
+		}
+	}
+
+	if h.handler != nil || !h.lastAttempt.IsZero() {
+		t.Fatal("health checks must not initialize the gateway")
+	}
+}
+
+func TestLazyGatewayValidatesConfig(t *testing.T) {
+	s := New(context.Background(), nil,
+		WithLazyGateway(),
+		WithHTTPMarshallers(map[string]runtime.Marshaler{"": &runtime.JSONPb{}}),
+	)
+
+	if _, err := newLazyGatewayHandler(context.Background(), s); err == nil {
+		t.Fatal("expected error for invalid marshallers")
+	}
+}
+
+// testInitializer IGRPCInitializer for tests.
+type testInitializer struct {
+	registerHTTPErr error
+}
+
+func (i *testInitializer) RegisterGRPCServer(*grpc.Server) {}
+
+func (i *testInitializer) RegisterHTTPHandler(context.Context, *runtime.ServeMux, *grpc.ClientConn) error {
+	return i.registerHTTPErr
+}
+
+func (i *testInitializer) GetOptions() InitializeOptions {
+	return InitializeOptions{HTTPHandlerRequired: true}
+}
+
+func TestLazyGatewayRetriesAfterFailure(t *testing.T) {
+	initializer := &testInitializer{registerHTTPErr: errors.New("registration failed")}
+	s := New(context.Background(), []IGRPCInitializer{initializer}, WithLazyGateway())
+
+	h, err := newLazyGatewayHandler(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/test", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+	if s.getGatewayConn() != nil {
+		t.Fatal("gateway connection must be closed after failed initialization")
+	}
+
+	// the next attempt succeeds after the retry interval
+	initializer.registerHTTPErr = nil
+	h.lastAttempt = time.Now().Add(-lazyGatewayRetryInterval)
+
+	if _, err = h.getHandler(); err != nil {
+		t.Fatalf("expected successful retry, got %v", err)
+	}
+	if conn := s.getGatewayConn(); conn != nil {
+		_ = conn.Close()
+	}
+}
@@ -0,0 +1,66 @@
+package grpcsrv
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/n-r-w/ctxlog"
+	"golang.org/x/net/netutil"
+)
+
+// limitListener limits the number of simultaneous connections and logs when the limit is reached.
+type limitListener struct {
+	net.Listener
+	ctx    context.Context //nolint:containedctx // used only for logging
+	logger ctxlog.ILogger
+	server string
+	limit  int64
+	active atomic.Int64
+}
+
+// newLimitListener wraps listener with netutil.LimitListener if limit is positive.
+func newLimitListener(ctx context.Context, listener net.Listener, limit int, logger ctxlog.ILogger, server string,
+) net.Listener {
+	if limit <= 0 {
+		return listener
+	}
+
+	return &limitListener{
+		Listener: netutil.LimitListener(listener, limit),
+		ctx:      ctx,
+		logger:   logger,
+		server:   server,
+		limit:    int64(limit),
+	}
+}
+
+// Accept waits for and returns the next connection to the listener.
+func (l *limitListener) Accept() (net.Conn, error) {
+	if l.active.Load() >= l.limit {
+		l.logger.Warn(l.ctx, "connection limit reached, throttling", "server", l.server, "limit", l.limit)
+	}
+
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	l.active.Add(1)
+
+	return &limitListenerConn{Conn: conn, release: func() { l.active.Add(-1) }}, nil
+}
+
+type limitListenerConn struct {
+	net.Conn
+	releaseOnce sync.Once
+	release     func()
+}
+
+// Close closes the connection and releases its slot.
+func (c *limitListenerConn) Close() error {
+	err := c.Conn.Close()
+	c.releaseOnce.Do(c.release)
+	return err
+}
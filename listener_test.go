@@ -0,0 +1,76 @@
+package grpcsrv
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/n-r-w/ctxlog"
+)
+
+func TestLimitListener(t *testing.T) {
+	base, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := newLimitListener(context.Background(), base, 1, ctxlog.NewStubWrapper(), "test")
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	dial := func() net.Conn {
+		conn, err := net.Dial("tcp", base.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		return conn
+	}
+
+	dial()
+	first := <-accepted
+
+	// the second connection waits in the backlog until the first one is closed
+	dial()
+	select {
+	case <-accepted:
+		t.Fatal("second connection must not be accepted while the limit is reached")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	_ = first.Close()
+	_ = first.Close() // double close must release the slot only once
+
+	select {
+	case <-accepted:
+	case <-time.After(time.Second):
+		t.Fatal("second connection must be accepted after the first one is closed")
+	}
+
+	if active := l.(*limitListener).active.Load(); active != 1 {
+		t.Fatalf("expected 1 active connection, got %d", active)
+	}
+}
+
+func TestLimitListenerDisabled(t *testing.T) {
+	base, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer base.Close()
+
+	if l := newLimitListener(context.Background(), base, 0, ctxlog.NewStubWrapper(), "test"); l != base {
+		t.Fatal("listener must not be wrapped without limit")
+	}
+}
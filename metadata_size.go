@@ -0,0 +1,63 @@
+package grpcsrv
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// metadataSize returns size of metadata calculated as HTTP/2 header list size (RFC 7540, 6.5.2):
+// length of key and value plus 32 bytes of overhead for each field.
+func metadataSize(md metadata.MD) int {
+	const fieldOverhead = 32
+
+	size := 0
+	for k, vals := range md {
+		for _, v := range vals {
+			size += len(k) + len(v) + fieldOverhead
+		}
+	}
+
+	return size
+}
+
+// checks size of incoming metadata.
+func (s *Service) checkMetadataSize(ctx context.Context, method string) error {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	if size := metadataSize(md); size > s.maxMetadataSize {
+		s.logger.Warn(ctx, "grpc metadata is too large", "grpc_method", method, "size", size, "limit", s.maxMetadataSize)
+		return status.Errorf(codes.ResourceExhausted,
+			"metadata size %d bytes exceeds limit of %d bytes", size, s.maxMetadataSize)
+	}
+
+	return nil
+}
+
+// interceptor for limiting size of unary call metadata.
+func (s *Service) metadataSizeUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if err := s.checkMetadataSize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// interceptor for limiting size of stream metadata.
+func (s *Service) metadataSizeStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if err := s.checkMetadataSize(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+
+	return handler(srv, ss)
+}
@@ -0,0 +1,65 @@
+package grpcsrv
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestMetadataSize(t *testing.T) {
+	md := metadata.Pairs("key", "value", "key", "v2", "authorization", "")
+	// (3+5+32) + (3+2+32) + (13+0+32)
+	if got, want := metadataSize(md), 40+37+45; got != want {
+		t.Fatalf("expected %d, got %d", want, got)
+	}
+
+	if got := metadataSize(nil); got != 0 {
+		t.Fatalf("expected 0 for empty metadata, got %d", got)
+	}
+}
+
+func TestMetadataSizeInterceptors(t *testing.T) {
+	s := New(context.Background(), nil, WithMaxMetadataSize(100))
+	handler := func(context.Context, any) (any, error) { return nil, nil }
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+	small := metadata.NewIncomingContext(context.Background(), metadata.Pairs("key", "value"))
+	if _, err := s.metadataSizeUnaryInterceptor(small, nil, info, handler); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := s.metadataSizeUnaryInterceptor(context.Background(), nil, info, handler); err != nil {
+		t.Fatalf("expected no error without metadata, got %v", err)
+	}
+
+	large := metadata.NewIncomingContext(context.Background(), metadata.Pairs("key", strings.Repeat("v", 100)))
+	if _, err := s.metadataSizeUnaryInterceptor(large, nil, info, handler); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+
+	err := s.metadataSizeStreamInterceptor(nil, &testServerStream{ctx: large},
+		&grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}, func(any, grpc.ServerStream) error { return nil })
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted for stream, got %v", err)
+	}
+}
+
+func TestMaxMetadataSizeServer(t *testing.T) {
+	s := startTestService(t, WithMaxMetadataSize(1024))
+	client := grpc_health_v1.NewHealthClient(dialTestService(t, s))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-large", strings.Repeat("v", 2048))
+	_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+
+	if _, err = client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{}); err != nil {
+		t.Fatalf("expected no error for small metadata, got %v", err)
+	}
+}
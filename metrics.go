@@ -2,31 +2,280 @@ package grpcsrv
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"sync"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
+var (
+	panicsRecoveredTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpcsrv_panics_recovered_total",
+		Help: "Total number of recovered panics in gRPC handlers.",
+	}, []string{"method"})
+
+	missingDeadlineTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpcsrv_missing_deadline_total",
+		Help: "Total number of gRPC requests without deadline.",
+	}, []string{"method"})
+
+	gatewayBackendDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_backend_duration_seconds",
+		Help:    "Duration of gRPC calls forwarded by HTTP gateway to the backend.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	serverHandlingDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Duration of gRPC calls handled by the server.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	requestSizeBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_request_size_bytes",
+		Help:    "Size of gRPC request messages.",
+		Buckets: prometheus.ExponentialBuckets(64, 4, 10), //nolint:mnd // 64B..16MB
+	}, []string{"method"})
+
+	responseSizeBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_response_size_bytes",
+		Help:    "Size of gRPC response messages.",
+		Buckets: prometheus.ExponentialBuckets(64, 4, 10), //nolint:mnd // 64B..16MB
+	}, []string{"method"})
+
+	registerMetricsOnce sync.Once
+)
+
+// registerMetrics registers grpcsrv metrics in the default prometheus registry.
+func registerMetrics() {
+	registerMetricsOnce.Do(func() {
+		prometheus.MustRegister(panicsRecoveredTotal, missingDeadlineTotal, gatewayBackendDuration,
+			serverHandlingDuration, requestSizeBytes, responseSizeBytes)
+	})
+}
+
+// incPanicsRecovered increments counter of recovered panics if metrics are enabled.
+func (s *Service) incPanicsRecovered(method string) {
+	if s.metricsEndpoint == "" {
+		return
+	}
+
+	panicsRecoveredTotal.WithLabelValues(s.metricLabel(method)).Inc()
+}
+
+// incMissingDeadline increments counter of requests without deadline if metrics are enabled.
+func (s *Service) incMissingDeadline(method string) {
+	if s.metricsEndpoint == "" {
+		return
+	}
+
+	missingDeadlineTotal.WithLabelValues(s.metricLabel(method)).Inc()
+}
+
+// metricLabel returns "method" label value of the full method name mapped by WithMetricLabelMapper.
+func (s *Service) metricLabel(fullMethod string) string {
+	if s.metricLabelMapper == nil {
+		return fullMethod
+	}
+
+	return s.metricLabelMapper(fullMethod)
+}
+
+// observeWithExemplar records value with trace ID exemplar if the context contains sampled span.
+// Exemplars are exposed only in OpenMetrics format.
+func (s *Service) observeWithExemplar(ctx context.Context, o prometheus.Observer, v float64) {
+	if !s.withoutOTel {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsSampled() {
+			if eo, ok := o.(prometheus.ExemplarObserver); ok {
+				eo.ObserveWithExemplar(v, prometheus.Labels{"trace_id": sc.TraceID().String()})
+				return
+			}
+		}
+	}
+
+	o.Observe(v)
+}
+
+// metricsUnaryInterceptor measures duration of unary calls.
+func (s *Service) metricsUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	method := s.metricLabel(info.FullMethod)
+	observeMessageSize(requestSizeBytes, method, req)
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	s.observeWithExemplar(ctx,
+		serverHandlingDuration.WithLabelValues(method, status.Code(err).String()), time.Since(start).Seconds())
+
+	if err == nil {
+		observeMessageSize(responseSizeBytes, method, resp)
+	}
+
+	return resp, err
+}
+
+// metricsStreamInterceptor measures duration of streams.
+func (s *Service) metricsStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	method := s.metricLabel(info.FullMethod)
+
+	start := time.Now()
+	err := handler(srv, &sizeMetricsServerStream{ServerStream: ss, method: method})
+	s.observeWithExemplar(ss.Context(),
+		serverHandlingDuration.WithLabelValues(method, status.Code(err).String()), time.Since(start).Seconds())
+
+	return err
+}
+
+// observeMessageSize records size of protobuf message without marshalling.
+func observeMessageSize(h *prometheus.HistogramVec, method string, msg any) {
+	if m, ok := msg.(proto.Message); ok {
+		h.WithLabelValues(method).Observe(float64(proto.Size(m)))
+	}
+}
+
+// sizeMetricsServerStream records sizes of stream messages.
+type sizeMetricsServerStream struct {
+	grpc.ServerStream
+	method string
+}
+
+// RecvMsg implements grpc.ServerStream.
+func (m *sizeMetricsServerStream) RecvMsg(msg any) error {
+	err := m.ServerStream.RecvMsg(msg)
+	if err == nil {
+		observeMessageSize(requestSizeBytes, m.method, msg)
+	}
+
+	return err
+}
+
+// SendMsg implements grpc.ServerStream.
+func (m *sizeMetricsServerStream) SendMsg(msg any) error {
+	err := m.ServerStream.SendMsg(msg)
+	if err == nil {
+		observeMessageSize(responseSizeBytes, m.method, msg)
+	}
+
+	return err
+}
+
+// gatewayBackendUnaryInterceptor measures duration of unary calls from gateway to gRPC server.
+func (s *Service) gatewayBackendUnaryInterceptor(
+	ctx context.Context,
+	method string,
+	req, reply any,
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	s.observeWithExemplar(ctx,
+		gatewayBackendDuration.WithLabelValues(s.metricLabel(method), status.Code(err).String()),
+		time.Since(start).Seconds())
+
+	return err
+}
+
+// gatewayBackendStreamInterceptor measures duration of streaming calls from gateway to gRPC server.
+// Duration is recorded when the stream is finished.
+func (s *Service) gatewayBackendStreamInterceptor(
+	ctx context.Context,
+	desc *grpc.StreamDesc,
+	cc *grpc.ClientConn,
+	method string,
+	streamer grpc.Streamer,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	label := s.metricLabel(method)
+
+	start := time.Now()
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		gatewayBackendDuration.WithLabelValues(label, status.Code(err).String()).Observe(time.Since(start).Seconds())
+		return nil, err
+	}
+
+	m := &measuredClientStream{ClientStream: stream, method: label, start: start}
+	// the gateway stops reading the stream when the HTTP client goes away, then ctx finishes the call
+	m.stop = context.AfterFunc(ctx, func() {
+		m.observe(status.FromContextError(ctx.Err()).Code())
+	})
+
+	return m, nil
+}
+
+// measuredClientStream records call duration once: on the first receive error (including io.EOF)
+// or when the call context is done, if the stream is not drained.
+type measuredClientStream struct {
+	grpc.ClientStream
+	method string
+	start  time.Time
+	once   sync.Once
+	stop   func() bool
+}
+
+// RecvMsg implements grpc.ClientStream.
+func (m *measuredClientStream) RecvMsg(msg any) error {
+	err := m.ClientStream.RecvMsg(msg)
+	if err != nil {
+		code := status.Code(err)
+		if errors.Is(err, io.EOF) {
+			code = codes.OK
+		}
+		m.stop()
+		m.observe(code)
+	}
+
+	return err
+}
+
+func (m *measuredClientStream) observe(code codes.Code) {
+	m.once.Do(func() {
+		gatewayBackendDuration.WithLabelValues(m.method, code.String()).Observe(time.Since(m.start).Seconds())
+	})
+}
+
 // startMetricsServer starts a dedicated HTTP server for prometheus metrics.
 func (s *Service) startMetricsServer(ctx context.Context) error {
 	if s.metricsEndpoint == "" {
 		return nil
 	}
 
+	registerMetrics()
+
 	metricsHandler := http.NewServeMux()
-	metricsHandler.Handle("/metrics", promhttp.Handler())
+	// OpenMetrics format is required for exemplars, it is used if requested by the scraper
+	metricsHandler.Handle("/metrics", promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	))
+
+	// listen synchronously so that bind errors abort Start
+	listener, err := net.Listen("tcp", s.metricsEndpoint)
+	if err != nil {
+		return fmt.Errorf("%s. failed to start metrics server listener on %s: %w", s.name, s.metricsEndpoint, err)
+	}
 
 	s.httpMetricsServer = &http.Server{
 		Addr:              s.metricsEndpoint,
 		Handler:           metricsHandler,
 		ReadHeaderTimeout: s.httpReadHeaderTimeout,
-	}
-
-	listener, err := net.Listen("tcp", s.metricsEndpoint)
-	if err != nil {
-		return fmt.Errorf("%s. failed to start metrics server listener: %w", s.name, err)
+		MaxHeaderBytes:    s.httpMaxHeaderBytes,
 	}
 
 	s.wg.Add(1)
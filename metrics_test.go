@@ -0,0 +1,268 @@
+package grpcsrv
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// histogramCount returns number of observations of the histogram series.
+func histogramCount(t *testing.T, o prometheus.Observer) uint64 {
+	t.Helper()
+
+	m, ok := o.(prometheus.Metric)
+	if !ok {
+		t.Fatal("observer is not a metric")
+	}
+
+	var pb dto.Metric
+	if err := m.Write(&pb); err != nil {
+		t.Fatal(err)
+	}
+
+	return pb.GetHistogram().GetSampleCount()
+}
+
+// counterValue returns value of the counter series.
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	t.Helper()
+
+	var pb dto.Metric
+	if err := c.Write(&pb); err != nil {
+		t.Fatal(err)
+	}
+
+	return pb.GetCounter().GetValue()
+}
+
+func TestPanicsRecoveredMetric(t *testing.T) {
+	const method = "/test.Panic/Call"
+	handler := func(context.Context, any) (any, error) { panic("boom") }
+
+	// without metrics endpoint the counter is not touched
+	s := New(context.Background(), nil)
+	_, _ = s.recoverUnaryGRPC(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	if got := counterValue(t, panicsRecoveredTotal.WithLabelValues(method)); got != 0 {
+		t.Fatalf("expected no panics counted without metrics, got %v", got)
+	}
+
+	s = New(context.Background(), nil, WithMetrics("127.0.0.1:0"))
+	for range 2 {
+		_, err := s.recoverUnaryGRPC(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		if status.Code(err) != codes.Internal {
+			t.Fatalf("expected Internal, got %v", err)
+		}
+	}
+
+	if got := counterValue(t, panicsRecoveredTotal.WithLabelValues(method)); got != 2 {
+		t.Fatalf("expected 2 recovered panics, got %v", got)
+	}
+}
+
+func TestMetricLabelMapper(t *testing.T) {
+	const group = "test.Mapped/*"
+
+	s := New(context.Background(), nil, WithMetricLabelMapper(func(string) string { return group }))
+
+	before := histogramCount(t, serverHandlingDuration.WithLabelValues(group, "OK"))
+
+	handler := func(context.Context, any) (any, error) { return nil, nil }
+	for _, method := range []string{"/test.Mapped/A", "/test.Mapped/B"} {
+		if _, err := s.metricsUnaryInterceptor(context.Background(), nil,
+			&grpc.UnaryServerInfo{FullMethod: method}, handler); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := histogramCount(t, serverHandlingDuration.WithLabelValues(group, "OK")) - before; got != 2 {
+		t.Fatalf("expected 2 observations in the mapped series, got %d", got)
+	}
+	if got := histogramCount(t, serverHandlingDuration.WithLabelValues("/test.Mapped/A", "OK")); got != 0 {
+		t.Fatalf("expected no observations for the real method name, got %d", got)
+	}
+}
+
+// metricName returns fully-qualified name of the single metric of the collector.
+func metricName(t *testing.T, c prometheus.Collector) string {
+	t.Helper()
+
+	ch := make(chan *prometheus.Desc, 1)
+	c.Describe(ch)
+	desc := (<-ch).String()
+
+	// Desc{fqName: "name", ...}
+	_, rest, _ := strings.Cut(desc, `fqName: "`)
+	name, _, _ := strings.Cut(rest, `"`)
+
+	return name
+}
+
+func TestMessageSizeMetrics(t *testing.T) {
+	if name := metricName(t, requestSizeBytes); name != "grpc_request_size_bytes" {
+		t.Fatalf("unexpected request size metric name %s", name)
+	}
+	if name := metricName(t, responseSizeBytes); name != "grpc_response_size_bytes" {
+		t.Fatalf("unexpected response size metric name %s", name)
+	}
+
+	const method = "/test.Size/Call"
+	s := New(context.Background(), nil)
+
+	handler := func(context.Context, any) (any, error) { return wrapperspb.String("response"), nil }
+	if _, err := s.metricsUnaryInterceptor(context.Background(), wrapperspb.String("request"),
+		&grpc.UnaryServerInfo{FullMethod: method}, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := histogramCount(t, requestSizeBytes.WithLabelValues(method)); got != 1 {
+		t.Fatalf("expected 1 request size observation, got %d", got)
+	}
+	if got := histogramCount(t, responseSizeBytes.WithLabelValues(method)); got != 1 {
+		t.Fatalf("expected 1 response size observation, got %d", got)
+	}
+}
+
+func TestGatewayBackendDuration(t *testing.T) {
+	if name := metricName(t, gatewayBackendDuration); name != "gateway_backend_duration_seconds" {
+		t.Fatalf("unexpected gateway backend metric name %s", name)
+	}
+
+	const method = "/test.Gateway/Call"
+	s := New(context.Background(), nil)
+
+	invoker := func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		return status.Error(codes.NotFound, "not found")
+	}
+	_ = s.gatewayBackendUnaryInterceptor(context.Background(), method, nil, nil, nil, invoker)
+
+	if got := histogramCount(t, gatewayBackendDuration.WithLabelValues(method, codes.NotFound.String())); got != 1 {
+		t.Fatalf("expected 1 observation, got %d", got)
+	}
+}
+
+// eofClientStream grpc.ClientStream, which is finished with io.EOF.
+type eofClientStream struct {
+	grpc.ClientStream
+}
+
+func (eofClientStream) RecvMsg(any) error { return io.EOF }
+
+func TestGatewayBackendStreamDuration(t *testing.T) {
+	s := New(context.Background(), nil)
+	streamer := func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return eofClientStream{}, nil
+	}
+
+	// drained stream is recorded once with its status
+	const drained = "/test.Gateway/Drained"
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := s.gatewayBackendStreamInterceptor(ctx, &grpc.StreamDesc{}, nil, drained, streamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = stream.RecvMsg(nil)
+	_ = stream.RecvMsg(nil)
+	cancel()
+
+	if got := histogramCount(t, gatewayBackendDuration.WithLabelValues(drained, codes.OK.String())); got != 1 {
+		t.Fatalf("expected 1 OK observation, got %d", got)
+	}
+	if got := histogramCount(t, gatewayBackendDuration.WithLabelValues(drained, codes.Canceled.String())); got != 0 {
+		t.Fatalf("expected no Canceled observations for drained stream, got %d", got)
+	}
+
+	// stream, which is not drained, is recorded when the context is done
+	const abandoned = "/test.Gateway/Abandoned"
+	ctx, cancel = context.WithCancel(context.Background())
+	if _, err = s.gatewayBackendStreamInterceptor(ctx, &grpc.StreamDesc{}, nil, abandoned, streamer); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	observer := gatewayBackendDuration.WithLabelValues(abandoned, codes.Canceled.String())
+	deadline := time.Now().Add(time.Second)
+	for histogramCount(t, observer) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected Canceled observation for the stream, which is not drained")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestServerHandlingExemplar(t *testing.T) {
+	if name := metricName(t, serverHandlingDuration); name != "grpc_server_handling_seconds" {
+		t.Fatalf("unexpected server handling metric name %s", name)
+	}
+
+	traceID := trace.TraceID{1, 2, 3}
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	const method = "/test.Exemplar/Call"
+	s := New(context.Background(), nil)
+
+	handler := func(context.Context, any) (any, error) { return nil, nil }
+	if _, err := s.metricsUnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	m, _ := serverHandlingDuration.WithLabelValues(method, "OK").(prometheus.Metric)
+	var pb dto.Metric
+	if err := m.Write(&pb); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, b := range pb.GetHistogram().GetBucket() {
+		for _, l := range b.GetExemplar().GetLabel() {
+			if l.GetName() == "trace_id" && l.GetValue() == traceID.String() {
+				return
+			}
+		}
+	}
+	t.Fatal("exemplar with trace id not found")
+}
+
+func TestMissingDeadline(t *testing.T) {
+	const method = "/test.Deadline/Call"
+	logger := &testLogger{}
+	s := New(context.Background(), nil, WithLogger(logger), WithWarnOnMissingDeadline(), WithMetrics("127.0.0.1:0"))
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+	handler := func(context.Context, any) (any, error) { return nil, nil }
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	_, _ = s.deadlineWarnUnaryInterceptor(ctx, nil, info, handler)
+	if _, ok := logger.find("grpc request without deadline"); ok {
+		t.Fatal("request with deadline must not be reported")
+	}
+
+	_, _ = s.deadlineWarnUnaryInterceptor(context.Background(), nil, info, handler)
+	e, ok := logger.find("grpc request without deadline")
+	if !ok || e.level != "warn" {
+		t.Fatal("expected warning for request without deadline")
+	}
+	if v, _ := e.arg("method"); v != method {
+		t.Fatalf("expected method %s in warning, got %v", method, v)
+	}
+
+	_ = s.deadlineWarnStreamInterceptor(nil, &testServerStream{}, &grpc.StreamServerInfo{FullMethod: method},
+		func(any, grpc.ServerStream) error { return nil })
+
+	if got := counterValue(t, missingDeadlineTotal.WithLabelValues(method)); got != 2 {
+		t.Fatalf("expected 2 requests without deadline, got %v", got)
+	}
+}
@@ -2,14 +2,23 @@ package grpcsrv
 
 import (
 	"context"
+	"maps"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	grpc_runtime "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/moznion/go-optional"
 	"github.com/n-r-w/ctxlog"
 	"github.com/rs/cors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/proto"
 )
 
 type (
@@ -23,11 +32,27 @@ type (
 	CtxHTTPModifier func(ctx context.Context, r *http.Request, traceID string) context.Context
 	// RegisterHTTPEndpoints function for registering additional endpoints.
 	RegisterHTTPEndpoints func(ctx context.Context, mux *grpc_runtime.ServeMux) error
+	// GatewayResponseModifier function for post-processing successful HTTP gateway response before it is written.
+	GatewayResponseModifier func(ctx context.Context, w http.ResponseWriter, msg proto.Message) error
 )
 
 // Option option for service initialization.
 type Option func(*Service)
 
+// ServerKind kind of server managed by the service.
+type ServerKind int
+
+const (
+	// ServerKindHTTP HTTP gateway server.
+	ServerKindHTTP ServerKind = iota
+	// ServerKindGRPC gRPC server.
+	ServerKindGRPC
+	// ServerKindPprof pprof server.
+	ServerKindPprof
+	// ServerKindMetrics prometheus metrics server.
+	ServerKindMetrics
+)
+
 // Endpoint hosts for gRPC and HTTP servers.
 type Endpoint struct {
 	GRPC string
@@ -48,6 +73,84 @@ func WithHTTPReadHeaderTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithHTTPMaxHeaderBytes sets maximum size of HTTP request headers for gateway, metrics and pprof servers.
+// If not set, http.DefaultMaxHeaderBytes is used.
+func WithHTTPMaxHeaderBytes(n int) Option {
+	return func(s *Service) {
+		s.httpMaxHeaderBytes = n
+	}
+}
+
+// WithMaxConnections sets maximum number of simultaneous connections to gRPC server.
+// If not set, the number of connections is not limited.
+func WithMaxConnections(n int) Option {
+	return func(s *Service) {
+		s.grpcMaxConnections = n
+	}
+}
+
+// WithHTTPMaxConnections sets maximum number of simultaneous connections to HTTP gateway.
+// If not set, the number of connections is not limited.
+func WithHTTPMaxConnections(n int) Option {
+	return func(s *Service) {
+		s.httpMaxConnections = n
+	}
+}
+
+// WithHTTPGzip enables gzip compression of HTTP gateway responses for clients that support it.
+// Only responses with body size not less than minSize are compressed.
+// Responses that already have Content-Encoding are not compressed.
+func WithHTTPGzip(minSize int) Option {
+	return func(s *Service) {
+		s.httpGzipMinSize = max(minSize, 0)
+	}
+}
+
+// WithHTTPPathPrefix mounts HTTP gateway under the path prefix (e.g. /api/v1).
+// The prefix is stripped before routing, so gateway, health check and additional endpoints
+// are available under the prefix. pprof and metrics servers are not affected.
+func WithHTTPPathPrefix(prefix string) Option {
+	return func(s *Service) {
+		prefix = strings.TrimRight(prefix, "/")
+		if prefix != "" && !strings.HasPrefix(prefix, "/") {
+			prefix = "/" + prefix
+		}
+		s.httpPathPrefix = prefix
+	}
+}
+
+// WithHTTPConcurrencyLimit sets maximum number of concurrent requests processed by HTTP gateway.
+// Requests exceeding the limit get 503 Service Unavailable. If not set, the number is not limited.
+func WithHTTPConcurrencyLimit(n int) Option {
+	return func(s *Service) {
+		s.httpConcurrencyLimit = n
+	}
+}
+
+// WithPerPeerConcurrencyLimit sets maximum number of concurrent gRPC requests per remote address.
+// Requests exceeding the limit get codes.ResourceExhausted. If not set, the number is not limited.
+// Requests from HTTP gateway are limited by the remote address of HTTP connection. X-Forwarded-For is not
+// trusted, so clients behind a proxy or load balancer share the limit of the proxy address.
+func WithPerPeerConcurrencyLimit(n int) Option {
+	return func(s *Service) {
+		s.perPeerConcurrencyLimit = n
+	}
+}
+
+// WithAPIKeyRateLimit enables token bucket rate limiting per API key.
+// keyFromCtx extracts API key from request context (e.g. from metadata).
+// Requests exceeding the limit get codes.ResourceExhausted with RetryInfo details and retry-after trailer.
+func WithAPIKeyRateLimit(keyFromCtx func(ctx context.Context) string, limit RateLimit) Option {
+	return func(s *Service) {
+		if limit.Rate <= 0 || limit.Burst <= 0 {
+			panic("rate limit Rate and Burst must be positive")
+		}
+
+		s.apiKeyFromCtx = keyFromCtx
+		s.apiKeyRateLimiter = newKeyRateLimiter(limit)
+	}
+}
+
 // WithGRPCInitializers sets gRPC server initializers.
 func WithGRPCInitializers(initializers ...IGRPCInitializer) Option {
 	return func(s *Service) {
@@ -55,6 +158,45 @@ func WithGRPCInitializers(initializers ...IGRPCInitializer) Option {
 	}
 }
 
+// WithTLS enables TLS for gRPC server using certificate and key files.
+// HTTP gateway connects to gRPC server using WithHTTPDialOptions, so they must contain appropriate credentials.
+func WithTLS(certFile, keyFile string) Option {
+	return func(s *Service) {
+		s.tlsCertFile = certFile
+		s.tlsKeyFile = keyFile
+	}
+}
+
+// WithTLSReload enables periodic reloading of TLS certificate files set by WithTLS.
+// New connections use the reloaded certificate, existing connections are not affected.
+func WithTLSReload(interval time.Duration) Option {
+	return func(s *Service) {
+		s.tlsReloadInterval = interval
+	}
+}
+
+// WithTLSClientCA sets CA certificate file for verifying client certificates (mTLS).
+// Client certificates are optional at TLS level, use WithClientCertRequiredMethods to require them.
+func WithTLSClientCA(caFile string) Option {
+	return func(s *Service) {
+		s.tlsClientCAFile = caFile
+	}
+}
+
+// WithClientCertRequiredMethods rejects calls to specified methods (full method names, e.g. /package.Service/Method)
+// without verified client certificate with codes.Unauthenticated. Requires WithTLS and WithTLSClientCA.
+func WithClientCertRequiredMethods(methods ...string) Option {
+	return func(s *Service) {
+		if s.clientCertMethods == nil {
+			s.clientCertMethods = make(map[string]struct{}, len(methods))
+		}
+
+		for _, m := range methods {
+			s.clientCertMethods[m] = struct{}{}
+		}
+	}
+}
+
 // WithGRPCOptions sets options for gRPC server.
 func WithGRPCOptions(options ...grpc.ServerOption) Option {
 	return func(s *Service) {
@@ -62,6 +204,418 @@ func WithGRPCOptions(options ...grpc.ServerOption) Option {
 	}
 }
 
+// WithUnknownServiceHandler sets handler of calls to services and methods not registered in gRPC server,
+// e.g. for transparent proxying to upstreams. All calls are handled as bidirectional streams;
+// the full method name is available via grpc.MethodFromServerStream. Stream interceptors are applied to the handler.
+func WithUnknownServiceHandler(h grpc.StreamHandler) Option {
+	return func(s *Service) {
+		s.unknownServiceHandler = h
+	}
+}
+
+// WithTraceIDInHeader sends traceID in gRPC response header in addition to the trailer.
+// Useful for clients that can't easily read trailers.
+func WithTraceIDInHeader() Option {
+	return func(s *Service) {
+		s.traceIDInHeader = true
+	}
+}
+
+// WithServerTimeHeader adds server time (RFC3339) to gRPC response trailer and HTTP response header
+// (see ServerTimeKey). Useful for clock-skew debugging.
+func WithServerTimeHeader() Option {
+	return func(s *Service) {
+		s.serverTimeHeader = true
+	}
+}
+
+// WithCorrelationIDHeader enables correlation ID support.
+// Correlation ID is taken from the specified HTTP header or gRPC metadata key (e.g. X-Correlation-ID),
+// generated if absent, added to context and logger fields and echoed in the response.
+// IDs longer than 128 characters or containing characters other than [A-Za-z0-9._-] are replaced with a new one.
+// See CorrelationIDFromContext.
+func WithCorrelationIDHeader(name string) Option {
+	return func(s *Service) {
+		s.correlationIDHeader = correlationIDHeaderKey(name)
+	}
+}
+
+// WithFeatureFlagMetadata enables request-scoped feature flags passed in gRPC metadata
+// with the specified prefix (e.g. "x-feature-": "x-feature-newalgo: on" -> flag "newalgo").
+// HTTP gateway clients should use Grpc-Metadata- prefixed headers (e.g. Grpc-Metadata-X-Feature-Newalgo).
+// Flags are set by the client, so any caller can enable them. Use allowed to restrict accepted flag names
+// and don't use flags for anything that must not be controlled by untrusted clients (e.g. access checks).
+// If allowed is empty, all flags with the prefix are accepted.
+// See FeatureFlagsFromContext.
+func WithFeatureFlagMetadata(prefix string, allowed ...string) Option {
+	return func(s *Service) {
+		s.featureFlagPrefix = strings.ToLower(prefix)
+		s.featureFlagAllowed = nil
+		if len(allowed) > 0 {
+			s.featureFlagAllowed = make(map[string]struct{}, len(allowed))
+			for _, name := range allowed {
+				s.featureFlagAllowed[strings.ToLower(name)] = struct{}{}
+			}
+		}
+	}
+}
+
+// WithFieldMaskValidation enables validation of google.protobuf.FieldMask fields in requests
+// of specified methods (full method names, e.g. /package.Service/Method).
+// Invalid paths result in codes.InvalidArgument.
+func WithFieldMaskValidation(methods ...string) Option {
+	return func(s *Service) {
+		if s.fieldMaskMethods == nil {
+			s.fieldMaskMethods = make(map[string]struct{}, len(methods))
+		}
+
+		for _, m := range methods {
+			s.fieldMaskMethods[m] = struct{}{}
+		}
+	}
+}
+
+// WithValidation enables validation of requests implementing ValidateAll() error or Validate() error methods
+// (e.g. generated by protoc-gen-validate). Invalid requests result in codes.InvalidArgument.
+// If the validator reports per-field errors (Field() and Reason() methods), they are returned
+// as google.rpc.BadRequest status details, which the HTTP gateway includes in the response body.
+// Such errors returned by handlers are converted as well.
+func WithValidation() Option {
+	return func(s *Service) {
+		s.validationEnabled = true
+	}
+}
+
+// WithCodec registers custom codec (e.g. vtprotobuf) for gRPC server.
+// Codec is selected by content-subtype, so clients must request the same codec name,
+// for example with grpc.CallContentSubtype(name). Codec registration is global for the process.
+func WithCodec(name string, codec encoding.Codec) Option {
+	return func(s *Service) {
+		if s.codecs == nil {
+			s.codecs = make(map[string]encoding.Codec)
+		}
+		s.codecs[name] = codec
+	}
+}
+
+// WithWarnOnMissingDeadline logs a warning for incoming gRPC requests without deadline.
+// If metrics are enabled, grpcsrv_missing_deadline_total counter is incremented as well.
+func WithWarnOnMissingDeadline() Option {
+	return func(s *Service) {
+		s.warnOnMissingDeadline = true
+	}
+}
+
+// WithAuditLog enables audit logging of specified methods (full method names, e.g. /package.Service/Method).
+// Audit record is sent to sink after the call is completed.
+func WithAuditLog(methods []string, sink IAuditSink) Option {
+	return func(s *Service) {
+		s.auditSink = sink
+		s.auditMethods = make(map[string]struct{}, len(methods))
+		for _, m := range methods {
+			s.auditMethods[m] = struct{}{}
+		}
+	}
+}
+
+// WithAuditPrincipal sets function for extracting caller identity for audit log.
+// If not set, common name of the verified client certificate is used (see PeerCertificateFromContext).
+func WithAuditPrincipal(principalFromCtx func(ctx context.Context) string) Option {
+	return func(s *Service) {
+		s.auditPrincipalFunc = principalFromCtx
+	}
+}
+
+// WithTrailerFilter removes trailers set by handlers and interceptors that are not in allow list,
+// so internal trailers don't leak to clients. Trailers set by the service itself (e.g. TraceIDKey) are preserved.
+func WithTrailerFilter(allow []string) Option {
+	return func(s *Service) {
+		s.trailerAllowlist = make(map[string]struct{}, len(allow)+1)
+		s.trailerAllowlist[TraceIDKey] = struct{}{}
+		for _, key := range allow {
+			s.trailerAllowlist[strings.ToLower(key)] = struct{}{}
+		}
+	}
+}
+
+// WithInitialWindowSize sets HTTP/2 flow-control window size for each stream of gRPC server.
+// Larger values improve streaming throughput over high-latency links. Values less than 64KB are ignored.
+func WithInitialWindowSize(n int32) Option {
+	return func(s *Service) {
+		s.grpcOptions = append(s.grpcOptions, grpc.InitialWindowSize(n))
+	}
+}
+
+// WithInitialConnWindowSize sets HTTP/2 flow-control window size for each connection of gRPC server.
+// Values less than 64KB are ignored.
+func WithInitialConnWindowSize(n int32) Option {
+	return func(s *Service) {
+		s.grpcOptions = append(s.grpcOptions, grpc.InitialConnWindowSize(n))
+	}
+}
+
+// WithReadBufferSize sets read buffer size of gRPC server connections and HTTP gateway connection to gRPC server.
+// Default is 32KB. Zero or negative value disables read buffer.
+func WithReadBufferSize(n int) Option {
+	return func(s *Service) {
+		s.grpcOptions = append(s.grpcOptions, grpc.ReadBufferSize(n))
+		s.httpDialBufferOptions = append(s.httpDialBufferOptions, grpc.WithReadBufferSize(n))
+	}
+}
+
+// WithWriteBufferSize sets write buffer size of gRPC server connections and HTTP gateway connection to gRPC server.
+// Default is 32KB. Zero or negative value disables write buffer.
+func WithWriteBufferSize(n int) Option {
+	return func(s *Service) {
+		s.grpcOptions = append(s.grpcOptions, grpc.WriteBufferSize(n))
+		s.httpDialBufferOptions = append(s.httpDialBufferOptions, grpc.WithWriteBufferSize(n))
+	}
+}
+
+// WithNumStreamWorkers sets number of worker goroutines for processing incoming streams
+// instead of creating a new goroutine per stream. Zero means a new goroutine per stream (default).
+// Blocking handlers occupy workers, so the number must be large enough for such workloads.
+func WithNumStreamWorkers(n uint32) Option {
+	return func(s *Service) {
+		s.grpcOptions = append(s.grpcOptions, grpc.NumStreamWorkers(n))
+	}
+}
+
+// WithLazyGateway defers creation of gRPC gateway multiplexer and its connection to gRPC server
+// until the first HTTP request. Useful for services with rare HTTP traffic.
+// The first request waits for the gateway initialization. If initialization fails,
+// HTTP requests are answered with 503 Service Unavailable and initialization is retried by subsequent requests
+// (at most once per second). Health check endpoints are served without initializing the gateway, so probes
+// don't trigger it. Configuration of marshallers and health check paths is validated by Start.
+func WithLazyGateway() Option {
+	return func(s *Service) {
+		s.lazyGateway = true
+	}
+}
+
+// WithoutOTelInstrumentation disables OpenTelemetry stats handlers of gRPC server and gateway connection,
+// HTTP tracing middleware and span enrichment. For latency-sensitive services with own instrumentation.
+// Options WithTracerProvider, WithPropagator and debug span payloads have no effect in this mode.
+func WithoutOTelInstrumentation() Option {
+	return func(s *Service) {
+		s.withoutOTel = true
+	}
+}
+
+// WithMetricLabelMapper maps full method names (e.g. "/package.Service/Method") to a bounded set of values
+// of the "method" label of prometheus metrics, to avoid high cardinality (e.g. with WithUnknownServiceHandler).
+// Methods mapped to the same value are aggregated under one series. Spans and OTel metrics recorded by otelgrpc
+// keep the real method name, since otelgrpc has no per-call attribute hook; limit their cardinality with views
+// of the meter provider (e.g. dropping rpc.method attribute).
+func WithMetricLabelMapper(mapper func(fullMethod string) string) Option {
+	return func(s *Service) {
+		s.metricLabelMapper = mapper
+	}
+}
+
+// WithStreamSendRateLimit limits the rate of messages sent to server streams of the given methods.
+// methods - full method name, e.g. "/package.Service/Method", to the number of messages per second.
+// Send waits until the next message is allowed or the stream context is done.
+func WithStreamSendRateLimit(methods map[string]float64) Option {
+	return func(s *Service) {
+		s.streamSendRateLimits = maps.Clone(methods)
+	}
+}
+
+// WithStreamHeartbeat periodically sends a message created by msgFactory to server streams of the given methods
+// if no messages were sent by the handler during the interval. Prevents idle streams from being dropped by proxies.
+// Methods are specified in full form, e.g. "/package.Service/Method". Heartbeats stop when the handler returns.
+// Only server streaming methods (without client streaming) whose response type is the type of the heartbeat
+// message are supported, heartbeats are disabled for other methods with a warning. Methods are resolved
+// in the global protobuf registry. Clients must be able to distinguish heartbeat messages from the real data,
+// e.g. by a dedicated field of the response. Heartbeats start after the handler sends the response header
+// (by SendHeader or with the first message), handlers of streams idle from the start should call SendHeader.
+func WithStreamHeartbeat(interval time.Duration, msgFactory func() proto.Message, methods ...string) Option {
+	return func(s *Service) {
+		if interval <= 0 || msgFactory == nil {
+			return
+		}
+
+		s.heartbeatInterval = interval
+		s.heartbeatMsgFactory = msgFactory
+		s.heartbeatMethods = make(map[string]struct{}, len(methods))
+		for _, m := range methods {
+			s.heartbeatMethods[m] = struct{}{}
+		}
+	}
+}
+
+// WithMaxInFlightWrites limits number of messages of a server stream that were sent by the handler
+// but not yet accepted by transport. When the limit is reached, SendMsg blocks until the client reads
+// enough data, which prevents unbounded memory growth when the handler sends faster than the client reads.
+// Messages are copied in SendMsg and written to transport asynchronously, send errors
+// are returned by subsequent SendMsg calls or by the handler call itself. Messages, which are not proto messages,
+// must not be modified after SendMsg.
+func WithMaxInFlightWrites(n int) Option {
+	return func(s *Service) {
+		s.maxInFlightWrites = n
+	}
+}
+
+// WithGatewayHealthForwarding registers HTTP endpoint on the path that calls gRPC Health.Check
+// for the service over the gateway connection and returns 200 for SERVING and 503 otherwise.
+// Empty service means the overall server status. gRPC health service must be registered by one of initializers.
+func WithGatewayHealthForwarding(path, service string) Option {
+	return func(s *Service) {
+		s.gatewayHealthPath = path
+		s.gatewayHealthService = service
+	}
+}
+
+// WithHandlerRetry retries handlers of the given methods with backoff while isRetriable returns true
+// for the handler error (e.g. transient database errors). Use only for idempotent methods.
+// Methods are specified in full form, e.g. "/package.Service/Method".
+func WithHandlerRetry(methods []string, policy RetryPolicy, isRetriable func(error) bool) Option {
+	return func(s *Service) {
+		if isRetriable == nil || policy.MaxAttempts <= 1 {
+			return
+		}
+
+		s.handlerRetryPolicy = policy
+		s.handlerRetryIsRetriable = isRetriable
+		s.handlerRetryMethods = make(map[string]struct{}, len(methods))
+		for _, m := range methods {
+			s.handlerRetryMethods[m] = struct{}{}
+		}
+	}
+}
+
+// WithRoutingErrorHandler sets handler of HTTP requests that don't match any gateway route
+// (e.g. because of trailing slash or wrong HTTP method). By default, the error is logged
+// and returned as JSON with 404 or 405 status.
+func WithRoutingErrorHandler(handler grpc_runtime.RoutingErrorHandlerFunc) Option {
+	return func(s *Service) {
+		s.httpRoutingErrorHandler = handler
+	}
+}
+
+// WithConnectProtocol serves unary calls of Connect protocol (connectrpc.com) on HTTP gateway endpoint.
+// Requests are routed by path "/package.Service/Method" and forwarded to the same gRPC services.
+// JSON and binary protobuf encodings are supported, streaming calls, compression and error details are not.
+// Message types must be registered in the global protobuf registry (which is done by generated code).
+func WithConnectProtocol() Option {
+	return func(s *Service) {
+		s.connectProtocol = true
+	}
+}
+
+// WithJWTClaims requires JWT bearer token in "authorization" metadata of gRPC calls. The token signature
+// is verified with the key returned by keyFunc, expiration and other registered claims are validated.
+// keyFunc must check the signing algorithm of the token (token.Method) before returning the key.
+// The token is parsed into claims created by claimsFactory, e.g. a pointer to a struct embedding
+// jwt.RegisteredClaims. Claims are available in handlers via ClaimsFromContext.
+// Missing, invalid or expired tokens are rejected with codes.Unauthenticated.
+// methods - full method names requiring the token, e.g. "/package.Service/Method". If empty, all methods
+// require the token except the skipped ones (see WithJWTClaimsSkipMethods). Health check and reflection
+// services are skipped by default, so health probes and grpcurl work without a token.
+func WithJWTClaims(keyFunc jwt.Keyfunc, claimsFactory func() jwt.Claims, methods ...string) Option {
+	return func(s *Service) {
+		if keyFunc == nil || claimsFactory == nil {
+			panic("keyFunc and claimsFactory must not be nil")
+		}
+
+		s.claimsKeyFunc = keyFunc
+		s.claimsFactory = claimsFactory
+		s.claimsMethods = make(map[string]struct{}, len(methods))
+		for _, m := range methods {
+			s.claimsMethods[m] = struct{}{}
+		}
+	}
+}
+
+// WithJWTClaimsSkipMethods sets methods that don't require bearer token when WithJWTClaims is applied
+// to all methods. Full method names ("/package.Service/Method") or services ("/package.Service/") are accepted.
+// Replaces the default list of health check and reflection services, include them if needed.
+func WithJWTClaimsSkipMethods(methods ...string) Option {
+	return func(s *Service) {
+		s.claimsSkipMethods = append([]string{}, methods...)
+	}
+}
+
+// WithMaxMetadataSize rejects gRPC calls with incoming metadata larger than n bytes with codes.ResourceExhausted.
+// Size is calculated as HTTP/2 header list size. Metadata exceeding the transport limit
+// (grpc.MaxHeaderListSize) is rejected by gRPC before this check, so n should be less than that limit.
+func WithMaxMetadataSize(n int) Option {
+	return func(s *Service) {
+		s.maxMetadataSize = n
+	}
+}
+
+// WithHTTPHandlerChainInspector logs order of HTTP gateway middlewares (from outer to inner) at startup.
+// If timing is true, time spent in each middleware (including inner ones) is logged at debug level
+// for every request. For debugging only.
+func WithHTTPHandlerChainInspector(timing bool) Option {
+	return func(s *Service) {
+		s.httpChainInspector = true
+		s.httpChainTiming = timing
+	}
+}
+
+// WithGRPCStatusHeader sets GRPCStatusCodeHeader and GRPCStatusMessageHeader headers in HTTP gateway responses
+// with the original gRPC status regardless of HTTP status mapping.
+func WithGRPCStatusHeader() Option {
+	return func(s *Service) {
+		s.grpcStatusHeader = true
+	}
+}
+
+// WithStreamAsJSONArray returns responses of the given server streaming methods from HTTP gateway
+// as a single JSON array instead of newline-delimited JSON objects. Elements are written as they arrive,
+// but clients that parse the whole array can't process messages incrementally.
+// Streams without messages return an empty JSON array ("[]").
+// Methods are specified in full form, e.g. "/package.Service/Method".
+func WithStreamAsJSONArray(methods ...string) Option {
+	return func(s *Service) {
+		s.streamJSONArrayMethods = make(map[string]struct{}, len(methods))
+		for _, m := range methods {
+			s.streamJSONArrayMethods[m] = struct{}{}
+		}
+	}
+}
+
+// WithInprocessGateway connects HTTP gateway to gRPC server via in-memory connection instead of TCP,
+// which removes loopback network round trip. All gRPC server options and interceptors are applied
+// as for TCP connections. Gateway dial options (WithHTTPDialOptions) must match the server credentials.
+func WithInprocessGateway() Option {
+	return func(s *Service) {
+		s.inprocessGateway = true
+	}
+}
+
+// WithInitFunc sets function (e.g. migrations, cache warm-up) that runs in Start before listeners start.
+// Start fails if the function returns an error. The function receives the context of Start, so it is bounded
+// by the startup timeout.
+func WithInitFunc(fn func(ctx context.Context) error) Option {
+	return func(s *Service) {
+		s.initFunc = fn
+	}
+}
+
+// WithAsyncInitFunc sets function that runs in background after listeners start.
+// Readiness endpoints report not ready until the function completes successfully,
+// if it fails the service stays not ready. The function context is canceled by Stop.
+func WithAsyncInitFunc(fn func(ctx context.Context) error) Option {
+	return func(s *Service) {
+		s.asyncInitFunc = fn
+	}
+}
+
+// WithStopSequence sets order of stopping servers in Stop. Each server from the sequence is stopped
+// after the previous one has been stopped. Servers not in the sequence are stopped after that:
+// HTTP servers concurrently, then gRPC server.
+// By default HTTP gateway, pprof and metrics servers are stopped concurrently, then gRPC server,
+// so in-flight gateway requests can complete.
+func WithStopSequence(seq []ServerKind) Option {
+	return func(s *Service) {
+		s.stopSequence = seq
+	}
+}
+
 // WithHealthCheck sets handler for service health checks.
 func WithHealthCheck(handler IHealther, livenessHandlerPath, readinessHandlerPath string) Option {
 	return func(s *Service) {
@@ -81,6 +635,52 @@ func WithHealthCheck(handler IHealther, livenessHandlerPath, readinessHandlerPat
 	}
 }
 
+// HealthPath additional path for health check endpoint.
+type HealthPath struct {
+	Path      string
+	Readiness bool // readiness check if true, liveness check otherwise
+}
+
+// WithHealthPaths exposes liveness and readiness checks set by WithHealthCheck at additional paths
+// (e.g. /healthz, /livez, /readyz).
+func WithHealthPaths(paths []HealthPath) Option {
+	return func(s *Service) {
+		s.healthPaths = append(s.healthPaths, paths...)
+	}
+}
+
+// WithHealthContentType sets content type of liveness and readiness responses (e.g. application/json),
+// overriding the one set by IHealther.
+func WithHealthContentType(contentType string) Option {
+	return func(s *Service) {
+		s.healthContentType = contentType
+	}
+}
+
+// WithReadinessGracePeriod debounces readiness endpoint set by WithHealthCheck to avoid flapping.
+// Not ready is reported only after the check has been failing for failureGrace,
+// ready is reported again only after the check has been successful for recoveryGrace.
+func WithReadinessGracePeriod(failureGrace, recoveryGrace time.Duration) Option {
+	return func(s *Service) {
+		s.readinessFailureGrace = failureGrace
+		s.readinessRecoveryGrace = recoveryGrace
+	}
+}
+
+// WithDrainDelay sets delay between marking the service as not ready and stopping it (see Drain).
+func WithDrainDelay(delay time.Duration) Option {
+	return func(s *Service) {
+		s.drainDelay = delay
+	}
+}
+
+// WithShutdownTimeout sets timeout for graceful shutdown in RunUntilSignal, including drain delay.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(s *Service) {
+		s.shutdownTimeout = timeout
+	}
+}
+
 // WithName sets the service name.
 func WithName(name string) Option {
 	return func(s *Service) {
@@ -95,6 +695,24 @@ func WithRecover() Option {
 	}
 }
 
+// WithPanicClassifier sets function for converting known panics to specific gRPC codes
+// (e.g. codes.InvalidArgument for validation panics of a library) during recovery.
+// If classifier returns false, codes.Internal is used.
+func WithPanicClassifier(classifier func(p any) (codes.Code, string, bool)) Option {
+	return func(s *Service) {
+		s.panicClassifier = classifier
+	}
+}
+
+// WithStreamMessageRecover enables panic recovery while receiving stream messages (e.g. panic in codec).
+// Panic returns an error from RecvMsg for that message instead of crashing the whole stream.
+// Handler logic is not recovered: the handler decides whether to continue the stream after the error.
+func WithStreamMessageRecover() Option {
+	return func(s *Service) {
+		s.streamMessageRecoverEnabled = true
+	}
+}
+
 // WithHTTPDialOptions sets options for HTTP gateway client when connecting to gRPC endpoint.
 // If not set, grpc.WithTransportCredentials(insecure.NewCredentials()) is used.
 func WithHTTPDialOptions(options ...grpc.DialOption) Option {
@@ -111,6 +729,37 @@ func WithHTTPMarshallers(marshallers map[string]grpc_runtime.Marshaler) Option {
 	}
 }
 
+// WithGatewayMarshalerMatcher sets function that selects marshaller of HTTP gateway for content type.
+// The matcher is consulted for each of contentTypes when the gateway is created, marshallers set by
+// WithHTTPMarshallers take precedence. Accept header with several media types and quality values
+// and Content-Type header with parameters are matched against the registered content types,
+// so requests can select the marshaller via Accept header.
+func WithGatewayMarshalerMatcher(
+	matcher func(contentType string) (grpc_runtime.Marshaler, bool), contentTypes ...string,
+) Option {
+	return func(s *Service) {
+		s.gatewayMarshalerMatcher = matcher
+		s.gatewayMatcherContentTypes = contentTypes
+	}
+}
+
+// WithStrictJSON makes default JSON marshaller of HTTP gateway report unknown fields in request body
+// with their names (400 Bad Request). Not applied if JSON marshaller is set by WithHTTPMarshallers.
+func WithStrictJSON() Option {
+	return func(s *Service) {
+		s.httpStrictJSON = true
+	}
+}
+
+// WithCaseInsensitiveEnums makes default JSON marshaller of HTTP gateway accept enum values
+// in any case (e.g. "active" for ACTIVE), including nested messages.
+// Enum value names in proto files must be upper case. Not applied if JSON marshaller is set by WithHTTPMarshallers.
+func WithCaseInsensitiveEnums() Option {
+	return func(s *Service) {
+		s.httpCaseInsensitiveEnums = true
+	}
+}
+
 // WithHTTPHeadersFromMetadata passes specified gRPC metadata to headers
 // For example, if you need a Location header in response, adding such metadata
 // will result in a Grpc-Metadata-Location header.
@@ -120,6 +769,31 @@ func WithHTTPHeadersFromMetadata(headers ...string) Option {
 	}
 }
 
+// WithHTTPStatusMapping overrides default mapping of gRPC codes to HTTP statuses in gateway error responses.
+// For example, {codes.FailedPrecondition: http.StatusConflict}.
+func WithHTTPStatusMapping(mapping map[codes.Code]int) Option {
+	return func(s *Service) {
+		s.httpStatusMapping = mapping
+	}
+}
+
+// WithGatewayResponseModifier adds functions for post-processing successful HTTP gateway responses
+// (e.g. setting headers). Modifiers are called after headers from metadata are set
+// (see WithHTTPHeadersFromMetadata), so they can override them. The response body is not written yet.
+func WithGatewayResponseModifier(modifiers ...GatewayResponseModifier) Option {
+	return func(s *Service) {
+		s.gatewayResponseModifiers = append(s.gatewayResponseModifiers, modifiers...)
+	}
+}
+
+// WithGatewayResponseRewriter sets function for rewriting successful HTTP gateway response body
+// before marshalling (e.g. wrapping it in an envelope).
+func WithGatewayResponseRewriter(rewriter grpc_runtime.ForwardResponseRewriter) Option {
+	return func(s *Service) {
+		s.gatewayResponseRewriter = rewriter
+	}
+}
+
 // WithCORSOptions sets options for CORS.
 func WithCORSOptions(options cors.Options) Option {
 	return func(s *Service) {
@@ -128,6 +802,7 @@ func WithCORSOptions(options cors.Options) Option {
 }
 
 // WithMetrics sets endpoint for prometheus metrics server.
+// Call duration histograms contain exemplars with trace IDs of sampled requests (OpenMetrics format only).
 func WithMetrics(endpoint string) Option {
 	return func(s *Service) {
 		s.metricsEndpoint = endpoint
@@ -156,15 +831,81 @@ func WithPanicLogger(panicLogger func(ctx context.Context, p any)) Option {
 	}
 }
 
-// WithContextModifiers sets function for enriching context before calling handlers.
-// For example, for setting logger or config in context.
+// WithContextModifiers adds functions for enriching context before calling handlers.
+// For example, for setting logger or config in context. Modifiers are appended to the ones
+// set by previous calls or by GetCtxLogOptions and are called in the order they were added.
+// Nil modifiers are ignored.
 func WithContextModifiers(
 	ctxUnaryModifier CtxUnaryModifier, ctxStreamModifier CtxStreamModifier, ctxHTTPModifier CtxHTTPModifier,
 ) Option {
+	return WithAppendContextModifiers(ctxUnaryModifier, ctxStreamModifier, ctxHTTPModifier)
+}
+
+// WithAppendContextModifiers adds functions for enriching context before calling handlers.
+// Same as WithContextModifiers. Modifiers are called in the order they were added, nil modifiers are ignored.
+func WithAppendContextModifiers(
+	ctxUnaryModifier CtxUnaryModifier, ctxStreamModifier CtxStreamModifier, ctxHTTPModifier CtxHTTPModifier,
+) Option {
+	return func(s *Service) {
+		if ctxUnaryModifier != nil {
+			s.ctxUnaryModifiers = append(s.ctxUnaryModifiers, ctxUnaryModifier)
+		}
+		if ctxStreamModifier != nil {
+			s.ctxStreamModifiers = append(s.ctxStreamModifiers, ctxStreamModifier)
+		}
+		if ctxHTTPModifier != nil {
+			s.ctxHTTPModifiers = append(s.ctxHTTPModifiers, ctxHTTPModifier)
+		}
+	}
+}
+
+// WithTracerProvider sets OpenTelemetry tracer provider for the service.
+// If not set, the global tracer provider is used.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(s *Service) {
+		s.tracerProvider = tp
+	}
+}
+
+// WithSpanAttributes adds attributes (e.g. deployment.env) to server spans of all gRPC requests.
+// Spans are also tagged with rpc.service and rpc.method attributes.
+func WithSpanAttributes(attrs ...attribute.KeyValue) Option {
 	return func(s *Service) {
-		s.ctxUnaryModifier = ctxUnaryModifier
-		s.ctxStreamModifier = ctxStreamModifier
-		s.ctxHTTPModifier = ctxHTTPModifier
+		s.spanAttributes = append(s.spanAttributes, attrs...)
+	}
+}
+
+// WithAlwaysCreateServerSpan creates grpc_data span with method, remote address and status attributes
+// for every unary request. Request and response payloads are still added only for requests
+// with debug header (see TraceDebugKey).
+func WithAlwaysCreateServerSpan() Option {
+	return func(s *Service) {
+		s.alwaysCreateServerSpan = true
+	}
+}
+
+// WithPropagator sets OpenTelemetry propagator for trace context (e.g. only W3C trace context).
+// If not set, the global propagator is used.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(s *Service) {
+		s.propagator = p
+	}
+}
+
+// WithBaggageLogKeys sets list of OpenTelemetry baggage members that will be added to ctxlog logger fields.
+// Works only if context modifiers put ctxlog.Logger into the request context (see GetCtxLogOptions).
+func WithBaggageLogKeys(keys ...string) Option {
+	return func(s *Service) {
+		s.baggageLogKeys = keys
+	}
+}
+
+// WithLoggerFields sets static fields (e.g. version, region) that will be added to ctxlog logger fields
+// of every request. kv is a list of key-value pairs.
+// Works only if context modifiers put ctxlog.Logger into the request context (see GetCtxLogOptions).
+func WithLoggerFields(kv ...any) Option {
+	return func(s *Service) {
+		s.loggerFields = append(s.loggerFields, kv...)
 	}
 }
 
@@ -176,9 +917,23 @@ func WithRegisterHTTPEndpoints(registerHealthCheckEndpoints RegisterHTTPEndpoint
 }
 
 // WithSanitizeKeys sets list of keys whose values will be replaced with "sanitized" in logs and spans.
-// Default: password, token, refreshToken, accessToken.
+// Default: password, token, refreshToken, accessToken. Call without keys keeps the current list,
+// use WithoutSanitization to disable sanitizing.
 func WithSanitizeKeys(keys ...string) Option {
 	return func(s *Service) {
+		if len(keys) == 0 {
+			return
+		}
+
 		s.sanitizeKeys = keys
+		s.sanitizeDisabled = false
+	}
+}
+
+// WithoutSanitization disables replacing of sensitive values in logs and spans.
+func WithoutSanitization() Option {
+	return func(s *Service) {
+		s.sanitizeKeys = nil
+		s.sanitizeDisabled = true
 	}
 }
@@ -0,0 +1,110 @@
+package grpcsrv
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	api "github.com/n-r-w/grpcsrv/example/protogen"
+)
+
+// receiveHellos calls SayManyHellos and returns number of received messages.
+func receiveHellos(t *testing.T, s *Service) int {
+	t.Helper()
+
+	stream, err := api.NewGreeterClient(dialTestService(t, s)).SayManyHellos(context.Background(),
+		&api.HelloRequest{Name: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := 0
+	for {
+		if _, err = stream.Recv(); err == io.EOF {
+			return n
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+}
+
+func TestWindowSizeOptions(t *testing.T) {
+	const count = 500
+	s, _ := startGatewayTestService(t, &testGreeter{count: count},
+		WithInitialWindowSize(1<<20), WithInitialConnWindowSize(2<<20))
+
+	if n := receiveHellos(t, s); n != count {
+		t.Fatalf("expected %d messages, got %d", count, n)
+	}
+}
+
+func TestBufferSizeOptions(t *testing.T) {
+	const count = 100
+	s, baseURL := startGatewayTestService(t, &testGreeter{count: count},
+		WithReadBufferSize(0), WithWriteBufferSize(64<<10))
+
+	if len(s.httpDialBufferOptions) != 2 {
+		t.Fatalf("buffer sizes must be applied to gateway connection, got %d options", len(s.httpDialBufferOptions))
+	}
+
+	if n := receiveHellos(t, s); n != count {
+		t.Fatalf("expected %d messages, got %d", count, n)
+	}
+
+	resp, body := doHTTP(t, http.MethodPost, baseURL+"/v1/greeter:SayHello", `{"name":"bob"}`, nil)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "hello bob") {
+		t.Fatalf("unexpected gateway response: %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestNumStreamWorkers(t *testing.T) {
+	const (
+		workers = 2
+		calls   = 20
+	)
+
+	var inFlight, maxInFlight atomic.Int32
+	greeter := &testGreeter{sayHello: func(_ context.Context, req *api.HelloRequest) (*api.HelloResponse, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		return &api.HelloResponse{Message: "hello " + req.GetName()}, nil
+	}}
+	s, _ := startGatewayTestService(t, greeter, WithNumStreamWorkers(workers))
+	client := api.NewGreeterClient(dialTestService(t, s))
+
+	// calls exceeding the number of workers are processed in new goroutines
+	var wg sync.WaitGroup
+	errs := make(chan error, calls)
+	for range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.SayHello(context.Background(), &api.HelloRequest{Name: "bob"}); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if maxInFlight.Load() <= workers {
+		t.Fatalf("expected more than %d concurrent calls, got %d", workers, maxInFlight.Load())
+	}
+}
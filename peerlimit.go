@@ -0,0 +1,130 @@
+package grpcsrv
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// metadata key with address of HTTP client, added by HTTP gateway.
+	gatewayPeerMetadataKey = "x-grpcsrv-gateway-peer"
+	// metadata key with secret token, proving that the request is sent by HTTP gateway of this service.
+	gatewayTokenMetadataKey = "x-grpcsrv-gateway-token"
+)
+
+// peerLimiter limits the number of concurrent requests per remote address.
+// Requests forwarded by HTTP gateway are limited by address of HTTP client instead of gateway address.
+type peerLimiter struct {
+	limit        int
+	gatewayToken string // random token, which is known only to HTTP gateway of this service
+
+	mu       sync.Mutex
+	inFlight map[string]int // remote address -> number of requests in progress
+}
+
+func newPeerLimiter(limit int) *peerLimiter {
+	const tokenSize = 16
+	b := make([]byte, tokenSize)
+	_, _ = rand.Read(b)
+
+	return &peerLimiter{
+		limit:        limit,
+		gatewayToken: hex.EncodeToString(b),
+		inFlight:     make(map[string]int),
+	}
+}
+
+// peerAddr returns the key for limiting the request. Requests from HTTP gateway are keyed by the address
+// of HTTP client, all other requests by the remote address of connection.
+func (l *peerLimiter) peerAddr(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		// grpc-gateway appends annotator metadata after metadata from client headers,
+		// so the last value is the one set by the gateway.
+		tokens := md.Get(gatewayTokenMetadataKey)
+		peers := md.Get(gatewayPeerMetadataKey)
+		if len(tokens) > 0 && len(peers) > 0 &&
+			subtle.ConstantTimeCompare([]byte(tokens[len(tokens)-1]), []byte(l.gatewayToken)) == 1 {
+			return peers[len(peers)-1]
+		}
+	}
+
+	return extractRemoteAddr(ctx)
+}
+
+// acquire reserves a slot for the peer. Returns false if the limit is exceeded.
+func (l *peerLimiter) acquire(addr string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight[addr] >= l.limit {
+		return false
+	}
+	l.inFlight[addr]++
+
+	return true
+}
+
+// release frees a slot of the peer. Counters of idle peers are removed.
+func (l *peerLimiter) release(addr string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight[addr] <= 1 {
+		delete(l.inFlight, addr)
+		return
+	}
+	l.inFlight[addr]--
+}
+
+// interceptor for limiting concurrent unary requests per peer.
+func (s *Service) peerLimitUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	addr := s.peerLimiter.peerAddr(ctx)
+	if !s.peerLimiter.acquire(addr) {
+		s.logger.Warn(ctx, "per-peer concurrency limit exceeded", "remote_addr", addr, "method", info.FullMethod)
+		return nil, status.Error(codes.ResourceExhausted, "too many concurrent requests")
+	}
+	defer s.peerLimiter.release(addr)
+
+	return handler(ctx, req)
+}
+
+// interceptor for limiting concurrent streams per peer.
+func (s *Service) peerLimitStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	addr := s.peerLimiter.peerAddr(ss.Context())
+	if !s.peerLimiter.acquire(addr) {
+		s.logger.Warn(ss.Context(), "per-peer concurrency limit exceeded",
+			"remote_addr", addr, "method", info.FullMethod)
+		return status.Error(codes.ResourceExhausted, "too many concurrent requests")
+	}
+	defer s.peerLimiter.release(addr)
+
+	return handler(srv, ss)
+}
+
+// propagateGatewayPeer passes address of HTTP client to the gRPC server for per-peer limiting.
+// The address is taken from the connection, X-Forwarded-For header is not trusted.
+func (s *Service) propagateGatewayPeer(_ context.Context, r *http.Request) metadata.MD {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return metadata.Pairs(
+		gatewayPeerMetadataKey, host,
+		gatewayTokenMetadataKey, s.peerLimiter.gatewayToken,
+	)
+}
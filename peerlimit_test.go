@@ -0,0 +1,105 @@
+package grpcsrv
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func peerContext(host string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP(host), Port: 12345},
+	})
+}
+
+func TestPeerLimiterAddr(t *testing.T) {
+	s := New(context.Background(), nil, WithPerPeerConcurrencyLimit(1))
+	s.peerLimiter = newPeerLimiter(s.perPeerConcurrencyLimit)
+
+	r := httptest.NewRequest("GET", "/v1/test", nil)
+	r.RemoteAddr = "10.0.0.7:5555"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+	gatewayMD := s.propagateGatewayPeer(context.Background(), r)
+
+	tests := []struct {
+		name string
+		md   metadata.MD
+		want string
+	}{
+		{name: "direct client", want: "127.0.0.1"},
+		{name: "gateway client", md: gatewayMD, want: "10.0.0.7"},
+		{
+			name: "forged token",
+			md:   metadata.Pairs(gatewayPeerMetadataKey, "1.2.3.4", gatewayTokenMetadataKey, "forged"),
+			want: "127.0.0.1",
+		},
+		{
+			name: "client metadata before gateway metadata",
+			md:   metadata.Join(metadata.Pairs(gatewayPeerMetadataKey, "1.2.3.4"), gatewayMD),
+			want: "10.0.0.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peerContext("127.0.0.1")
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+
+			if got := s.peerLimiter.peerAddr(ctx); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPeerLimitUnaryInterceptor(t *testing.T) {
+	s := New(context.Background(), nil, WithPerPeerConcurrencyLimit(1))
+	s.peerLimiter = newPeerLimiter(s.perPeerConcurrencyLimit)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	blocking := func(context.Context, any) (any, error) {
+		close(started)
+		<-unblock
+		return nil, nil
+	}
+	noop := func(context.Context, any) (any, error) { return nil, nil }
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = s.peerLimitUnaryInterceptor(peerContext("10.0.0.1"), nil, info, blocking)
+	}()
+	<-started
+
+	_, err := s.peerLimitUnaryInterceptor(peerContext("10.0.0.1"), nil, info, noop)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted for the same peer, got %v", err)
+	}
+
+	if _, err = s.peerLimitUnaryInterceptor(peerContext("10.0.0.2"), nil, info, noop); err != nil {
+		t.Fatalf("other peer must not be limited: %v", err)
+	}
+
+	close(unblock)
+	wg.Wait()
+
+	if _, err = s.peerLimitUnaryInterceptor(peerContext("10.0.0.1"), nil, info, noop); err != nil {
+		t.Fatalf("slot must be released: %v", err)
+	}
+	if len(s.peerLimiter.inFlight) != 0 {
+		t.Fatalf("idle peers must be removed, got %v", s.peerLimiter.inFlight)
+	}
+}
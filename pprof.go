@@ -70,15 +70,17 @@ func (s *Service) startPProfServer(ctx context.Context) error {
 		return nil
 	}
 
+	// listen synchronously so that bind errors abort Start
+	listener, err := net.Listen("tcp", s.pprofEndpoint)
+	if err != nil {
+		return fmt.Errorf("%s. failed to start pprof server listener on %s: %w", s.name, s.pprofEndpoint, err)
+	}
+
 	s.pprofServer = &http.Server{
 		Addr:              s.pprofEndpoint,
 		Handler:           getPProfHandler(),
 		ReadHeaderTimeout: s.httpReadHeaderTimeout,
-	}
-
-	listener, err := net.Listen("tcp", s.pprofEndpoint)
-	if err != nil {
-		return fmt.Errorf("failed to start pprof server listener: %w", err)
+		MaxHeaderBytes:    s.httpMaxHeaderBytes,
 	}
 
 	s.wg.Add(1)
@@ -0,0 +1,170 @@
+package grpcsrv
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// RateLimit parameters of token bucket rate limiting.
+type RateLimit struct {
+	// Rate number of requests per second. Must be positive.
+	Rate float64
+	// Burst maximum number of requests at once. Must be positive.
+	Burst int
+	// MaxKeys maximum number of keys with tracked buckets. When exceeded, the least recently used key is removed
+	// and its next request starts with a full bucket. 0 means 100000.
+	MaxKeys int
+	// RejectEmptyKey rejects requests without a key with codes.Unauthenticated.
+	// Otherwise such requests share a single bucket.
+	RejectEmptyKey bool
+}
+
+const defaultRateLimitMaxKeys = 100000
+
+// tokenBucket token bucket state.
+type tokenBucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// keyRateLimiter applies token bucket per key.
+type keyRateLimiter struct {
+	limit   RateLimit
+	refill  time.Duration // time to refill an empty bucket completely
+	maxKeys int
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	lru     *list.List // front is the most recently used
+}
+
+func newKeyRateLimiter(limit RateLimit) *keyRateLimiter {
+	maxKeys := limit.MaxKeys
+	if maxKeys <= 0 {
+		maxKeys = defaultRateLimitMaxKeys
+	}
+
+	return &keyRateLimiter{
+		limit:   limit,
+		refill:  time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second)),
+		maxKeys: maxKeys,
+		buckets: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// allow takes a token from the bucket of the key.
+// If the bucket is empty, returns false and the time until the next token is available.
+func (l *keyRateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	var b *tokenBucket
+	if e, ok := l.buckets[key]; ok {
+		b, _ = e.Value.(*tokenBucket)
+		l.lru.MoveToFront(e)
+	} else {
+		b = &tokenBucket{key: key, tokens: float64(l.limit.Burst), last: now}
+		l.buckets[key] = l.lru.PushFront(b)
+		if l.lru.Len() > l.maxKeys {
+			l.removeElement(l.lru.Back())
+		}
+	}
+
+	b.tokens = min(float64(l.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*l.limit.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.limit.Rate * float64(time.Second))
+	}
+	b.tokens--
+
+	return true, 0
+}
+
+// sweep removes buckets that have been refilled completely. Must be called with mu locked.
+// Buckets are ordered by the last use, so only the removed ones are visited.
+func (l *keyRateLimiter) sweep(now time.Time) {
+	for e := l.lru.Back(); e != nil; e = l.lru.Back() {
+		if b, _ := e.Value.(*tokenBucket); now.Sub(b.last) <= l.refill {
+			return
+		}
+		l.removeElement(e)
+	}
+}
+
+func (l *keyRateLimiter) removeElement(e *list.Element) {
+	b, _ := l.lru.Remove(e).(*tokenBucket)
+	delete(l.buckets, b.key)
+}
+
+// checks rate limit for the API key of the request.
+// Returns the time until the next request of the key is allowed for rejected requests.
+func (s *Service) checkAPIKeyRateLimit(ctx context.Context, method string) (time.Duration, error) {
+	key := s.apiKeyFromCtx(ctx)
+	if key == "" && s.apiKeyRateLimiter.limit.RejectEmptyKey {
+		return 0, status.Error(codes.Unauthenticated, "API key required")
+	}
+
+	ok, retryAfter := s.apiKeyRateLimiter.allow(key)
+	if ok {
+		return 0, nil
+	}
+
+	s.logger.Debug(ctx, "API key rate limit exceeded", "method", method, "retry_after", retryAfter)
+
+	st := status.New(codes.ResourceExhausted, "rate limit exceeded")
+	if stDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+		st = stDetails
+	}
+
+	return retryAfter, st.Err()
+}
+
+// retryAfterMD returns trailer, which is converted to Retry-After HTTP header by the gateway.
+func retryAfterMD(retryAfter time.Duration) metadata.MD {
+	return metadata.Pairs(RetryAfterKey, strconv.FormatInt(int64(math.Ceil(retryAfter.Seconds())), 10))
+}
+
+// interceptor for per API key rate limiting of unary requests.
+func (s *Service) apiKeyRateLimitUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if retryAfter, err := s.checkAPIKeyRateLimit(ctx, info.FullMethod); err != nil {
+		if retryAfter > 0 {
+			_ = grpc.SetTrailer(ctx, retryAfterMD(retryAfter))
+		}
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// interceptor for per API key rate limiting of streams.
+func (s *Service) apiKeyRateLimitStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if retryAfter, err := s.checkAPIKeyRateLimit(ss.Context(), info.FullMethod); err != nil {
+		if retryAfter > 0 {
+			ss.SetTrailer(retryAfterMD(retryAfter))
+		}
+		return err
+	}
+
+	return handler(srv, ss)
+}
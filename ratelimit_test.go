@@ -0,0 +1,187 @@
+package grpcsrv
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// allowed reports whether the request of the key is allowed by the limiter.
+func allowed(l *keyRateLimiter, key string) bool {
+	ok, _ := l.allow(key)
+	return ok
+}
+
+func TestKeyRateLimiter(t *testing.T) {
+	l := newKeyRateLimiter(RateLimit{Rate: 1000, Burst: 2})
+
+	if !allowed(l, "a") || !allowed(l, "a") {
+		t.Fatal("burst must be allowed")
+	}
+	ok, retryAfter := l.allow("a")
+	if ok {
+		t.Fatal("request over burst must be rejected")
+	}
+	if retryAfter <= 0 || retryAfter > time.Millisecond {
+		t.Fatalf("expected retry after up to 1ms, got %s", retryAfter)
+	}
+	if !allowed(l, "b") {
+		t.Fatal("keys must have separate buckets")
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if !allowed(l, "a") {
+		t.Fatal("bucket must be refilled")
+	}
+}
+
+func TestKeyRateLimiterSweep(t *testing.T) {
+	l := newKeyRateLimiter(RateLimit{Rate: 10, Burst: 1})
+	allowed(l, "old")
+
+	l.mu.Lock()
+	b, _ := l.buckets["old"].Value.(*tokenBucket)
+	b.last = time.Now().Add(-time.Second)
+	l.mu.Unlock()
+
+	allowed(l, "new")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.buckets["old"]; ok {
+		t.Fatal("refilled bucket must be removed")
+	}
+	if _, ok := l.buckets["new"]; !ok {
+		t.Fatal("active bucket must be kept")
+	}
+}
+
+func TestKeyRateLimiterMaxKeys(t *testing.T) {
+	l := newKeyRateLimiter(RateLimit{Rate: 1e-6, Burst: 1, MaxKeys: 2})
+
+	allowed(l, "a")
+	allowed(l, "b")
+	if allowed(l, "a") {
+		t.Fatal("request over burst must be rejected")
+	}
+
+	// "b" is the least recently used key
+	allowed(l, "c")
+
+	l.mu.Lock()
+	n := len(l.buckets)
+	_, okA := l.buckets["a"]
+	_, okB := l.buckets["b"]
+	l.mu.Unlock()
+
+	if n != 2 || !okA || okB {
+		t.Fatalf("expected keys a and c, got %d keys (a: %v, b: %v)", n, okA, okB)
+	}
+}
+
+func TestAPIKeyRateLimitValidation(t *testing.T) {
+	for _, limit := range []RateLimit{{Rate: 0, Burst: 1}, {Rate: -1, Burst: 1}, {Rate: 1, Burst: 0}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected panic for %+v", limit)
+				}
+			}()
+			New(context.Background(), nil, WithAPIKeyRateLimit(testAPIKeyFromCtx, limit))
+		}()
+	}
+}
+
+func TestKeyRateLimiterConcurrent(t *testing.T) {
+	const burst = 50
+	l := newKeyRateLimiter(RateLimit{Rate: 1e-6, Burst: burst})
+
+	var (
+		allowedN atomic.Int32
+		wg       sync.WaitGroup
+	)
+	for range 4 * burst {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if allowed(l, "key") {
+				allowedN.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowedN.Load() != burst {
+		t.Fatalf("expected %d allowed requests, got %d", burst, allowedN.Load())
+	}
+}
+
+func testAPIKeyFromCtx(ctx context.Context) string {
+	if v := metadata.ValueFromIncomingContext(ctx, "x-api-key"); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func TestAPIKeyRateLimitUnaryInterceptor(t *testing.T) {
+	s := New(context.Background(), nil,
+		WithAPIKeyRateLimit(testAPIKeyFromCtx, RateLimit{Rate: 1e-6, Burst: 1, RejectEmptyKey: true}))
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(context.Context, any) (any, error) { return nil, nil }
+	keyCtx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "key"))
+
+	if _, err := s.apiKeyRateLimitUnaryInterceptor(keyCtx, nil, info, handler); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	_, err := s.apiKeyRateLimitUnaryInterceptor(keyCtx, nil, info, handler)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+	var retryInfo *errdetails.RetryInfo
+	for _, d := range status.Convert(err).Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			retryInfo = ri
+		}
+	}
+	if retryInfo == nil || retryInfo.GetRetryDelay().AsDuration() <= 0 {
+		t.Fatalf("expected RetryInfo with positive delay, got %v", status.Convert(err).Details())
+	}
+
+	_, err = s.apiKeyRateLimitUnaryInterceptor(context.Background(), nil, info, handler)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated for empty key, got %v", err)
+	}
+
+	ss := &trailerServerStream{testServerStream: testServerStream{ctx: keyCtx}}
+	err = s.apiKeyRateLimitStreamInterceptor(nil, ss,
+		&grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}, func(any, grpc.ServerStream) error { return nil })
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted for stream, got %v", err)
+	}
+	if v := ss.trailer.Get(RetryAfterKey); len(v) != 1 || v[0] == "0" {
+		t.Fatalf("expected retry-after trailer, got %v", v)
+	}
+}
+
+func BenchmarkKeyRateLimiter(b *testing.B) {
+	l := newKeyRateLimiter(RateLimit{Rate: 1e9, Burst: 1e9})
+	keys := []string{"a", "b", "c", "d"}
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			_, _ = l.allow(keys[i%len(keys)])
+			i++
+		}
+	})
+}
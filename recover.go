@@ -3,15 +3,25 @@ package grpcsrv
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"runtime/debug"
 
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/n-r-w/ctxlog"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-func errFromPanic(p any) error {
+// errFromPanic converts panic to gRPC error using panic classifier if set.
+func (s *Service) errFromPanic(p any) error {
+	if s.panicClassifier != nil {
+		if code, msg, ok := s.panicClassifier(p); ok {
+			return status.Error(code, msg)
+		}
+	}
+
 	var errText string
 	switch e := p.(type) {
 	case error:
@@ -25,6 +35,20 @@ func errFromPanic(p any) error {
 	return status.Errorf(codes.Internal, "recover: %s", errText)
 }
 
+// logRecoveredPanic logs recovered panic with trace ID and stack trace.
+// stack is taken by the caller, so the trace doesn't include this function.
+func (s *Service) logRecoveredPanic(ctx context.Context, msg string, p any, stack []byte) {
+	const maxAttrs = 6
+	attrs := make([]any, 0, maxAttrs)
+	attrs = append(attrs, "panic", p)
+	if traceID, traceOK := s.traceIDFromContext(ctx); traceOK {
+		attrs = append(attrs, "trace_id", traceID)
+	}
+	attrs = append(attrs, "stack_trace", string(stack))
+
+	s.logger.Error(ctx, msg, attrs...)
+}
+
 func (s *Service) logPanic(ctx context.Context, p any) {
 	if s.panicLogger != nil {
 		s.panicLogger(ctx, p)
@@ -32,23 +56,15 @@ func (s *Service) logPanic(ctx context.Context, p any) {
 }
 
 // gRPC interceptor for panic recovery.
-func (s *Service) recoverUnaryGRPC(ctx context.Context, req any, _ *grpc.UnaryServerInfo,
+func (s *Service) recoverUnaryGRPC(ctx context.Context, req any, info *grpc.UnaryServerInfo,
 	handler grpc.UnaryHandler,
 ) (_ any, err error) {
 	defer func() {
 		if p := recover(); p != nil {
-			traceID, traceOK := s.traceIDFromContext(ctx)
-
-			attrs := make([]any, 0, 2) //nolint:mnd // ok
-			attrs = append(attrs, "panic", p)
-			if traceOK {
-				attrs = append(attrs, "trace_id", traceID)
-			}
-			attrs = append(attrs, "stack_trace", string(debug.Stack()))
+			s.logRecoveredPanic(ctx, "recovered from grpc panic", p, debug.Stack())
 
-			s.logger.Error(ctx, "recovered from grpc panic", attrs...)
-
-			err = errFromPanic(p)
+			err = s.errFromPanic(p)
+			s.incPanicsRecovered(info.FullMethod)
 			s.logPanic(ctx, p)
 		}
 	}()
@@ -56,22 +72,15 @@ func (s *Service) recoverUnaryGRPC(ctx context.Context, req any, _ *grpc.UnarySe
 }
 
 // gRPC interceptor for panic recovery.
-func (s *Service) recoverStreamGRPC(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo,
+func (s *Service) recoverStreamGRPC(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
 	handler grpc.StreamHandler,
 ) (err error) {
 	defer func() {
 		if p := recover(); p != nil {
-			traceID, traceOK := s.traceIDFromContext(ss.Context())
-
-			attrs := make([]any, 0, 2) //nolint:mnd // ok
-			attrs = append(attrs, "panic", p)
-			if traceOK {
-				attrs = append(attrs, "trace_id", traceID)
-			}
-			attrs = append(attrs, "stack_trace", string(debug.Stack()))
-			s.logger.Error(ss.Context(), "recovered from grpc panic", attrs...)
+			s.logRecoveredPanic(ss.Context(), "recovered from grpc panic", p, debug.Stack())
 
-			err = errFromPanic(p)
+			err = s.errFromPanic(p)
+			s.incPanicsRecovered(info.FullMethod)
 			s.logPanic(ss.Context(), p)
 		}
 	}()
@@ -83,18 +92,10 @@ func (s *Service) recoverHTTP(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if p := recover(); p != nil {
-				traceID, traceOK := s.traceIDFromContext(r.Context())
+				s.logRecoveredPanic(r.Context(), "recovered from http panic", p, debug.Stack())
 
-				attrs := make([]any, 0, 2) //nolint:mnd // ok
-				attrs = append(attrs, "panic", p)
-				if traceOK {
-					attrs = append(attrs, "trace_id", traceID)
-				}
-				attrs = append(attrs, "stack_trace", string(debug.Stack()))
-				s.logger.Error(r.Context(), "recovered from http panic", attrs...)
-
-				err := errFromPanic(p)
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				err := s.errFromPanic(p)
+				http.Error(w, err.Error(), runtime.HTTPStatusFromCode(status.Code(err)))
 
 				s.logPanic(r.Context(), p)
 			}
@@ -103,3 +104,81 @@ func (s *Service) recoverHTTP(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// recoverStreamMessageGRPC wraps stream so that panic during receiving of a single message
+// returns an error for that call instead of crashing the whole stream.
+func (s *Service) recoverStreamMessageGRPC(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	return handler(srv, &recoverServerStream{ServerStream: ss, s: s, method: info.FullMethod})
+}
+
+// recoverServerStream recovers from panics in RecvMsg.
+type recoverServerStream struct {
+	grpc.ServerStream
+	s      *Service
+	method string
+}
+
+// RecvMsg receives a message, converting panic into an error.
+func (r *recoverServerStream) RecvMsg(m any) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			ctx := r.Context()
+
+			r.s.logRecoveredPanic(ctx, "recovered from grpc stream message panic", p, debug.Stack())
+
+			err = r.s.errFromPanic(p)
+			r.s.incPanicsRecovered(r.method)
+			r.s.logPanic(ctx, p)
+		}
+	}()
+
+	return r.ServerStream.RecvMsg(m)
+}
+
+// logGoroutinePanic logs panic recovered by Go without service in context.
+func logGoroutinePanic(ctx context.Context, p any) {
+	const msg = "recovered from goroutine panic"
+	stack := string(debug.Stack())
+
+	if ctxlog.InContext(ctx) {
+		ctxlog.Error(ctx, msg, "panic", p, "stack_trace", stack)
+		return
+	}
+
+	slog.Default().ErrorContext(ctx, msg, "panic", p, "stack_trace", stack)
+}
+
+type serviceKey struct{}
+
+// serviceToContext puts service into request context for use in Go.
+func (s *Service) serviceToContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, serviceKey{}, s)
+}
+
+// Go runs f in a new goroutine with panic recovery.
+// If ctx is derived from the request context, the panic is logged by the logger and panic logger of the service
+// processing the request. Otherwise it is logged by the ctxlog logger of ctx or, if there is none,
+// by the default slog logger.
+// Use it in handlers instead of the go statement to avoid crashing the process.
+func Go(ctx context.Context, f func()) {
+	s, _ := ctx.Value(serviceKey{}).(*Service)
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				if s == nil {
+					logGoroutinePanic(ctx, p)
+					return
+				}
+
+				s.logRecoveredPanic(ctx, "recovered from goroutine panic", p, debug.Stack())
+
+				s.logPanic(ctx, p)
+			}
+		}()
+
+		f()
+	}()
+}
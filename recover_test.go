@@ -0,0 +1,182 @@
+package grpcsrv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// syncBuffer bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestGoLogsPanicWithoutService(t *testing.T) {
+	var out syncBuffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&out, nil)))
+	defer slog.SetDefault(prev)
+
+	done := make(chan struct{})
+	Go(context.Background(), func() {
+		defer close(done)
+		panic("boom")
+	})
+	<-done
+
+	// the panic is logged after f returns
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(out.String(), "recovered from goroutine panic") {
+		if time.Now().After(deadline) {
+			t.Fatalf("panic is not logged, output: %q", out.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if !strings.Contains(out.String(), "boom") {
+		t.Fatalf("panic value is not logged, output: %q", out.String())
+	}
+}
+
+func TestRecoverUnaryGRPC(t *testing.T) {
+	s := New(context.Background(), nil)
+
+	var logged any
+	s.panicLogger = func(_ context.Context, p any) { logged = p }
+
+	_, err := s.recoverUnaryGRPC(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"},
+		func(context.Context, any) (any, error) {
+			panic("boom")
+		})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("expected Internal, got %v", err)
+	}
+	if logged != "boom" {
+		t.Fatalf("expected panic to be passed to panic logger, got %v", logged)
+	}
+}
+
+// The no-panic path must not allocate: attributes for logging are built only after recover.
+func TestRecoverUnaryGRPCAllocs(t *testing.T) {
+	s := New(context.Background(), nil)
+	ctx := context.Background()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(context.Context, any) (any, error) { return nil, nil }
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = s.recoverUnaryGRPC(ctx, nil, info, handler)
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %v", allocs)
+	}
+}
+
+func BenchmarkRecoverUnaryGRPC(b *testing.B) {
+	s := New(context.Background(), nil)
+	ctx := context.Background()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(context.Context, any) (any, error) { return nil, nil }
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		_, _ = s.recoverUnaryGRPC(ctx, nil, info, handler)
+	}
+}
+
+func TestRecoverStreamMessageGRPC(t *testing.T) {
+	s := New(context.Background(), nil, WithStreamMessageRecover(),
+		WithPanicClassifier(func(p any) (codes.Code, string, bool) {
+			if p == "bad message" {
+				return codes.InvalidArgument, "bad message", true
+			}
+			return codes.OK, "", false
+		}))
+
+	calls := 0
+	ss := &testServerStream{recv: func(any) error {
+		calls++
+		if calls == 1 {
+			panic("bad message")
+		}
+		return nil
+	}}
+
+	handler := func(_ any, stream grpc.ServerStream) error {
+		// the handler continues the stream after the failed message
+		if err := stream.RecvMsg(nil); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("expected InvalidArgument for panic in RecvMsg, got %v", err)
+		}
+		return stream.RecvMsg(nil)
+	}
+
+	err := s.recoverStreamMessageGRPC(nil, ss, &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}, handler)
+	if err != nil {
+		t.Fatalf("stream must continue after recovered message panic: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 receive calls, got %d", calls)
+	}
+}
+
+type testValidationPanic struct {
+	field string
+}
+
+func TestPanicClassifier(t *testing.T) {
+	s := New(context.Background(), nil, WithPanicClassifier(func(p any) (codes.Code, string, bool) {
+		if v, ok := p.(testValidationPanic); ok {
+			return codes.InvalidArgument, "invalid " + v.field, true
+		}
+		return codes.OK, "", false
+	}))
+	s.panicLogger = func(context.Context, any) {}
+
+	tests := []struct {
+		name  string
+		panic any
+		code  codes.Code
+		msg   string
+	}{
+		{name: "classified", panic: testValidationPanic{field: "name"}, code: codes.InvalidArgument, msg: "invalid name"},
+		{name: "not classified", panic: "boom", code: codes.Internal, msg: "recover: boom"},
+		{name: "error", panic: errors.New("failed"), code: codes.Internal, msg: "recover: failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.recoverUnaryGRPC(context.Background(), nil,
+				&grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"},
+				func(context.Context, any) (any, error) {
+					panic(tt.panic)
+				})
+
+			st := status.Convert(err)
+			if st.Code() != tt.code || st.Message() != tt.msg {
+				t.Fatalf("expected %s %q, got %s %q", tt.code, tt.msg, st.Code(), st.Message())
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package grpcsrv
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc"
+)
+
+// checkDuplicateServices registers each initializer in a separate temporary gRPC server
+// and returns an error if the same service is registered by several initializers.
+// Otherwise grpc.Server.RegisterService panics during the real registration.
+func (s *Service) checkDuplicateServices() error {
+	registeredBy := make(map[string]int) // service name -> initializer index
+
+	for idx, i := range s.grpcInitializers {
+		services, err := s.dryRunRegistration(i)
+		if err != nil {
+			return fmt.Errorf("%s. grpc initializer #%d (%T): %w", s.name, idx, i, err)
+		}
+
+		for _, name := range services {
+			if prev, ok := registeredBy[name]; ok {
+				return fmt.Errorf("%s. grpc service %s is registered by initializers #%d (%T) and #%d (%T)",
+					s.name, name, prev, s.grpcInitializers[prev], idx, i)
+			}
+			registeredBy[name] = idx
+		}
+	}
+
+	return nil
+}
+
+// dryRunRegistration returns names of services registered by the initializer.
+func (s *Service) dryRunRegistration(i IGRPCInitializer) (services []string, err error) {
+	srv := grpc.NewServer()
+	defer srv.Stop()
+
+	defer func() {
+		if r := recover(); r != nil {
+			// the initializer registers the same service twice
+			err = fmt.Errorf("failed to register grpc services: %v", r)
+		}
+	}()
+
+	i.RegisterGRPCServer(srv)
+
+	for name := range srv.GetServiceInfo() {
+		services = append(services, name)
+	}
+
+	return services, nil
+}
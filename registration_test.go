@@ -0,0 +1,101 @@
+package grpcsrv
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	api "github.com/n-r-w/grpcsrv/example/protogen"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestStartRejectsInvalidInitializers(t *testing.T) {
+	var nilInitializer *healthInitializer
+
+	tests := []struct {
+		name         string
+		initializers []IGRPCInitializer
+		wantErr      string
+	}{
+		{name: "no initializers", wantErr: "no grpc initializers"},
+		{name: "nil initializer", initializers: []IGRPCInitializer{newHealthInitializer(), nil}, wantErr: "#1 is nil"},
+		{
+			name: "nil pointer", initializers: []IGRPCInitializer{nilInitializer},
+			wantErr: "#0 (*grpcsrv.healthInitializer) is nil",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(context.Background(), tt.initializers, WithEndpoint(Endpoint{GRPC: freeAddr(t)}))
+
+			err := s.Start(context.Background())
+			if err == nil {
+				_ = s.Stop(context.Background())
+				t.Fatal("expected Start error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// doubleHealthInitializer registers health service twice.
+type doubleHealthInitializer struct {
+	healthInitializer
+}
+
+func (i *doubleHealthInitializer) RegisterGRPCServer(s *grpc.Server) {
+	grpc_health_v1.RegisterHealthServer(s, i.health)
+	grpc_health_v1.RegisterHealthServer(s, i.health)
+}
+
+func TestStartRejectsDuplicateServices(t *testing.T) {
+	tests := []struct {
+		name         string
+		initializers []IGRPCInitializer
+		wantErr      string
+	}{
+		{
+			name: "several initializers",
+			initializers: []IGRPCInitializer{
+				newHealthInitializer(), &greeterInitializer{greeter: &testGreeter{}}, newHealthInitializer(),
+			},
+			wantErr: "grpc service grpc.health.v1.Health is registered by initializers #0",
+		},
+		{
+			name:         "same initializer",
+			initializers: []IGRPCInitializer{&doubleHealthInitializer{healthInitializer: *newHealthInitializer()}},
+			wantErr:      "failed to register grpc services",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(context.Background(), tt.initializers, WithEndpoint(Endpoint{GRPC: freeAddr(t)}))
+
+			err := s.Start(context.Background())
+			if err == nil {
+				_ = s.Stop(context.Background())
+				t.Fatal("expected Start error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDryRunRegistration(t *testing.T) {
+	s := New(context.Background(), nil)
+
+	services, err := s.dryRunRegistration(&greeterInitializer{greeter: &testGreeter{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || services[0] != api.Greeter_ServiceDesc.ServiceName {
+		t.Fatalf("expected greeter service, got %v", services)
+	}
+}
@@ -0,0 +1,38 @@
+package grpcsrv
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// originalRequestKey context key of the request as it was received by the server.
+type originalRequestKey struct{}
+
+// withOriginalRequest stores a deep copy of the request in the context, so handlers can't modify it.
+// Returns the copy as well. Non-protobuf requests are not stored.
+func withOriginalRequest(ctx context.Context, req any) (context.Context, proto.Message) {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return ctx, nil
+	}
+
+	original := proto.Clone(msg)
+
+	return context.WithValue(ctx, originalRequestKey{}, original), original
+}
+
+// CloneRequest returns a deep copy of the request as it was received by the server, if it is available
+// in ctx (methods with WithHandlerRetry), otherwise a deep copy of req. Modifications of the copy don't affect
+// the original request and vice versa. Non-protobuf requests are returned as is.
+func CloneRequest(ctx context.Context, req any) any {
+	if original, ok := ctx.Value(originalRequestKey{}).(proto.Message); ok {
+		return proto.Clone(original)
+	}
+
+	if msg, ok := req.(proto.Message); ok {
+		return proto.Clone(msg)
+	}
+
+	return req
+}
@@ -0,0 +1,71 @@
+package grpcsrv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCloneRequestIndependent(t *testing.T) {
+	req := wrapperspb.String("original")
+
+	clone, ok := CloneRequest(context.Background(), req).(*wrapperspb.StringValue)
+	if !ok {
+		t.Fatal("clone has unexpected type")
+	}
+
+	clone.Value = "modified"
+	if req.GetValue() != "original" {
+		t.Fatal("modification of the clone changed the original")
+	}
+
+	req.Value = "changed"
+	if clone.GetValue() != "modified" {
+		t.Fatal("modification of the original changed the clone")
+	}
+}
+
+func TestHandlerRetryIsolatesRequest(t *testing.T) {
+	const method = "/test.Retry/Call"
+	s := New(context.Background(), nil, WithHandlerRetry([]string{method},
+		RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+		func(err error) bool { return status.Code(err) == codes.Unavailable }))
+
+	req := wrapperspb.String("original")
+
+	var seen []string
+	handler := func(ctx context.Context, r any) (any, error) {
+		msg, _ := r.(*wrapperspb.StringValue)
+		seen = append(seen, msg.GetValue())
+
+		// the request as received by the server is available in the handler
+		if original, _ := CloneRequest(ctx, r).(*wrapperspb.StringValue); original.GetValue() != "original" {
+			t.Errorf("CloneRequest returned modified request %q", original.GetValue())
+		}
+
+		msg.Value = "consumed"
+		if len(seen) < 3 {
+			return nil, status.Error(codes.Unavailable, "try again")
+		}
+		return msg, nil
+	}
+
+	if _, err := s.handlerRetryUnaryInterceptor(context.Background(), req,
+		&grpc.UnaryServerInfo{FullMethod: method}, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, v := range seen {
+		if v != "original" {
+			t.Fatalf("attempt %d got modified request %q", i+1, v)
+		}
+	}
+	if req.GetValue() != "original" {
+		t.Fatalf("request seen by outer interceptors was modified: %q", req.GetValue())
+	}
+}
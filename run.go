@@ -0,0 +1,54 @@
+package grpcsrv
+
+import (
+	"context"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Drain prepares the service for shutdown: readiness endpoint starts reporting not ready,
+// so load balancers stop sending new requests. Waits for the drain delay set by WithDrainDelay
+// or until ctx is done.
+func (s *Service) Drain(ctx context.Context) {
+	s.draining.Store(true)
+	s.logger.Info(ctx, "draining", "delay", s.drainDelay)
+
+	if s.drainDelay <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(s.drainDelay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// RunUntilSignal starts the service, waits for SIGTERM/SIGINT or ctx cancellation,
+// then drains and stops the service gracefully.
+// Stop timeout is set by WithShutdownTimeout. Use it when running the service without bootstrap.
+func (s *Service) RunUntilSignal(ctx context.Context) error {
+	if err := s.Start(ctx); err != nil {
+		return err
+	}
+
+	signalCtx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
+	<-signalCtx.Done()
+	stop()
+
+	s.logger.Info(ctx, "shutdown signal received")
+
+	stopCtx := context.WithoutCancel(ctx)
+	if s.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		stopCtx, cancel = context.WithTimeout(stopCtx, s.shutdownTimeout)
+		defer cancel()
+	}
+
+	s.Drain(stopCtx)
+
+	return s.Stop(stopCtx)
+}
@@ -0,0 +1,129 @@
+package grpcsrv
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+)
+
+// serveHealth serves GET request for path by health check endpoints registered on a new mux.
+func serveHealth(t *testing.T, s *Service, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	mux := runtime.NewServeMux()
+	if err := s.registerHealthCheckEndpoints(context.Background(), mux); err != nil {
+		t.Fatalf("failed to register health check endpoints: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func healthStatus(t *testing.T, s *Service, path string) int {
+	t.Helper()
+	return serveHealth(t, s, path).Code
+}
+
+func TestDrain(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	s := New(context.Background(), nil,
+		WithHealthCheck(&testHealther{ready: true}, "/live", "/ready"), WithDrainDelay(delay))
+
+	if code := healthStatus(t, s, "/ready"); code != http.StatusOK {
+		t.Fatalf("expected ready before drain, got %d", code)
+	}
+
+	start := time.Now()
+	s.Drain(context.Background())
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Fatalf("drain must wait for delay %s, waited %s", delay, elapsed)
+	}
+
+	if code := healthStatus(t, s, "/ready"); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected not ready while draining, got %d", code)
+	}
+	if code := healthStatus(t, s, "/live"); code != http.StatusOK {
+		t.Fatalf("liveness must not be affected by drain, got %d", code)
+	}
+}
+
+func TestDrainContextDone(t *testing.T) {
+	s := New(context.Background(), nil, WithDrainDelay(time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.Drain(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("drain must stop waiting when context is done")
+	}
+}
+
+func TestRunUntilSignal(t *testing.T) {
+	grpcAddr := freeAddr(t)
+	s := New(context.Background(), []IGRPCInitializer{newHealthInitializer()},
+		WithEndpoint(Endpoint{GRPC: grpcAddr}), WithShutdownTimeout(5*time.Second),
+		WithHealthCheck(&testHealther{ready: true}, "/live", "/ready"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.RunUntilSignal(ctx)
+	}()
+
+	// wait for the server to start
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", grpcAddr)
+		if err == nil {
+			_ = conn.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("service is not started: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("expected graceful stop, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("service is not stopped after context cancellation")
+	}
+
+	if code := healthStatus(t, s, "/ready"); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected not ready after shutdown, got %d", code)
+	}
+}
+
+func TestRunUntilSignalStartError(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	s := New(context.Background(), []IGRPCInitializer{newHealthInitializer()},
+		WithEndpoint(Endpoint{GRPC: busy.Addr().String()}))
+	if err = s.RunUntilSignal(context.Background()); err == nil {
+		t.Fatal("expected start error")
+	}
+}
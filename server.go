@@ -3,18 +3,32 @@ package grpcsrv
 
 import (
 	"context"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	grpc_runtime "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/moznion/go-optional"
 	"github.com/rs/cors"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/proto"
 
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/n-r-w/bootstrap"
@@ -26,27 +40,102 @@ type Service struct {
 	name                  string
 	logger                ctxlog.ILogger
 	httpReadHeaderTimeout time.Duration
+	httpMaxHeaderBytes    int
+	httpMaxConnections    int
+	httpConcurrencyLimit  int
+	httpPathPrefix        string
+	httpGzipMinSize       int // -1 if disabled
+	grpcMaxConnections    int
 	grpcInitializers      []IGRPCInitializer
 	grpcOptions           []grpc.ServerOption
 	endpoint              Endpoint
 
+	// handler of calls to services not registered in gRPC server
+	unknownServiceHandler grpc.StreamHandler
+
+	perPeerConcurrencyLimit int
+	peerLimiter             *peerLimiter
+	apiKeyFromCtx           func(ctx context.Context) string
+	apiKeyRateLimiter       *keyRateLimiter
+
+	tlsCertFile       string
+	tlsKeyFile        string
+	tlsReloadInterval time.Duration
+	certReloader      *certReloader
+	tlsReloadStop     chan struct{}
+	tlsClientCAFile   string
+	tlsClientCAs      *x509.CertPool
+	// methods that require verified client certificate
+	clientCertMethods map[string]struct{}
+
 	healthCheckHandler   IHealther
 	livenessHandlerPath  string
 	readinessHandlerPath string
+	healthContentType    string
+	healthPaths          []HealthPath
+	// debouncing of readiness transitions
+	readinessFailureGrace  time.Duration
+	readinessRecoveryGrace time.Duration
 	// list of keys whose values will be replaced with "sanitized" in logs.
 	sanitizeKeys []string
+	// lowercased sanitizeKeys for fast search
+	sanitizeKeysLower [][]byte
+	// sanitizing is disabled by WithoutSanitization
+	sanitizeDisabled bool
+
+	recoverEnabled              bool
+	streamMessageRecoverEnabled bool
+
+	// send traceID in response header in addition to the trailer
+	traceIDInHeader bool
+	// add server time to response metadata
+	serverTimeHeader bool
+	// log requests without deadline
+	warnOnMissingDeadline bool
+
+	// allowed trailers. If nil, trailers are not filtered.
+	trailerAllowlist map[string]struct{}
+
+	// audit logging
+	auditSink          IAuditSink
+	auditMethods       map[string]struct{}
+	auditPrincipalFunc func(ctx context.Context) string
+
+	// header/metadata key for correlation ID. Empty if disabled.
+	correlationIDHeader string
+
+	// metadata prefix for feature flags. Empty if disabled.
+	featureFlagPrefix string
+	// accepted feature flag names. Nil if all flags are accepted.
+	featureFlagAllowed map[string]struct{}
+
+	// methods for which field masks are validated
+	fieldMaskMethods map[string]struct{}
+
+	// validation of requests with Validate methods
+	validationEnabled bool
 
-	recoverEnabled bool
+	// custom codecs: name -> codec
+	codecs map[string]encoding.Codec
 
 	pprofEndpoint string
 
-	httpDialOptions         []grpc.DialOption
-	httpMarshallers         map[string]grpc_runtime.Marshaler // content-type -> marshaler
-	httpHeadersFromMetadata []string
-	corsOptions             optional.Option[cors.Options]
+	httpDialOptions          []grpc.DialOption
+	httpDialBufferOptions    []grpc.DialOption
+	httpMarshallers          map[string]grpc_runtime.Marshaler // content-type -> marshaler
+	httpStrictJSON           bool
+	httpCaseInsensitiveEnums bool
+	httpHeadersFromMetadata  []string
+	httpStatusMapping        map[codes.Code]int // gRPC code -> HTTP status
+	corsOptions              optional.Option[cors.Options]
+
+	gatewayResponseModifiers []GatewayResponseModifier
+	gatewayResponseRewriter  grpc_runtime.ForwardResponseRewriter
 
 	wg          sync.WaitGroup
+	httpMu      sync.Mutex // guards httpServer during restart
 	httpServer  *http.Server
+	httpHandler http.Handler
 	pprofServer *http.Server
 
 	// used for serving prometheus metrics (if enabled)
@@ -55,24 +144,126 @@ type Service struct {
 
 	// function for panic logging (logging only, not recovery)
 	panicLogger func(ctx context.Context, p any)
-	// function for enriching context. Called before request processing.
-	ctxUnaryModifier  CtxUnaryModifier
-	ctxStreamModifier CtxStreamModifier
-	ctxHTTPModifier   CtxHTTPModifier
+	// function for converting panics to gRPC codes
+	panicClassifier func(p any) (codes.Code, string, bool)
+	// functions for enriching context. Called in order before request processing.
+	ctxUnaryModifiers  []CtxUnaryModifier
+	ctxStreamModifiers []CtxStreamModifier
+	ctxHTTPModifiers   []CtxHTTPModifier
+	// tracer provider. If nil, the global one is used.
+	tracerProvider trace.TracerProvider
+	// propagator for trace context. If nil, the global one is used.
+	propagator propagation.TextMapPropagator
+	// baggage members that will be added to ctxlog logger fields
+	baggageLogKeys []string
+	// static fields that will be added to ctxlog logger fields
+	loggerFields []any
 	// Function for registering additional http endpoints
 	registerHTTPEndpoints RegisterHTTPEndpoints
 
+	gatewayConnMu   sync.Mutex // guards grpcGatewayConn, which is created lazily with WithLazyGateway
 	grpcGatewayConn *grpc.ClientConn
 	grpcServer      *grpc.Server
+	// defer gateway initialization until the first HTTP request
+	lazyGateway bool
+	// do not attach OpenTelemetry stats handlers and middlewares
+	withoutOTel bool
+	// attributes added to server spans of all requests
+	spanAttributes []attribute.KeyValue
+	// create grpc_data span for all unary requests, not only for debug ones
+	alwaysCreateServerSpan bool
+
+	// server-side retries of handlers
+	handlerRetryMethods     map[string]struct{}
+	handlerRetryPolicy      RetryPolicy
+	handlerRetryIsRetriable func(error) bool
+
+	// selects marshaller for content type, consulted for gatewayMatcherContentTypes
+	gatewayMarshalerMatcher    func(contentType string) (grpc_runtime.Marshaler, bool)
+	gatewayMatcherContentTypes []string
+	// content types of all registered gateway marshallers (lower case)
+	httpContentTypes map[string]struct{}
+
+	// connect the gateway to gRPC server via in-memory listener instead of TCP
+	inprocessGateway  bool
+	inprocessListener *pipeListener
+
+	// order of stopping servers
+	stopSequence []ServerKind
+
+	// full names of server streaming methods returned by HTTP gateway as JSON arrays
+	streamJSONArrayMethods map[string]struct{}
+
+	// set gRPC status headers in HTTP gateway responses
+	grpcStatusHeader bool
+
+	// log HTTP gateway middlewares order and time spent in each of them
+	httpChainInspector bool
+	httpChainTiming    bool
+
+	// limit of incoming metadata size
+	maxMetadataSize int
+
+	// JWT verification key and claims of bearer token
+	claimsKeyFunc jwt.Keyfunc
+	claimsFactory func() jwt.Claims
+	// methods requiring bearer token, all except claimsSkipMethods if empty
+	claimsMethods     map[string]struct{}
+	claimsSkipMethods []string
+
+	// serve unary calls of Connect protocol via HTTP gateway
+	connectProtocol bool
+
+	// handler of requests that don't match gateway routes
+	httpRoutingErrorHandler grpc_runtime.RoutingErrorHandlerFunc
+
+	// HTTP endpoint reporting status of gRPC health service
+	gatewayHealthPath    string
+	gatewayHealthService string
+
+	// maps full method names to "method" label of metrics
+	metricLabelMapper func(fullMethod string) string
+
+	// messages per second for server streams
+	streamSendRateLimits map[string]float64
+
+	// heartbeats for idle server streams
+	heartbeatInterval   time.Duration
+	heartbeatMsgFactory func() proto.Message
+	heartbeatMethods    map[string]struct{}
+	heartbeatCompat     sync.Map // full method -> whether heartbeat message matches the response type
+
+	// limit of messages of server stream not yet accepted by transport
+	maxInFlightWrites int
+
+	// configuration error detected in New, returned by Start
+	initErr error
+
+	// number of gRPC requests currently being processed
+	inFlight atomic.Int64
+
+	// readiness endpoint reports not ready while draining
+	draining        atomic.Bool
+	drainDelay      time.Duration
+	shutdownTimeout time.Duration
+
+	// initialization before listeners start and in background after them
+	initFunc        func(ctx context.Context) error
+	asyncInitFunc   func(ctx context.Context) error
+	asyncInitCancel context.CancelFunc
+	// readiness endpoint reports not ready until async init function completes
+	initializing atomic.Bool
 }
 
 var _ bootstrap.IService = (*Service)(nil)
 
 // New creates a new service instance.
+// Invalid initializers (empty list or nil entries) are reported by Start.
 func New(ctx context.Context, grpcSevices []IGRPCInitializer, opt ...Option) *Service {
 	s := &Service{
 		name:             "grpc",
 		grpcInitializers: grpcSevices,
+		httpGzipMinSize:  -1,
 		endpoint: Endpoint{
 			GRPC: ":50051",
 			HTTP: ":50052",
@@ -87,41 +278,53 @@ func New(ctx context.Context, grpcSevices []IGRPCInitializer, opt ...Option) *Se
 		s.logger = ctxlog.NewStubWrapper()
 	}
 
-	if s.ctxUnaryModifier == nil {
-		s.ctxUnaryModifier = func(
-			ctx context.Context, _ any, _ *grpc.UnaryServerInfo, _ grpc.UnaryHandler, _, _ string,
-		) context.Context {
-			return ctx
-		}
-	}
-
-	if s.ctxStreamModifier == nil {
-		s.ctxStreamModifier = func(
-			ctx context.Context, _ *grpc.StreamServerInfo, _ grpc.StreamHandler, _, _ string,
-		) context.Context {
-			return ctx
+	if s.registerHTTPEndpoints == nil {
+		s.registerHTTPEndpoints = func(ctx context.Context, _ *grpc_runtime.ServeMux) error {
+			return nil
 		}
 	}
 
-	if s.ctxHTTPModifier == nil {
-		s.ctxHTTPModifier = func(ctx context.Context, _ *http.Request, _ string) context.Context {
-			return ctx
-		}
+	if s.claimsSkipMethods == nil {
+		s.claimsSkipMethods = defaultClaimsSkipMethods
 	}
 
-	if s.registerHTTPEndpoints == nil {
-		s.registerHTTPEndpoints = func(ctx context.Context, _ *grpc_runtime.ServeMux) error {
-			return nil
-		}
+	if s.healthCheckHandler != nil && (s.readinessFailureGrace > 0 || s.readinessRecoveryGrace > 0) {
+		s.healthCheckHandler = newReadinessDebouncer(
+			s.healthCheckHandler, s.readinessFailureGrace, s.readinessRecoveryGrace)
 	}
 
-	if len(s.sanitizeKeys) == 0 {
+	if len(s.sanitizeKeys) == 0 && !s.sanitizeDisabled {
 		s.sanitizeKeys = []string{"password", "token", "refreshToken", "accessToken"}
 	}
+	for _, k := range s.sanitizeKeys {
+		s.sanitizeKeysLower = append(s.sanitizeKeysLower, []byte(strings.ToLower(k)))
+	}
+
+	s.initErr = s.validateInitializers()
 
 	return s
 }
 
+// validateInitializers checks that there is at least one initializer and all of them are not nil.
+func (s *Service) validateInitializers() error {
+	if len(s.grpcInitializers) == 0 {
+		return fmt.Errorf("%s. no grpc initializers provided", s.name)
+	}
+
+	for idx, i := range s.grpcInitializers {
+		if i == nil {
+			return fmt.Errorf("%s. grpc initializer #%d is nil", s.name, idx)
+		}
+
+		// interface holding a nil pointer
+		if v := reflect.ValueOf(i); v.Kind() == reflect.Pointer && v.IsNil() {
+			return fmt.Errorf("%s. grpc initializer #%d (%T) is nil", s.name, idx, i)
+		}
+	}
+
+	return nil
+}
+
 // Info returns information about the service.
 // Implements bootstrap.IService interface.
 func (s *Service) Info() bootstrap.Info {
@@ -134,27 +337,50 @@ func (s *Service) Info() bootstrap.Info {
 // Start starts the service.
 // Implements bootstrap.IService interface.
 func (s *Service) Start(ctx context.Context) error {
+	if s.initErr != nil {
+		return s.initErr
+	}
+
+	// init function is bounded by startup timeout
+	if err := s.runInitFunc(ctx); err != nil {
+		return err
+	}
+
 	ctx = context.WithoutCancel(ctx) // ignore startup timeout since context will go to goroutine
+	s.initializing.Store(s.asyncInitFunc != nil)
 
-	httpRequired := s.prepare(ctx)
+	if err := s.prepareTLS(); err != nil {
+		return err
+	}
+
+	httpRequired, err := s.prepare(ctx)
+	if err != nil {
+		return err
+	}
 
 	if err := s.startGRPCServer(ctx); err != nil {
 		return err
 	}
 
+	s.startInprocessListener(ctx)
+	s.startTLSReload(ctx)
+
 	// start pprof server if enabled
 	if err := s.startPProfServer(ctx); err != nil {
+		s.abortStart()
 		return err
 	}
 
 	// start metrics server if enabled
 	if err := s.startMetricsServer(ctx); err != nil {
+		s.abortStart()
 		return err
 	}
 
 	// start HTTP gateway
 	if httpRequired {
 		if err := s.startHTTPGateway(ctx); err != nil {
+			s.abortStart()
 			return err
 		}
 	}
@@ -163,79 +389,263 @@ func (s *Service) Start(ctx context.Context) error {
 		s.logger.Info(ctx, "HTTP server is disabled")
 	}
 
+	s.startAsyncInitFunc(ctx)
+	s.logStartupSummary(ctx, httpRequired)
+
 	return nil
 }
 
+// logStartupSummary logs effective configuration of the service.
+func (s *Service) logStartupSummary(ctx context.Context, httpRequired bool) {
+	httpEndpoint := ""
+	if httpRequired {
+		httpEndpoint = s.endpoint.HTTP
+	}
+
+	s.logger.Info(ctx, "service started",
+		"grpc", s.endpoint.GRPC,
+		"http", httpEndpoint,
+		"metrics", s.metricsEndpoint,
+		"pprof", s.pprofEndpoint,
+		"recover", s.recoverEnabled,
+		"tls", s.certReloader != nil,
+		"reflection", true,
+		"health_check", s.healthCheckHandler != nil,
+		"sanitize_keys", len(s.sanitizeKeys),
+	)
+}
+
+// abortStart immediately stops servers that have already been started when Start fails.
+func (s *Service) abortStart() {
+	if s.httpServer != nil {
+		_ = s.httpServer.Close()
+	}
+	if conn := s.getGatewayConn(); conn != nil {
+		_ = conn.Close()
+	}
+	if s.pprofServer != nil {
+		_ = s.pprofServer.Close()
+	}
+	if s.httpMetricsServer != nil {
+		_ = s.httpMetricsServer.Close()
+	}
+
+	s.grpcServer.Stop()
+	s.stopTLSReload()
+}
+
 // Stop stops the service. Stop timeout is set through context.
+// Returns joined errors of stopping servers and closing connections; all servers are stopped anyway.
 // Implements bootstrap.IService interface.
 func (s *Service) Stop(ctx context.Context) error {
-	var wg sync.WaitGroup
+	drainStart := time.Now()
+	s.logger.Info(ctx, "shutdown started", "in_flight", s.inFlight.Load())
+
+	stoppers := map[ServerKind]func(context.Context) error{
+		ServerKindHTTP:    s.stopHTTP,
+		ServerKindPprof:   s.stopPprof,
+		ServerKindMetrics: s.stopMetrics,
+		ServerKindGRPC:    s.stopGRPC,
+	}
 
-	if s.httpServer != nil {
-		wg.Add(1)
+	var (
+		errMu   sync.Mutex
+		stopErr error
+	)
+	addErr := func(err error) {
+		errMu.Lock()
+		stopErr = errors.Join(stopErr, err)
+		errMu.Unlock()
+	}
 
+	// servers from the stop sequence are stopped one by one
+	for _, kind := range s.stopSequence {
+		if stop, ok := stoppers[kind]; ok {
+			addErr(stop(ctx))
+			delete(stoppers, kind)
+		}
+	}
+
+	// the rest of HTTP servers are stopped concurrently, gRPC server is stopped last,
+	// so in-flight gateway requests can complete
+	stopGRPC, grpcPending := stoppers[ServerKindGRPC]
+	delete(stoppers, ServerKindGRPC)
+
+	var wg sync.WaitGroup
+	for _, stop := range stoppers {
+		wg.Add(1)
 		go func() {
 			defer wg.Done()
-
-			s.logger.Info(ctx, "gracefully stopping http")
-			err := s.httpServer.Shutdown(ctx)
-			if err != nil {
-				s.logger.Error(ctx, "failed to stop http server", "error", err)
-			}
-			s.logger.Info(ctx, "http stopped gracefully")
-			err = s.grpcGatewayConn.Close()
-			if err != nil {
-				s.logger.Error(ctx, "failed to close grpc gateway connection", "error", err)
-			}
+			addErr(stop(ctx))
 		}()
 	}
+	wg.Wait()
 
-	if s.pprofServer != nil {
-		wg.Add(1)
+	if grpcPending {
+		addErr(stopGRPC(ctx))
+	}
 
-		go func() {
-			defer wg.Done()
+	s.stopTLSReload()
+	s.stopAsyncInitFunc()
+	s.wg.Wait()
 
-			s.logger.Info(ctx, "gracefully stopping pprof server")
-			err := s.pprofServer.Shutdown(ctx)
-			if err != nil {
-				s.logger.Error(ctx, "failed to stop pprof server", "error", err)
-			}
-			s.logger.Info(ctx, "pprof server stopped gracefully")
-		}()
+	if stopErr != nil {
+		s.logger.Error(ctx, "shutdown completed with errors", "drain_duration", time.Since(drainStart), "error", stopErr)
+		return fmt.Errorf("%s. failed to stop: %w", s.name, stopErr)
 	}
 
-	if s.httpMetricsServer != nil {
-		wg.Add(1)
+	s.logger.Info(ctx, "shutdown completed", "drain_duration", time.Since(drainStart))
 
-		go func() {
-			defer wg.Done()
+	return nil
+}
 
-			s.logger.Info(ctx, "gracefully stopping metrics server")
-			err := s.httpMetricsServer.Shutdown(ctx)
-			if err != nil {
-				s.logger.Error(ctx, "failed to stop metrics server", "error", err)
-			}
-			s.logger.Info(ctx, "metrics server stopped gracefully")
-		}()
+// stopHTTP gracefully stops HTTP gateway server and closes its connection to gRPC server.
+func (s *Service) stopHTTP(ctx context.Context) error {
+	s.httpMu.Lock()
+	httpServer := s.httpServer
+	s.httpMu.Unlock()
+
+	if httpServer == nil {
+		return nil
 	}
 
-	wg.Wait()
+	var res error
+
+	s.logger.Info(ctx, "gracefully stopping http")
+	err := httpServer.Shutdown(ctx)
+	if err != nil {
+		s.logger.Error(ctx, "failed to stop http server", "error", err)
+		res = fmt.Errorf("http server: %w", err)
+	}
+	s.logger.Info(ctx, "http stopped gracefully")
+	if conn := s.getGatewayConn(); conn != nil {
+		if err = conn.Close(); err != nil {
+			s.logger.Error(ctx, "failed to close grpc gateway connection", "error", err)
+			res = errors.Join(res, fmt.Errorf("grpc gateway connection: %w", err))
+		}
+	}
+
+	return res
+}
+
+// stopPprof gracefully stops pprof server.
+func (s *Service) stopPprof(ctx context.Context) error {
+	if s.pprofServer == nil {
+		return nil
+	}
+
+	s.logger.Info(ctx, "gracefully stopping pprof server")
+	err := s.pprofServer.Shutdown(ctx)
+	if err != nil {
+		s.logger.Error(ctx, "failed to stop pprof server", "error", err)
+		return fmt.Errorf("pprof server: %w", err)
+	}
+	s.logger.Info(ctx, "pprof server stopped gracefully")
+
+	return nil
+}
 
+// stopMetrics gracefully stops metrics server.
+func (s *Service) stopMetrics(ctx context.Context) error {
+	if s.httpMetricsServer == nil {
+		return nil
+	}
+
+	s.logger.Info(ctx, "gracefully stopping metrics server")
+	err := s.httpMetricsServer.Shutdown(ctx)
+	if err != nil {
+		s.logger.Error(ctx, "failed to stop metrics server", "error", err)
+		return fmt.Errorf("metrics server: %w", err)
+	}
+	s.logger.Info(ctx, "metrics server stopped gracefully")
+
+	return nil
+}
+
+// stopGRPC gracefully stops gRPC server.
+func (s *Service) stopGRPC(ctx context.Context) error {
 	s.logger.Info(ctx, "gracefully stopping grpc")
 	s.grpcServer.GracefulStop()
 	s.logger.Info(ctx, "grpc stopped gracefully")
 
-	s.wg.Wait()
-
 	return nil
 }
 
-func (s *Service) prepare(_ context.Context) (httpRequired bool) {
+func (s *Service) prepare(_ context.Context) (httpRequired bool, err error) {
+	if err = s.checkDuplicateServices(); err != nil {
+		return false, err
+	}
+
+	s.registerCodecs()
+
+	// callServerInterceptor and callServerStreamInterceptor must go first: they apply context modifiers,
+	// so all subsequent interceptors (including initializers' ones) and handlers see the enriched context.
 	unaryInterceptors := []grpc.UnaryServerInterceptor{
 		s.callServerInterceptor,
 		pprofUnaryInterceptor,
-		s.tracingDataServerInterceptor,
+	}
+
+	if !s.withoutOTel {
+		unaryInterceptors = append(unaryInterceptors, s.tracingDataServerInterceptor)
+	}
+
+	if s.metricsEndpoint != "" {
+		unaryInterceptors = append(unaryInterceptors, s.metricsUnaryInterceptor)
+	}
+
+	// trailers set by callServerInterceptor (outside of the filter) are not filtered
+	if s.trailerAllowlist != nil {
+		unaryInterceptors = append(unaryInterceptors, s.trailerFilterUnaryInterceptor)
+	}
+
+	if s.maxMetadataSize > 0 {
+		unaryInterceptors = append(unaryInterceptors, s.metadataSizeUnaryInterceptor)
+	}
+
+	if len(s.clientCertMethods) > 0 {
+		unaryInterceptors = append(unaryInterceptors, s.clientCertUnaryInterceptor)
+	}
+
+	if s.perPeerConcurrencyLimit > 0 {
+		s.peerLimiter = newPeerLimiter(s.perPeerConcurrencyLimit)
+		unaryInterceptors = append(unaryInterceptors, s.peerLimitUnaryInterceptor)
+	}
+
+	if s.apiKeyRateLimiter != nil {
+		unaryInterceptors = append(unaryInterceptors, s.apiKeyRateLimitUnaryInterceptor)
+	}
+
+	if s.claimsKeyFunc != nil {
+		unaryInterceptors = append(unaryInterceptors, s.claimsUnaryInterceptor)
+	}
+
+	if s.correlationIDHeader != "" {
+		unaryInterceptors = append(unaryInterceptors, s.correlationIDUnaryInterceptor)
+	}
+
+	if s.warnOnMissingDeadline {
+		unaryInterceptors = append(unaryInterceptors, s.deadlineWarnUnaryInterceptor)
+	}
+
+	if s.auditSink != nil && len(s.auditMethods) > 0 {
+		unaryInterceptors = append(unaryInterceptors, s.auditUnaryInterceptor)
+	}
+
+	if s.featureFlagPrefix != "" {
+		unaryInterceptors = append(unaryInterceptors, s.featureFlagsUnaryInterceptor)
+	}
+
+	if len(s.fieldMaskMethods) > 0 {
+		unaryInterceptors = append(unaryInterceptors, s.fieldMaskValidationInterceptor)
+	}
+
+	if s.validationEnabled {
+		unaryInterceptors = append(unaryInterceptors, s.validationUnaryInterceptor)
+	}
+
+	// panics converted to errors by recover interceptor can be retried as well
+	if len(s.handlerRetryMethods) > 0 {
+		unaryInterceptors = append(unaryInterceptors, s.handlerRetryUnaryInterceptor)
 	}
 
 	if s.recoverEnabled {
@@ -246,12 +656,78 @@ func (s *Service) prepare(_ context.Context) (httpRequired bool) {
 		s.callServerStreamInterceptor,
 		pprofStreamInterceptor,
 	}
+	if !s.withoutOTel {
+		streamInterceptors = append(streamInterceptors, s.spanAttributesStreamInterceptor)
+	}
+	if s.metricsEndpoint != "" {
+		streamInterceptors = append(streamInterceptors, s.metricsStreamInterceptor)
+	}
+	if s.trailerAllowlist != nil {
+		streamInterceptors = append(streamInterceptors, s.trailerFilterStreamInterceptor)
+	}
+	if s.maxMetadataSize > 0 {
+		streamInterceptors = append(streamInterceptors, s.metadataSizeStreamInterceptor)
+	}
+	if len(s.clientCertMethods) > 0 {
+		streamInterceptors = append(streamInterceptors, s.clientCertStreamInterceptor)
+	}
+	if s.peerLimiter != nil {
+		streamInterceptors = append(streamInterceptors, s.peerLimitStreamInterceptor)
+	}
+	if s.apiKeyRateLimiter != nil {
+		streamInterceptors = append(streamInterceptors, s.apiKeyRateLimitStreamInterceptor)
+	}
+	if s.claimsKeyFunc != nil {
+		streamInterceptors = append(streamInterceptors, s.claimsStreamInterceptor)
+	}
+	if s.correlationIDHeader != "" {
+		streamInterceptors = append(streamInterceptors, s.correlationIDStreamInterceptor)
+	}
+	if s.featureFlagPrefix != "" {
+		streamInterceptors = append(streamInterceptors, s.featureFlagsStreamInterceptor)
+	}
+	if s.warnOnMissingDeadline {
+		streamInterceptors = append(streamInterceptors, s.deadlineWarnStreamInterceptor)
+	}
+	if s.auditSink != nil && len(s.auditMethods) > 0 {
+		streamInterceptors = append(streamInterceptors, s.auditStreamInterceptor)
+	}
+	if s.validationEnabled {
+		streamInterceptors = append(streamInterceptors, s.validationStreamInterceptor)
+	}
 	if s.recoverEnabled {
 		streamInterceptors = append(streamInterceptors, s.recoverStreamGRPC)
 	}
+	if s.streamMessageRecoverEnabled {
+		streamInterceptors = append(streamInterceptors, s.recoverStreamMessageGRPC)
+	}
+	if len(s.streamSendRateLimits) > 0 {
+		streamInterceptors = append(streamInterceptors, s.streamSendRateLimitInterceptor)
+	}
+	if s.maxInFlightWrites > 0 {
+		streamInterceptors = append(streamInterceptors, s.backpressureStreamInterceptor)
+	}
+	if len(s.heartbeatMethods) > 0 {
+		streamInterceptors = append(streamInterceptors, s.heartbeatStreamInterceptor)
+	}
 
-	grpcOptions := s.grpcOptions
-	grpcOptions = append(grpcOptions, grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	// copy to avoid modifying s.grpcOptions backing array on subsequent starts
+	grpcOptions := slices.Clone(s.grpcOptions)
+	if !s.withoutOTel {
+		grpcOptions = append(grpcOptions,
+			grpc.StatsHandler(otelgrpc.NewServerHandler(
+				otelgrpc.WithTracerProvider(s.getTracerProvider()),
+				otelgrpc.WithPropagators(s.getPropagator()),
+			)))
+	}
+
+	if s.certReloader != nil {
+		grpcOptions = append(grpcOptions, grpc.Creds(credentials.NewTLS(s.getTLSConfig())))
+	}
+
+	if s.unknownServiceHandler != nil {
+		grpcOptions = append(grpcOptions, grpc.UnknownServiceHandler(s.unknownServiceHandler))
+	}
 
 	for _, i := range s.grpcInitializers {
 		opt := i.GetOptions()
@@ -274,7 +750,7 @@ func (s *Service) prepare(_ context.Context) (httpRequired bool) {
 		i.RegisterGRPCServer(s.grpcServer)
 	}
 
-	return s.endpoint.HTTP != ""
+	return s.endpoint.HTTP != "", nil
 }
 
 func (s *Service) startGRPCServer(ctx context.Context) error {
@@ -282,6 +758,7 @@ func (s *Service) startGRPCServer(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to listen: %w", err)
 	}
+	listener = newLimitListener(ctx, listener, s.grpcMaxConnections, s.logger, "grpc")
 
 	s.wg.Add(1)
 	go func() {
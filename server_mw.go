@@ -1,22 +1,28 @@
 package grpcsrv
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/rs/cors"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
@@ -32,10 +38,36 @@ const (
 	TraceDebugKey = "x-trace-debug"
 	// TraceDebugKeyValue value for traceDebugKey.
 	TraceDebugKeyValue = "1"
+
+	// ServerTimeKey key for server time (RFC3339) in response metadata.
+	ServerTimeKey = "x-server-time"
+
+	// RetryAfterKey key in response metadata that is converted to Retry-After HTTP header by the gateway.
+	RetryAfterKey = "retry-after"
+	// GRPCStatusCodeHeader HTTP gateway response header with numeric gRPC status code (see WithGRPCStatusHeader).
+	GRPCStatusCodeHeader = "Grpc-Status-Code"
+	// GRPCStatusMessageHeader HTTP gateway response header with gRPC status message (see WithGRPCStatusHeader).
+	GRPCStatusMessageHeader = "Grpc-Status-Message"
 )
 
+// getTracerProvider returns tracer provider set by WithTracerProvider or the global one.
+func (s *Service) getTracerProvider() trace.TracerProvider {
+	if s.tracerProvider != nil {
+		return s.tracerProvider
+	}
+
+	return otel.GetTracerProvider()
+}
+
+type traceIDKey struct{}
+
 // TraceIDFromContext returns traceID from context.
+// Uses traceID cached by traceIDToContext if available.
 func (s *Service) traceIDFromContext(ctx context.Context) (string, bool) {
+	if traceID, ok := ctx.Value(traceIDKey{}).(string); ok {
+		return traceID, true
+	}
+
 	span := trace.SpanFromContext(ctx).SpanContext()
 	if span.HasTraceID() {
 		return span.TraceID().String(), true
@@ -44,25 +76,50 @@ func (s *Service) traceIDFromContext(ctx context.Context) (string, bool) {
 	return "", false
 }
 
+// caches traceID in context to avoid repeated extraction from span.
+func traceIDToContext(ctx context.Context, traceID string, traceOK bool) context.Context {
+	if !traceOK {
+		return ctx
+	}
+
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
 // interceptor for incoming gRPC requests.
 func (s *Service) callServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
 	handler grpc.UnaryHandler,
 ) (resp any, err error) {
+	s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+
 	// add traceID to response metadata
 	traceID, traceOK := s.traceIDFromContext(ctx)
 	if traceOK {
 		header := metadata.Pairs(TraceIDKey, traceID)
 		_ = grpc.SetTrailer(ctx, header)
+		if s.traceIDInHeader {
+			_ = grpc.SetHeader(ctx, header)
+		}
 	}
 
 	// add additional data to context
-	ctx = s.ctxUnaryModifier(ctx, req, info, handler, extractRemoteAddr(ctx), traceID)
+	ctx = traceIDToContext(ctx, traceID, traceOK)
+	ctx = s.serviceToContext(ctx)
+	remoteAddr := extractRemoteAddr(ctx)
+	for _, modifier := range s.ctxUnaryModifiers {
+		ctx = modifier(ctx, req, info, handler, remoteAddr, traceID)
+	}
+	ctx = s.enrichLogger(ctx)
 
 	resp, err = handler(ctx, req)
 	if err != nil {
 		s.logger.Debug(ctx, "grpc server error", "error", err)
 	}
 
+	if s.serverTimeHeader {
+		_ = grpc.SetTrailer(ctx, serverTimeMetadata())
+	}
+
 	return resp, err
 }
 
@@ -70,6 +127,9 @@ func (s *Service) callServerInterceptor(ctx context.Context, req any, info *grpc
 func (s *Service) callServerStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
 	handler grpc.StreamHandler,
 ) error {
+	s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+
 	ctx := ss.Context()
 
 	wrapped := grpc_middleware.WrapServerStream(ss)
@@ -79,24 +139,72 @@ func (s *Service) callServerStreamInterceptor(srv any, ss grpc.ServerStream, inf
 	if traceOK {
 		header := metadata.Pairs(TraceIDKey, traceID)
 		wrapped.SetTrailer(header)
+		if s.traceIDInHeader {
+			_ = wrapped.SetHeader(header)
+		}
 	}
 
 	// add additional data to context
-	ctx = s.ctxStreamModifier(ctx, info, handler, extractRemoteAddr(ctx), traceID)
+	ctx = traceIDToContext(ctx, traceID, traceOK)
+	ctx = s.serviceToContext(ctx)
+	remoteAddr := extractRemoteAddr(ctx)
+	for _, modifier := range s.ctxStreamModifiers {
+		ctx = modifier(ctx, info, handler, remoteAddr, traceID)
+	}
+	ctx = s.enrichLogger(ctx)
 
+	// next interceptors and the handler get the enriched context via stream.Context()
 	wrapped.WrappedContext = ctx
 	err := handler(srv, wrapped)
 	if err != nil {
 		s.logger.Debug(ctx, "grpc server stream error", "error", err)
 	}
 
+	if s.serverTimeHeader {
+		wrapped.SetTrailer(serverTimeMetadata())
+	}
+
 	return err
 }
 
+// returns response metadata with current server time.
+func serverTimeMetadata() metadata.MD {
+	return metadata.Pairs(ServerTimeKey, time.Now().UTC().Format(time.RFC3339))
+}
+
+// warns if incoming request has no deadline.
+func (s *Service) checkDeadline(ctx context.Context, method string) {
+	if _, ok := ctx.Deadline(); ok {
+		return
+	}
+
+	s.logger.Warn(ctx, "grpc request without deadline", "method", method, "remote_addr", extractRemoteAddr(ctx))
+	s.incMissingDeadline(method)
+}
+
+// interceptor for warning on unary requests without deadline.
+func (s *Service) deadlineWarnUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	s.checkDeadline(ctx, info.FullMethod)
+	return handler(ctx, req)
+}
+
+// interceptor for warning on streams without deadline.
+func (s *Service) deadlineWarnStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	s.checkDeadline(ss.Context(), info.FullMethod)
+	return handler(srv, ss)
+}
+
 // creates span for gRPC request and adds request and response to it.
 func (s *Service) tracingDataServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
 	handler grpc.UnaryHandler,
 ) (any, error) {
+	// server span of otelgrpc stats handler
+	s.tagSpanMethod(trace.SpanFromContext(ctx), info.FullMethod)
+
 	// check for debug header requirement
 	needDebug := false
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
@@ -105,62 +213,156 @@ func (s *Service) tracingDataServerInterceptor(ctx context.Context, req any, inf
 		}
 	}
 
-	if !needDebug {
+	if !needDebug && !s.alwaysCreateServerSpan {
 		return handler(ctx, req)
 	}
 
 	var span trace.Span
-	ctx, span = otel.GetTracerProvider().Tracer("").Start(ctx, "grpc_data")
+	ctx, span = s.getTracerProvider().Tracer("").Start(ctx, "grpc_data")
 	defer span.End()
 
 	tagRemoteAddr(ctx, span)
+	s.tagSpanMethod(span, info.FullMethod)
 
-	var (
-		reqMessage protoreflect.ProtoMessage
-		ok         bool
-	)
-	if reqMessage, ok = req.(protoreflect.ProtoMessage); ok {
-		if reqBytes, err := protojson.Marshal(reqMessage); err == nil {
-			if len(reqBytes) < MaxSpanBytes {
-				span.SetAttributes(attribute.String("grpc_request", string(s.sanitizeBytes(reqBytes))))
-			}
+	// the request is added before the handler is called, so its modifications by the handler are not recorded
+	if needDebug {
+		if reqMessage, ok := req.(protoreflect.ProtoMessage); ok {
+			s.setSpanMessage(span, "grpc_request", reqMessage)
 		}
 	}
 
 	resp, rpcErr := handler(ctx, req)
 
-	if rpcErr == nil { //nolint:nestif // ok
-		if reqMessage, ok = resp.(protoreflect.ProtoMessage); ok {
-			if replyBytes, err := protojson.Marshal(reqMessage); err == nil {
-				if len(replyBytes) > MaxSpanBytes {
-					replyBytes = replyBytes[:MaxSpanBytes]
-				}
-				span.SetAttributes(attribute.String("grpc_response", string(s.sanitizeBytes(replyBytes))))
-			}
+	span.SetAttributes(attribute.String("rpc.grpc.status_code", status.Code(rpcErr).String()))
+	if rpcErr != nil {
+		span.SetStatus(otelcodes.Error, rpcErr.Error())
+	}
+
+	if !needDebug {
+		return resp, rpcErr
+	}
+
+	if rpcErr == nil {
+		if replyMessage, ok := resp.(protoreflect.ProtoMessage); ok {
+			s.setSpanMessage(span, "grpc_response", replyMessage)
 		}
+	} else {
+		// status with message and details
+		s.setSpanMessage(span, "grpc_error", status.Convert(rpcErr).Proto())
 	}
 
 	return resp, rpcErr
 }
 
+// payloadBufPool pool of buffers for marshalling span payloads.
+var payloadBufPool = sync.Pool{
+	New: func() any {
+		const initialSize = 4096
+		b := make([]byte, 0, initialSize)
+		return &b
+	},
+}
+
+// setSpanMessage marshals message to JSON using pooled buffer and adds it to span.
+func (s *Service) setSpanMessage(span trace.Span, key string, msg protoreflect.ProtoMessage) {
+	bufPtr, _ := payloadBufPool.Get().(*[]byte)
+	if bufPtr == nil {
+		bufPtr = new([]byte)
+	}
+
+	data, err := protojson.MarshalOptions{}.MarshalAppend((*bufPtr)[:0], msg)
+	if err == nil {
+		// payload is copied to span attribute, so the buffer can be reused
+		s.setSpanPayload(span, key, data)
+	}
+
+	// don't keep too large buffers in the pool
+	if cap(data) <= 2*MaxSpanBytes {
+		*bufPtr = data[:0]
+		payloadBufPool.Put(bufPtr)
+	}
+}
+
+// setSpanPayload adds sanitized JSON payload to span.
+// Payloads exceeding MaxSpanBytes are not added, only marked as truncated with their size.
+func (s *Service) setSpanPayload(span trace.Span, key string, data []byte) {
+	// check size before sanitization to avoid expensive processing of large payloads
+	if len(data) <= MaxSpanBytes {
+		data = s.sanitizeBytes(data)
+	}
+
+	if len(data) > MaxSpanBytes {
+		span.SetAttributes(
+			attribute.Bool(key+"_truncated", true),
+			attribute.Int(key+"_size", len(data)),
+		)
+		return
+	}
+
+	span.SetAttributes(attribute.String(key, string(data)))
+}
+
 // removes values of keys from sanitizeKeys in JSON.
 func (s *Service) sanitizeBytes(data []byte) []byte {
-	var (
-		m   map[string]any
-		err error
-	)
+	// fast path: no keys to sanitize, so JSON round trip is not needed
+	if !s.containsSanitizeKey(data) {
+		return data
+	}
 
-	if err = json.Unmarshal(data, &m); err != nil {
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
 		return data
 	}
 
 	s.sanitizeJSON(m)
 
-	if data, err = json.Marshal(m); err != nil {
+	sanitized, err := json.Marshal(m)
+	if err != nil {
 		return data
 	}
 
-	return data
+	return sanitized
+}
+
+// checks if data contains any of sanitizeKeys (case-insensitive).
+func (s *Service) containsSanitizeKey(data []byte) bool {
+	for _, k := range s.sanitizeKeysLower {
+		if containsFold(data, k) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checks if data contains lowerSubstr under case folding without allocations.
+// lowerSubstr must be in lower case.
+func containsFold(data, lowerSubstr []byte) bool {
+	n := len(lowerSubstr)
+	if n == 0 {
+		return true
+	}
+
+	first := lowerSubstr[0]
+	checkFirst := first < utf8.RuneSelf
+	for i := 0; i+n <= len(data); i++ {
+		// fast reject by the first ASCII byte
+		if checkFirst {
+			c := data[i]
+			if 'A' <= c && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			if c != first {
+				continue
+			}
+		}
+
+		if bytes.EqualFold(data[i:i+n], lowerSubstr) {
+			return true
+		}
+	}
+
+	return false
 }
 
 // removes values of keys from sanitizeKeys in JSON.
@@ -202,6 +404,39 @@ func tagRemoteAddr(ctx context.Context, span trace.Span) {
 	}
 }
 
+// adds rpc.service, rpc.method and attributes from WithSpanAttributes to span.
+func (s *Service) tagSpanMethod(span trace.Span, fullMethod string) {
+	if !span.IsRecording() {
+		return
+	}
+
+	service, method := splitFullMethod(fullMethod)
+	span.SetAttributes(
+		attribute.String("rpc.service", service),
+		attribute.String("rpc.method", method),
+	)
+	span.SetAttributes(s.spanAttributes...)
+}
+
+// splits full gRPC method name "/package.Service/Method" into service and method names.
+func splitFullMethod(fullMethod string) (service, method string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+
+	return "", fullMethod
+}
+
+// interceptor for tagging server span of streams with method attributes.
+func (s *Service) spanAttributesStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	s.tagSpanMethod(trace.SpanFromContext(ss.Context()), info.FullMethod)
+
+	return handler(srv, ss)
+}
+
 // adds traceID to HTTP response metadata.
 func (s *Service) setCtxModifierHTTPMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -211,7 +446,12 @@ func (s *Service) setCtxModifierHTTPMiddleware(next http.Handler) http.Handler {
 			w.Header().Set(TraceIDKey, traceID)
 		}
 
-		ctx = s.ctxHTTPModifier(ctx, r, traceID)
+		ctx = traceIDToContext(ctx, traceID, traceOK)
+		ctx = s.serviceToContext(ctx)
+		for _, modifier := range s.ctxHTTPModifiers {
+			ctx = modifier(ctx, r, traceID)
+		}
+		ctx = s.enrichLogger(ctx)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -232,30 +472,97 @@ func (s *Service) setTraceRouteHTTPMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func (s *Service) registerHealthCheckEndpoints(ctx context.Context, mux *runtime.ServeMux) error {
-	if s.healthCheckHandler != nil {
-		if err := mux.HandlePath(http.MethodGet, s.livenessHandlerPath,
-			func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-				s.healthCheckHandler.LiveEndpoint(w, r)
-			},
-		); err != nil {
-			return fmt.Errorf("%s. failed to register liveness handler: %w", s.name, err)
+// healthCheckRoutes returns health check paths: path -> true for readiness, false for liveness.
+func (s *Service) healthCheckRoutes() (map[string]bool, error) {
+	if s.healthCheckHandler == nil {
+		return nil, nil
+	}
+
+	paths := append([]HealthPath{
+		{Path: s.livenessHandlerPath, Readiness: false},
+		{Path: s.readinessHandlerPath, Readiness: true},
+	}, s.healthPaths...)
+
+	routes := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		if readiness, ok := routes[p.Path]; ok && readiness != p.Readiness {
+			return nil, fmt.Errorf("%s. health check path %s is used for both liveness and readiness", s.name, p.Path)
 		}
+		routes[p.Path] = p.Readiness
+	}
 
-		if err := mux.HandlePath(http.MethodGet, s.readinessHandlerPath,
-			func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-				s.healthCheckHandler.ReadyEndpoint(w, r)
-			},
-		); err != nil {
-			return fmt.Errorf("%s. failed to register readiness handler: %w", s.name, err)
+	return routes, nil
+}
+
+// serveLiveness serves liveness check.
+func (s *Service) serveLiveness(w http.ResponseWriter, r *http.Request) {
+	s.healthCheckHandler.LiveEndpoint(s.healthResponseWriter(w), r)
+}
+
+// serveReadiness serves readiness check.
+func (s *Service) serveReadiness(w http.ResponseWriter, r *http.Request) {
+	w = s.healthResponseWriter(w)
+	if reason := s.notReadyReason(); reason != "" {
+		http.Error(w, reason, http.StatusServiceUnavailable)
+		return
+	}
+	s.healthCheckHandler.ReadyEndpoint(w, r)
+}
+
+func (s *Service) registerHealthCheckEndpoints(ctx context.Context, mux *runtime.ServeMux) error {
+	routes, err := s.healthCheckRoutes()
+	if err != nil || len(routes) == 0 {
+		return err
+	}
+
+	for path, readiness := range routes {
+		handler, kind := s.serveLiveness, "liveness"
+		if readiness {
+			handler, kind = s.serveReadiness, "readiness"
 		}
 
-		s.logger.Info(ctx, "health check endpoints registered")
+		err = mux.HandlePath(http.MethodGet, path, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			handler(w, r)
+		})
+		if err != nil {
+			return fmt.Errorf("%s. failed to register %s handler %s: %w", s.name, kind, path, err)
+		}
 	}
 
+	s.logger.Info(ctx, "health check endpoints registered")
+
 	return nil
 }
 
+// setHeadHTTPMiddleware serves HEAD requests with GET routes. Headers are sent, the body is discarded.
+func setHeadHTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r = r.Clone(r.Context())
+		r.Method = http.MethodGet
+		next.ServeHTTP(&headResponseWriter{ResponseWriter: w}, r)
+	})
+}
+
+// headResponseWriter discards response body.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+// Write discards data.
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// Unwrap returns the original http.ResponseWriter.
+func (w *headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // setCORSMiddleware adds CORS headers.
 func (s *Service) setCORSMiddleware(next http.Handler) http.Handler {
 	if s.corsOptions.IsNone() {
@@ -264,3 +571,26 @@ func (s *Service) setCORSMiddleware(next http.Handler) http.Handler {
 
 	return cors.New(s.corsOptions.Unwrap()).Handler(next)
 }
+
+// setConcurrencyLimitHTTPMiddleware limits the number of concurrent HTTP requests.
+// Requests exceeding the limit get 503 Service Unavailable.
+func (s *Service) setConcurrencyLimitHTTPMiddleware(next http.Handler) http.Handler {
+	if s.httpConcurrencyLimit <= 0 {
+		return next
+	}
+
+	sem := make(chan struct{}, s.httpConcurrencyLimit)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+		default:
+			s.logger.Warn(r.Context(), "http concurrency limit exceeded", "limit", s.httpConcurrencyLimit)
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
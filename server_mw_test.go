@@ -0,0 +1,485 @@
+package grpcsrv
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	api "github.com/n-r-w/grpcsrv/example/protogen"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func testSpanContext() context.Context {
+	return trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	}))
+}
+
+func TestTraceIDCache(t *testing.T) {
+	s := New(context.Background(), nil)
+	ctx := testSpanContext()
+
+	traceID, ok := s.traceIDFromContext(ctx)
+	if !ok || traceID != (trace.TraceID{1, 2, 3}).String() {
+		t.Fatalf("unexpected trace ID %q", traceID)
+	}
+
+	cached, ok := s.traceIDFromContext(traceIDToContext(ctx, traceID, ok))
+	if !ok || cached != traceID {
+		t.Fatalf("expected cached trace ID %q, got %q", traceID, cached)
+	}
+
+	if _, ok = s.traceIDFromContext(traceIDToContext(context.Background(), "", false)); ok {
+		t.Fatal("trace ID must be absent without span")
+	}
+}
+
+func TestContainsSanitizeKey(t *testing.T) {
+	s := New(context.Background(), nil, WithSanitizeKeys("password", "Token"))
+
+	tests := []struct {
+		data string
+		want bool
+	}{
+		{data: `{"login":"user","password":"secret"}`, want: true},
+		{data: `{"PASSWORD":"secret"}`, want: true},
+		{data: `{"accessToken":"secret"}`, want: true},
+		{data: `{"login":"user"}`, want: false},
+		{data: `{"pass":"word"}`, want: false},
+		{data: ``, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			if got := s.containsSanitizeKey([]byte(tt.data)); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestContainsSanitizeKeyAllocs(t *testing.T) {
+	s := New(context.Background(), nil, WithSanitizeKeys("password"))
+	data := []byte(`{"login":"user","Password":"secret"}`)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = s.containsSanitizeKey(data)
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %v", allocs)
+	}
+}
+
+func BenchmarkContainsSanitizeKey(b *testing.B) {
+	s := New(context.Background(), nil, WithSanitizeKeys("password", "token", "secret"))
+	data := []byte(`{"items":[` + strings.Repeat(`{"id":"12345","name":"Some Name"},`, 100) + `{"id":"0"}]}`)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		_ = s.containsSanitizeKey(data)
+	}
+}
+
+// The trace ID is already cheap to get from span context: the only cost is hex encoding.
+// Caching saves this allocation for each repeated lookup in the interceptor chain.
+func BenchmarkTraceIDFromContext(b *testing.B) {
+	s := New(context.Background(), nil)
+	ctx := testSpanContext()
+	traceID, ok := s.traceIDFromContext(ctx)
+	cachedCtx := traceIDToContext(ctx, traceID, ok)
+
+	b.Run("span", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			_, _ = s.traceIDFromContext(ctx)
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			_, _ = s.traceIDFromContext(cachedCtx)
+		}
+	})
+}
+
+func TestConcurrencyLimitHTTPMiddleware(t *testing.T) {
+	s := New(context.Background(), nil, WithHTTPConcurrencyLimit(1))
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	handler := s.setConcurrencyLimitHTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-unblock
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = serve("/slow")
+	}()
+	<-started
+
+	if code := serve("/fast"); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 while the limit is reached, got %d", code)
+	}
+
+	close(unblock)
+	wg.Wait()
+
+	if code := serve("/fast"); code != http.StatusOK {
+		t.Fatalf("expected 200 after the slot is released, got %d", code)
+	}
+}
+
+// debugSpan calls tracingDataServerInterceptor with trace debug header and returns recorded span.
+func debugSpan(t *testing.T, s *Service, rec *tracetest.SpanRecorder, req any, handler grpc.UnaryHandler,
+) sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(TraceDebugKey, TraceDebugKeyValue))
+	_, _ = s.tracingDataServerInterceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler)
+
+	spans := rec.Ended()
+	if len(spans) == 0 {
+		t.Fatal("expected span")
+	}
+
+	return spans[len(spans)-1]
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key string) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+
+	return attribute.Value{}, false
+}
+
+func TestSpanPayloadSize(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	setTracerProvider(t, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	s := New(context.Background(), nil)
+
+	small := wrapperspb.String("small")
+	large := wrapperspb.String(strings.Repeat("a", MaxSpanBytes))
+	handler := func(_ context.Context, req any) (any, error) { return req, nil }
+
+	span := debugSpan(t, s, rec, small, handler)
+	if v, ok := spanAttr(span, "grpc_request"); !ok || v.AsString() != `"small"` {
+		t.Fatalf("expected small request in span, got %q", v.AsString())
+	}
+	if _, ok := spanAttr(span, "grpc_response"); !ok {
+		t.Fatal("expected small response in span")
+	}
+
+	// request and response are handled the same way
+	span = debugSpan(t, s, rec, large, handler)
+	for _, key := range []string{"grpc_request", "grpc_response"} {
+		if _, ok := spanAttr(span, key); ok {
+			t.Fatalf("oversized %s must not be added", key)
+		}
+		if v, ok := spanAttr(span, key+"_truncated"); !ok || !v.AsBool() {
+			t.Fatalf("expected %s_truncated", key)
+		}
+		if v, ok := spanAttr(span, key+"_size"); !ok || v.AsInt64() <= MaxSpanBytes {
+			t.Fatalf("unexpected %s_size %v", key, v.AsInt64())
+		}
+	}
+}
+
+func TestSpanErrorStatus(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	setTracerProvider(t, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	s := New(context.Background(), nil)
+
+	span := debugSpan(t, s, rec, wrapperspb.String("req"), func(context.Context, any) (any, error) {
+		return nil, status.Error(codes.NotFound, "item not found")
+	})
+
+	if span.Status().Code != otelcodes.Error {
+		t.Fatalf("expected error span status, got %v", span.Status().Code)
+	}
+	if v, _ := spanAttr(span, "rpc.grpc.status_code"); v.AsString() != codes.NotFound.String() {
+		t.Fatalf("expected status code %s, got %q", codes.NotFound, v.AsString())
+	}
+
+	v, ok := spanAttr(span, "grpc_error")
+	if !ok || !strings.Contains(v.AsString(), "item not found") {
+		t.Fatalf("expected error status in span, got %q", v.AsString())
+	}
+	if _, ok := spanAttr(span, "grpc_response"); ok {
+		t.Fatal("response must not be added on error")
+	}
+}
+
+// Span attributes must not share memory with pooled buffers reused by the next messages.
+func TestSetSpanMessagePooledBuffer(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	s := New(context.Background(), nil)
+	_, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("").Start(context.Background(), "test")
+
+	long := strings.Repeat("a", 1000)
+	s.setSpanMessage(span, "first", wrapperspb.String(long))
+	s.setSpanMessage(span, "second", wrapperspb.String("b"))
+	for range 10 {
+		s.setSpanMessage(span, "other", wrapperspb.String(strings.Repeat("c", 2000)))
+	}
+	span.End()
+
+	ended := rec.Ended()[0]
+	if v, _ := spanAttr(ended, "first"); v.AsString() != `"`+long+`"` {
+		t.Fatal("first payload is corrupted by buffer reuse")
+	}
+	if v, _ := spanAttr(ended, "second"); v.AsString() != `"b"` {
+		t.Fatalf("unexpected second payload %q", v.AsString())
+	}
+}
+
+func BenchmarkSetSpanMessage(b *testing.B) {
+	s := New(context.Background(), nil)
+	_, span := sdktrace.NewTracerProvider().Tracer("").Start(context.Background(), "bench")
+	defer span.End()
+	msg := wrapperspb.String(strings.Repeat("payload ", 100))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		s.setSpanMessage(span, "grpc_request", msg)
+	}
+}
+
+// orderUnaryModifier appends name to the list of modifiers stored in context.
+func orderUnaryModifier(name string) CtxUnaryModifier {
+	return func(ctx context.Context, _ any, _ *grpc.UnaryServerInfo, _ grpc.UnaryHandler, _, _ string) context.Context {
+		prev, _ := ctx.Value(testCtxKey{}).([]string)
+		return context.WithValue(ctx, testCtxKey{}, append(slices.Clone(prev), name))
+	}
+}
+
+// orderHTTPModifier appends name to the list of modifiers stored in context.
+func orderHTTPModifier(name string) CtxHTTPModifier {
+	return func(ctx context.Context, _ *http.Request, _ string) context.Context {
+		prev, _ := ctx.Value(testCtxKey{}).([]string)
+		return context.WithValue(ctx, testCtxKey{}, append(slices.Clone(prev), name))
+	}
+}
+
+// appliedModifiers returns names of unary and HTTP modifiers applied to request context.
+func appliedModifiers(t *testing.T, s *Service) (unary, httpMods []string) {
+	t.Helper()
+
+	_, err := s.callServerInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"},
+		func(ctx context.Context, _ any) (any, error) {
+			unary, _ = ctx.Value(testCtxKey{}).([]string)
+			return nil, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.setCtxModifierHTTPMiddleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		httpMods, _ = r.Context().Value(testCtxKey{}).([]string)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	return unary, httpMods
+}
+
+func TestAppendContextModifiers(t *testing.T) {
+	s := New(context.Background(), nil,
+		WithAppendContextModifiers(orderUnaryModifier("first"), nil, orderHTTPModifier("first")),
+		WithAppendContextModifiers(nil, nil, nil),
+		WithAppendContextModifiers(orderUnaryModifier("second"), nil, orderHTTPModifier("second")))
+
+	unary, httpMods := appliedModifiers(t, s)
+	if !slices.Equal(unary, []string{"first", "second"}) {
+		t.Fatalf("unexpected order of unary modifiers %v", unary)
+	}
+	if !slices.Equal(httpMods, []string{"first", "second"}) {
+		t.Fatalf("unexpected order of HTTP modifiers %v", httpMods)
+	}
+}
+
+func TestSpanMethodAttributes(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	s, _ := startGatewayTestService(t, &testGreeter{count: 1},
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))),
+		WithSpanAttributes(attribute.String("deployment.env", "test")))
+
+	_, err := api.NewGreeterClient(dialTestService(t, s)).SayHello(context.Background(), &api.HelloRequest{Name: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiveHellos(t, s)
+
+	for _, method := range []string{"SayHello", "SayManyHellos"} {
+		span := waitSpan(t, rec, trace.SpanKindServer, "/"+method)
+
+		want := map[string]string{
+			"rpc.service":    api.Greeter_ServiceDesc.ServiceName,
+			"rpc.method":     method,
+			"deployment.env": "test",
+		}
+		for key, value := range want {
+			if v, _ := spanAttr(span, key); v.AsString() != value {
+				t.Fatalf("expected %s=%s in %s span, got %q", key, value, method, v.AsString())
+			}
+		}
+	}
+}
+
+func TestSplitFullMethod(t *testing.T) {
+	tests := []struct {
+		fullMethod, service, method string
+	}{
+		{fullMethod: "/package.Service/Method", service: "package.Service", method: "Method"},
+		{fullMethod: "package.Service/Method", service: "package.Service", method: "Method"},
+		{fullMethod: "Method", service: "", method: "Method"},
+	}
+
+	for _, tt := range tests {
+		if service, method := splitFullMethod(tt.fullMethod); service != tt.service || method != tt.method {
+			t.Fatalf("%s: expected %q %q, got %q %q", tt.fullMethod, tt.service, tt.method, service, method)
+		}
+	}
+}
+
+func TestAlwaysCreateServerSpan(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(_ context.Context, req any) (any, error) { return req, nil }
+
+	// without the option spans are created only for requests with debug header
+	s := New(context.Background(), nil, WithTracerProvider(tp))
+	_, _ = s.tracingDataServerInterceptor(context.Background(), wrapperspb.String("req"), info, handler)
+	if len(rec.Ended()) != 0 {
+		t.Fatal("span must not be created without debug header")
+	}
+
+	s = New(context.Background(), nil, WithTracerProvider(tp), WithAlwaysCreateServerSpan())
+	_, _ = s.tracingDataServerInterceptor(peerContext("10.0.0.1"), wrapperspb.String("req"), info, handler)
+
+	spans := rec.Ended()
+	if len(spans) != 1 || spans[0].Name() != "grpc_data" {
+		t.Fatalf("expected grpc_data span, got %d spans", len(spans))
+	}
+
+	for key, value := range map[string]string{
+		"rpc.method":           "Method",
+		"remote_addr":          "10.0.0.1",
+		"rpc.grpc.status_code": codes.OK.String(),
+	} {
+		if v, _ := spanAttr(spans[0], key); v.AsString() != value {
+			t.Fatalf("expected %s=%s, got %q", key, value, v.AsString())
+		}
+	}
+
+	// payloads are added only for debug requests
+	if _, ok := spanAttr(spans[0], "grpc_request"); ok {
+		t.Fatal("request payload must not be added without debug header")
+	}
+}
+
+func TestHeadHTTPMiddleware(t *testing.T) {
+	var gotMethod string
+	handler := setHeadHTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/v1/items", nil))
+	if gotMethod != http.MethodGet {
+		t.Fatalf("HEAD must be served by GET route, got %s", gotMethod)
+	}
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected headers of GET response, got %d %v", rec.Code, rec.Header())
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("body must be discarded, got %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/items", nil))
+	if gotMethod != http.MethodGet || rec.Body.String() != `{"status":"ok"}` {
+		t.Fatalf("GET must not be modified, got %s %q", gotMethod, rec.Body.String())
+	}
+}
+
+func TestHeadGateway(t *testing.T) {
+	_, baseURL := startGatewayTestService(t, &testGreeter{},
+		WithHealthCheck(&testHealther{ready: true}, "/live", "/ready"))
+
+	resp, body := doHTTP(t, http.MethodHead, baseURL+"/live", "", nil)
+	if resp.StatusCode != http.StatusOK || body != "" {
+		t.Fatalf("expected 200 without body, got %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestSanitizationOptions(t *testing.T) {
+	data := []byte(`{"login":"user","password":"secret","apiKey":"key"}`)
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		hidden     []string
+		notChanged bool
+	}{
+		{name: "default keys", hidden: []string{"secret"}},
+		{name: "custom keys", opts: []Option{WithSanitizeKeys("apiKey")}, hidden: []string{`:"key"`}},
+		{name: "empty keys keep defaults", opts: []Option{WithSanitizeKeys()}, hidden: []string{"secret"}},
+		{name: "disabled", opts: []Option{WithoutSanitization()}, notChanged: true},
+		{
+			name: "enabled after disabling", opts: []Option{WithoutSanitization(), WithSanitizeKeys("password")},
+			hidden: []string{"secret"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(New(context.Background(), nil, tt.opts...).sanitizeBytes(data))
+
+			if tt.notChanged && got != string(data) {
+				t.Fatalf("data must not be changed, got %s", got)
+			}
+			for _, value := range tt.hidden {
+				if strings.Contains(got, value) {
+					t.Fatalf("value %s must be sanitized, got %s", value, got)
+				}
+			}
+			if !strings.Contains(got, `"login":"user"`) {
+				t.Fatalf("not sensitive values must be kept, got %s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,621 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	api "github.com/n-r-w/grpcsrv/example/protogen"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// healthInitializer IGRPCInitializer, which registers standard gRPC health service.
+type healthInitializer struct {
+	health       *health.Server
+	httpRequired bool
+	unary        []grpc.UnaryServerInterceptor
+	stream       []grpc.StreamServerInterceptor
+}
+
+func newHealthInitializer() *healthInitializer {
+	return &healthInitializer{health: health.NewServer()}
+}
+
+func (i *healthInitializer) RegisterGRPCServer(s *grpc.Server) {
+	grpc_health_v1.RegisterHealthServer(s, i.health)
+}
+
+func (i *healthInitializer) RegisterHTTPHandler(context.Context, *runtime.ServeMux, *grpc.ClientConn) error {
+	return nil
+}
+
+func (i *healthInitializer) GetOptions() InitializeOptions {
+	return InitializeOptions{
+		GRPCUnaryInterceptors:  i.unary,
+		GRPCStreamInterceptors: i.stream,
+		HTTPHandlerRequired:    i.httpRequired,
+	}
+}
+
+// testLogEntry log record of testLogger.
+type testLogEntry struct {
+	level string
+	msg   string
+	args  []any
+}
+
+// arg returns value of the key from log record arguments.
+func (e testLogEntry) arg(key string) (any, bool) {
+	for i := 0; i+1 < len(e.args); i += 2 {
+		if e.args[i] == key {
+			return e.args[i+1], true
+		}
+	}
+
+	return nil, false
+}
+
+// testLogger ctxlog.ILogger, which records log entries.
+type testLogger struct {
+	mu      sync.Mutex
+	entries []testLogEntry
+}
+
+func (l *testLogger) log(level, msg string, args []any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, testLogEntry{level: level, msg: msg, args: args})
+}
+
+func (l *testLogger) Debug(_ context.Context, msg string, args ...any) { l.log("debug", msg, args) }
+func (l *testLogger) Info(_ context.Context, msg string, args ...any)  { l.log("info", msg, args) }
+func (l *testLogger) Warn(_ context.Context, msg string, args ...any)  { l.log("warn", msg, args) }
+func (l *testLogger) Error(_ context.Context, msg string, args ...any) { l.log("error", msg, args) }
+
+// find returns the first entry with the message.
+func (l *testLogger) find(msg string) (testLogEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, e := range l.entries {
+		if e.msg == msg {
+			return e, true
+		}
+	}
+
+	return testLogEntry{}, false
+}
+
+// freeAddr returns a local address with a free port.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+
+	return addr
+}
+
+// startTestService starts service with health initializer on a free gRPC port and stops it on cleanup.
+func startTestService(t *testing.T, opts ...Option) *Service {
+	t.Helper()
+
+	return startTestServiceWith(t, []IGRPCInitializer{newHealthInitializer()}, opts...)
+}
+
+// startTestServiceWith starts service with the initializers on a free gRPC port and stops it on cleanup.
+func startTestServiceWith(t *testing.T, initializers []IGRPCInitializer, opts ...Option) *Service {
+	t.Helper()
+
+	opts = append([]Option{WithEndpoint(Endpoint{GRPC: freeAddr(t)})}, opts...)
+	s := New(context.Background(), initializers, opts...)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = s.Stop(ctx)
+	})
+
+	return s
+}
+
+// dialTestService creates gRPC client connection to the service.
+func dialTestService(t *testing.T, s *Service) *grpc.ClientConn {
+	t.Helper()
+
+	conn, err := grpc.NewClient(s.endpoint.GRPC, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func TestHTTPMaxHeaderBytes(t *testing.T) {
+	pprofAddr := freeAddr(t)
+	s := startTestService(t, WithPprof(pprofAddr), WithHTTPMaxHeaderBytes(1024))
+
+	if s.pprofServer.MaxHeaderBytes != 1024 {
+		t.Fatalf("expected MaxHeaderBytes 1024, got %d", s.pprofServer.MaxHeaderBytes)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+pprofAddr+"/debug/pprof/cmdline", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Large", strings.Repeat("a", 64*1024))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusRequestHeaderFieldsTooLarge {
+		t.Fatalf("expected 431, got %d", resp.StatusCode)
+	}
+}
+
+func TestInFlightRequests(t *testing.T) {
+	logger := &testLogger{}
+	s := New(context.Background(), []IGRPCInitializer{newHealthInitializer()},
+		WithEndpoint(Endpoint{GRPC: freeAddr(t)}), WithLogger(logger))
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = s.callServerInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{},
+			func(context.Context, any) (any, error) {
+				close(started)
+				<-unblock
+				return nil, nil
+			})
+	}()
+	<-started
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- s.Stop(context.Background())
+	}()
+
+	// shutdown is logged before waiting for in-flight requests
+	deadline := time.Now().Add(time.Second)
+	for {
+		if e, ok := logger.find("shutdown started"); ok {
+			if v, _ := e.arg("in_flight"); v != int64(1) {
+				t.Fatalf("expected 1 in-flight request, got %v", v)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("shutdown start is not logged")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	close(unblock)
+	<-done
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+
+	if n := s.inFlight.Load(); n != 0 {
+		t.Fatalf("expected no in-flight requests, got %d", n)
+	}
+	if e, ok := logger.find("shutdown completed"); !ok {
+		t.Fatal("shutdown completion is not logged")
+	} else if _, ok = e.arg("drain_duration"); !ok {
+		t.Fatal("drain duration is not logged")
+	}
+}
+
+// checkHealth calls gRPC health check and returns response header and trailer.
+func checkHealth(t *testing.T, conn *grpc.ClientConn) (metadata.MD, metadata.MD) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var header, trailer metadata.MD
+	_, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{},
+		grpc.Header(&header), grpc.Trailer(&trailer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return header, trailer
+}
+
+// setTracerProvider sets global tracer provider and restores the previous one on cleanup.
+func setTracerProvider(t *testing.T, tp trace.TracerProvider) {
+	t.Helper()
+
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+}
+
+func TestTraceIDInHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantHeader bool
+	}{
+		{name: "trailer only"},
+		{name: "header and trailer", opts: []Option{WithTraceIDInHeader()}, wantHeader: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTracerProvider(t, sdktrace.NewTracerProvider())
+			s := startTestService(t, tt.opts...)
+
+			header, trailer := checkHealth(t, dialTestService(t, s))
+
+			traceID := trailer.Get(TraceIDKey)
+			if len(traceID) != 1 || traceID[0] == "" {
+				t.Fatalf("expected trace ID in trailer, got %v", trailer)
+			}
+
+			got := header.Get(TraceIDKey)
+			if tt.wantHeader && (len(got) != 1 || got[0] != traceID[0]) {
+				t.Fatalf("expected trace ID %s in header, got %v", traceID[0], header)
+			}
+			if !tt.wantHeader && len(got) != 0 {
+				t.Fatalf("unexpected trace ID in header: %v", header)
+			}
+		})
+	}
+}
+
+type testCtxKey struct{}
+
+func TestStreamContextModifiers(t *testing.T) {
+	// initializer interceptors are called after context modifiers
+	var (
+		mu  sync.Mutex
+		got []any
+	)
+	initializer := newHealthInitializer()
+	initializer.stream = []grpc.StreamServerInterceptor{
+		func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			mu.Lock()
+			got = append(got, ss.Context().Value(testCtxKey{}))
+			mu.Unlock()
+			return handler(srv, ss)
+		},
+	}
+
+	streamModifier := func(ctx context.Context, info *grpc.StreamServerInfo, _ grpc.StreamHandler,
+		_, _ string,
+	) context.Context {
+		return context.WithValue(ctx, testCtxKey{}, info.FullMethod)
+	}
+	s := startTestServiceWith(t, []IGRPCInitializer{initializer}, WithContextModifiers(nil, streamModifier, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := grpc_health_v1.NewHealthClient(dialTestService(t, s)).Watch(ctx,
+		&grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 1 || got[0] != "/grpc.health.v1.Health/Watch" {
+		t.Fatalf("stream context is not enriched by modifiers: %v", got)
+	}
+}
+
+// startWithBusyPort starts service with option using busy address and checks that Start fails
+// and gRPC server is stopped.
+func startWithBusyPort(t *testing.T, opt func(addr string) Option) {
+	t.Helper()
+
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	grpcAddr := freeAddr(t)
+	s := New(context.Background(), []IGRPCInitializer{newHealthInitializer()},
+		WithEndpoint(Endpoint{GRPC: grpcAddr}), opt(busy.Addr().String()))
+	if err = s.Start(context.Background()); err == nil {
+		_ = s.Stop(context.Background())
+		t.Fatal("expected Start error for busy port")
+	}
+
+	// gRPC listener is closed by abortStart
+	deadline := time.Now().Add(time.Second)
+	for {
+		l, err := net.Listen("tcp", grpcAddr)
+		if err == nil {
+			_ = l.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("gRPC server is not stopped after failed Start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStartFailsOnMetricsBindError(t *testing.T) {
+	startWithBusyPort(t, WithMetrics)
+}
+
+func TestStartFailsOnPprofBindError(t *testing.T) {
+	startWithBusyPort(t, WithPprof)
+}
+
+// httpGetStatus returns status code of GET request.
+func httpGetStatus(t *testing.T, url string) int {
+	t.Helper()
+
+	resp, err := http.Get(url) //nolint:noctx // test
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	return resp.StatusCode
+}
+
+func TestPprofAndMetricsServers(t *testing.T) {
+	pprofAddr, metricsAddr := freeAddr(t), freeAddr(t)
+	startTestService(t, WithPprof(pprofAddr), WithMetrics(metricsAddr))
+
+	if code := httpGetStatus(t, "http://"+pprofAddr+"/debug/pprof/cmdline"); code != http.StatusOK {
+		t.Fatalf("pprof: expected 200, got %d", code)
+	}
+	if code := httpGetStatus(t, "http://"+metricsAddr+"/metrics"); code != http.StatusOK {
+		t.Fatalf("metrics: expected 200, got %d", code)
+	}
+}
+
+func TestStopShutsDownPprof(t *testing.T) {
+	pprofAddr := freeAddr(t)
+	s := New(context.Background(), []IGRPCInitializer{newHealthInitializer()},
+		WithEndpoint(Endpoint{GRPC: freeAddr(t)}), WithPprof(pprofAddr))
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := httpGetStatus(t, "http://"+pprofAddr+"/debug/pprof/cmdline"); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the port is released after Stop
+	l, err := net.Listen("tcp", pprofAddr)
+	if err != nil {
+		t.Fatalf("pprof server is not stopped: %v", err)
+	}
+	_ = l.Close()
+}
+
+func TestServerTimeHeader(t *testing.T) {
+	s := startTestService(t, WithServerTimeHeader())
+
+	_, trailer := checkHealth(t, dialTestService(t, s))
+
+	vals := trailer.Get(ServerTimeKey)
+	if len(vals) != 1 {
+		t.Fatalf("expected server time in trailer, got %v", trailer)
+	}
+	if _, err := time.Parse(time.RFC3339, vals[0]); err != nil {
+		t.Fatalf("server time is not RFC3339: %v", err)
+	}
+}
+
+func TestStartupSummaryLog(t *testing.T) {
+	logger := &testLogger{}
+	metricsAddr := freeAddr(t)
+	s := startTestService(t, WithLogger(logger), WithMetrics(metricsAddr), WithRecover())
+
+	e, ok := logger.find("service started")
+	if !ok {
+		t.Fatal("startup summary is not logged")
+	}
+
+	want := map[string]any{
+		"grpc":    s.endpoint.GRPC,
+		"http":    "",
+		"metrics": metricsAddr,
+		"recover": true,
+		"tls":     false,
+	}
+	for key, value := range want {
+		if got, _ := e.arg(key); got != value {
+			t.Errorf("%s: expected %v, got %v", key, value, got)
+		}
+	}
+}
+
+// stopOrder returns order in which servers were stopped according to the log.
+func stopOrder(logger *testLogger) []string {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	var order []string
+	for _, e := range logger.entries {
+		if server, ok := strings.CutSuffix(e.msg, " stopped gracefully"); ok {
+			order = append(order, server)
+		}
+	}
+
+	return order
+}
+
+func TestStopSequence(t *testing.T) {
+	tests := []struct {
+		name       string
+		seq        []ServerKind
+		want       []string
+		concurrent int // index of the first of two HTTP servers stopped concurrently, -1 if none
+	}{
+		{name: "default", want: []string{"http", "pprof server", "grpc"}, concurrent: 0},
+		{
+			name:       "grpc first",
+			seq:        []ServerKind{ServerKindGRPC},
+			want:       []string{"grpc", "http", "pprof server"},
+			concurrent: 1,
+		},
+		{
+			name:       "full sequence",
+			seq:        []ServerKind{ServerKindHTTP, ServerKindGRPC, ServerKindPprof},
+			want:       []string{"http", "grpc", "pprof server"},
+			concurrent: -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &testLogger{}
+			s := New(context.Background(), []IGRPCInitializer{&greeterInitializer{greeter: &testGreeter{}}},
+				WithEndpoint(Endpoint{GRPC: freeAddr(t), HTTP: freeAddr(t)}), WithPprof(freeAddr(t)),
+				WithLogger(logger), WithStopSequence(tt.seq))
+			if err := s.Start(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := s.Stop(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			got := stopOrder(logger)
+			if len(got) == len(tt.want) && tt.concurrent >= 0 {
+				slices.Sort(got[tt.concurrent : tt.concurrent+2])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStopReturnsShutdownErrors(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	greeter := &testGreeter{sayHello: func(_ context.Context, req *api.HelloRequest) (*api.HelloResponse, error) {
+		close(entered)
+		<-release
+		return &api.HelloResponse{Message: "hello " + req.GetName()}, nil
+	}}
+
+	httpAddr, logger := freeAddr(t), &testLogger{}
+	s := New(context.Background(), []IGRPCInitializer{&greeterInitializer{greeter: greeter}},
+		WithEndpoint(Endpoint{GRPC: freeAddr(t), HTTP: httpAddr}), WithLogger(logger))
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// in-flight gateway request doesn't let HTTP server shut down before the stop timeout
+	go func() {
+		resp, err := http.Post("http://"+httpAddr+"/v1/greeter:SayHello", //nolint:noctx // test
+			"application/json", strings.NewReader(`{"name":"bob"}`))
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+	<-entered
+	// gRPC server is stopped gracefully after HTTP, so the handler is released to let it complete
+	time.AfterFunc(200*time.Millisecond, func() { close(release) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := s.Stop(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "http server") {
+		t.Fatalf("expected error of http server, got %v", err)
+	}
+	if _, ok := logger.find("shutdown completed with errors"); !ok {
+		t.Fatal("expected shutdown errors to be logged")
+	}
+}
+
+func TestUnknownServiceHandler(t *testing.T) {
+	var intercepted, handled string
+	initializer := newHealthInitializer()
+	initializer.stream = []grpc.StreamServerInterceptor{
+		func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			intercepted = info.FullMethod
+			return handler(srv, ss)
+		},
+	}
+
+	s := startTestServiceWith(t, []IGRPCInitializer{initializer},
+		WithUnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+			handled, _ = grpc.MethodFromServerStream(stream)
+			return status.Error(codes.FailedPrecondition, "proxied")
+		}))
+	conn := dialTestService(t, s)
+
+	const method = "/unknown.Service/Method"
+	err := conn.Invoke(context.Background(), method, &grpc_health_v1.HealthCheckRequest{},
+		&grpc_health_v1.HealthCheckResponse{})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected error of unknown service handler, got %v", err)
+	}
+	if handled != method || intercepted != method {
+		t.Fatalf("expected method %s in handler and interceptor, got %q, %q", method, handled, intercepted)
+	}
+
+	// registered services are not affected
+	_, err = grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// without the handler unknown services are not implemented
+	conn = dialTestService(t, startTestService(t))
+	err = conn.Invoke(context.Background(), method, &grpc_health_v1.HealthCheckRequest{},
+		&grpc_health_v1.HealthCheckResponse{})
+	if status.Code(err) != codes.Unimplemented {
+		t.Fatalf("expected Unimplemented, got %v", err)
+	}
+}
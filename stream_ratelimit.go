@@ -0,0 +1,57 @@
+package grpcsrv
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// rateLimitedServerStream throttles SendMsg calls to the configured number of messages per second.
+type rateLimitedServerStream struct {
+	grpc.ServerStream
+
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time // earliest time of the next send
+}
+
+// SendMsg implements grpc.ServerStream. Waits until the next message is allowed to be sent.
+func (r *rateLimitedServerStream) SendMsg(m any) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if wait := time.Until(r.next); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			timer.Stop()
+			return r.Context().Err()
+		}
+	}
+
+	err := r.ServerStream.SendMsg(m)
+	r.next = time.Now().Add(r.interval)
+
+	return err
+}
+
+// interceptor for limiting message send rate of server streams.
+func (s *Service) streamSendRateLimitInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if !info.IsServerStream {
+		return handler(srv, ss)
+	}
+
+	rate, ok := s.streamSendRateLimits[info.FullMethod]
+	if !ok || rate <= 0 {
+		return handler(srv, ss)
+	}
+
+	return handler(srv, &rateLimitedServerStream{
+		ServerStream: ss,
+		interval:     time.Duration(float64(time.Second) / rate),
+	})
+}
@@ -0,0 +1,118 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// rateLimitedStream returns stream passed to the handler by streamSendRateLimitInterceptor.
+func rateLimitedStream(t *testing.T, s *Service, ss grpc.ServerStream, info *grpc.StreamServerInfo) grpc.ServerStream {
+	t.Helper()
+
+	var got grpc.ServerStream
+	err := s.streamSendRateLimitInterceptor(nil, ss, info, func(_ any, stream grpc.ServerStream) error {
+		got = stream
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return got
+}
+
+func TestStreamSendRateLimitInterceptor(t *testing.T) {
+	const (
+		limited  = "/test.Service/Limited"
+		disabled = "/test.Service/Disabled"
+	)
+	s := New(context.Background(), nil, WithStreamSendRateLimit(map[string]float64{limited: 20, disabled: 0}))
+
+	tests := []struct {
+		name    string
+		info    *grpc.StreamServerInfo
+		limited bool
+	}{
+		{name: "limited", info: &grpc.StreamServerInfo{FullMethod: limited, IsServerStream: true}, limited: true},
+		{name: "client stream", info: &grpc.StreamServerInfo{FullMethod: limited, IsClientStream: true}},
+		{name: "zero rate", info: &grpc.StreamServerInfo{FullMethod: disabled, IsServerStream: true}},
+		{name: "not configured", info: &grpc.StreamServerInfo{FullMethod: "/test.Service/Other", IsServerStream: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss := &testServerStream{}
+			stream := rateLimitedStream(t, s, ss, tt.info)
+			if _, ok := stream.(*rateLimitedServerStream); ok != tt.limited {
+				t.Fatalf("expected rate limited stream %v, got %T", tt.limited, stream)
+			}
+
+			start := time.Now()
+			for i := range 3 {
+				if err := stream.SendMsg(i); err != nil {
+					t.Fatal(err)
+				}
+			}
+			elapsed := time.Since(start)
+
+			if len(ss.sent) != 3 {
+				t.Fatalf("expected 3 messages, got %d", len(ss.sent))
+			}
+			// 3 messages at 20 per second are sent with 2 intervals of 50ms
+			if tt.limited && elapsed < 90*time.Millisecond {
+				t.Fatalf("expected send to be throttled, took %s", elapsed)
+			}
+			if !tt.limited && elapsed > 50*time.Millisecond {
+				t.Fatalf("expected send without throttling, took %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestStreamSendRateLimitContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &rateLimitedServerStream{ServerStream: &testServerStream{ctx: ctx}, interval: time.Hour}
+
+	if err := stream.SendMsg(1); err != nil {
+		t.Fatal(err)
+	}
+
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	if err := stream.SendMsg(2); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("send must stop waiting when context is done, took %s", elapsed)
+	}
+}
+
+func TestStreamSendRateLimitConcurrent(t *testing.T) {
+	ss := &testServerStream{}
+	stream := &rateLimitedServerStream{ServerStream: ss, interval: 10 * time.Millisecond}
+
+	const senders = 5
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := range senders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = stream.SendMsg(i)
+		}()
+	}
+	wg.Wait()
+
+	if len(ss.sent) != senders {
+		t.Fatalf("expected %d messages, got %d", senders, len(ss.sent))
+	}
+	// concurrent senders share one limit: 5 messages are sent with 4 intervals of 10ms
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Fatalf("expected sends to be throttled, took %s", elapsed)
+	}
+}
@@ -0,0 +1,70 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// stdoutExporter writes finished spans to writer as JSON lines. For local development without collector.
+type stdoutExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// stdoutSpan JSON representation of span.
+type stdoutSpan struct {
+	Name         string            `json:"name"`
+	TraceID      string            `json:"trace_id"`
+	SpanID       string            `json:"span_id"`
+	ParentSpanID string            `json:"parent_span_id,omitempty"`
+	Kind         string            `json:"kind"`
+	Start        time.Time         `json:"start"`
+	Duration     string            `json:"duration"`
+	Status       string            `json:"status"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+}
+
+// ExportSpans implements sdktrace.SpanExporter.
+func (e *stdoutExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	enc := json.NewEncoder(e.w)
+	for _, span := range spans {
+		out := stdoutSpan{
+			Name:     span.Name(),
+			TraceID:  span.SpanContext().TraceID().String(),
+			SpanID:   span.SpanContext().SpanID().String(),
+			Kind:     span.SpanKind().String(),
+			Start:    span.StartTime(),
+			Duration: span.EndTime().Sub(span.StartTime()).String(),
+			Status:   span.Status().Code.String(),
+		}
+		if span.Parent().IsValid() {
+			out.ParentSpanID = span.Parent().SpanID().String()
+		}
+		if attrs := span.Attributes(); len(attrs) > 0 {
+			out.Attributes = make(map[string]string, len(attrs))
+			for _, a := range attrs {
+				out.Attributes[string(a.Key)] = a.Value.Emit()
+			}
+		}
+
+		if err := enc.Encode(out); err != nil {
+			return fmt.Errorf("failed to write span: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Shutdown implements sdktrace.SpanExporter.
+func (e *stdoutExporter) Shutdown(_ context.Context) error {
+	return nil
+}
@@ -0,0 +1,73 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestStdoutExporter(t *testing.T) {
+	restoreGlobals(t)
+
+	var buf bytes.Buffer
+	shutdown, err := InitTracing(context.Background(), Config{ServiceName: "test"}, WithStdoutExporter(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, parent := otel.Tracer("").Start(context.Background(), "parent")
+	_, child := otel.Tracer("").Start(ctx, "child")
+	child.SetAttributes(attribute.String("user", "bob"), attribute.Int("items", 3))
+	child.SetStatus(codes.Error, "failed")
+	child.End()
+	parent.End()
+
+	// flushes batched spans
+	if err = shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 spans, got %d: %s", len(lines), buf.String())
+	}
+
+	var got stdoutSpan
+	if err = json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	switch {
+	case got.Name != "child":
+		t.Fatalf("expected child span first, got %q", got.Name)
+	case got.TraceID != parent.SpanContext().TraceID().String():
+		t.Fatalf("unexpected trace ID %s", got.TraceID)
+	case got.ParentSpanID != parent.SpanContext().SpanID().String():
+		t.Fatalf("unexpected parent span ID %s", got.ParentSpanID)
+	case got.Status != codes.Error.String():
+		t.Fatalf("unexpected status %s", got.Status)
+	case got.Attributes["user"] != "bob" || got.Attributes["items"] != "3":
+		t.Fatalf("unexpected attributes %v", got.Attributes)
+	}
+}
+
+func TestExporterOptions(t *testing.T) {
+	var cfg Config
+
+	WithStdoutExporter(nil)(&cfg)
+	if cfg.StdoutWriter == nil {
+		t.Fatal("stdout must be used for nil writer")
+	}
+
+	// the last option wins
+	WithOTLPEndpoint("collector:4317", true)(&cfg)
+	if cfg.StdoutWriter != nil || cfg.OTLPEndpoint != "collector:4317" || !cfg.Insecure {
+		t.Fatalf("OTLP exporter is not configured: %+v", cfg)
+	}
+}
@@ -0,0 +1,156 @@
+// Package telemetry initializes OpenTelemetry tracing for services based on grpcsrv.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// samplerEnv environment variable with sampler name, handled by OpenTelemetry SDK.
+const samplerEnv = "OTEL_TRACES_SAMPLER"
+
+// DefaultOTLPEndpoint endpoint of OTLP gRPC collector used if Config.OTLPEndpoint is empty.
+const DefaultOTLPEndpoint = "localhost:4317"
+
+// Config tracing configuration.
+type Config struct {
+	// ServiceName name of the service in traces.
+	ServiceName string
+	// OTLPEndpoint address of OTLP gRPC collector. If empty, DefaultOTLPEndpoint is used.
+	OTLPEndpoint string
+	// Insecure disables TLS for connection to the collector.
+	Insecure bool
+	// SampleRatio fraction of traces to sample for root spans, child spans follow the parent decision.
+	// Values <= 0 or >= 1 mean all traces.
+	SampleRatio float64
+	// ResourceAttributes additional attributes of the resource (e.g. deployment.environment).
+	ResourceAttributes []attribute.KeyValue
+	// StdoutWriter if set, spans are written to it as JSON lines instead of sending to OTLP collector.
+	StdoutWriter io.Writer
+}
+
+// Option - function for changing tracing configuration.
+type Option func(*Config)
+
+// WithSampleRatio sets fraction of sampled root spans, child spans follow the parent decision
+// (ParentBased(TraceIDRatioBased(ratio))).
+func WithSampleRatio(ratio float64) Option {
+	return func(c *Config) {
+		c.SampleRatio = ratio
+	}
+}
+
+// WithOTLPEndpoint sets address of OTLP gRPC collector. insecure disables TLS.
+func WithOTLPEndpoint(addr string, insecure bool) Option {
+	return func(c *Config) {
+		c.OTLPEndpoint = addr
+		c.Insecure = insecure
+		c.StdoutWriter = nil
+	}
+}
+
+// WithStdoutExporter writes spans to w as JSON lines instead of sending them to OTLP collector.
+// For local development without collector. If w is nil, os.Stdout is used.
+func WithStdoutExporter(w io.Writer) Option {
+	return func(c *Config) {
+		if w == nil {
+			w = os.Stdout
+		}
+		c.StdoutWriter = w
+	}
+}
+
+// InitTracing initializes OpenTelemetry tracer provider with OTLP gRPC or stdout exporter and propagators
+// (W3C trace context, baggage, Jaeger, B3) and sets them as global.
+// Options are applied on top of cfg. Sampler set by OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG
+// environment variables overrides the configured sample ratio.
+// Returns function that flushes spans and shuts down the tracer provider.
+func InitTracing(ctx context.Context, cfg Config, opts ...Option) (func(context.Context) error, error) {
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(cfg.ResourceAttributes)+1)
+	attrs = append(attrs, semconv.ServiceNameKey.String(cfg.ServiceName))
+	attrs = append(attrs, cfg.ResourceAttributes...)
+
+	rc := resource.NewWithAttributes(semconv.SchemaURL, attrs...)
+
+	tpOptions := []sdktrace.TracerProviderOption{
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(rc),
+	}
+	// otherwise the sampler is configured by the SDK from environment variables
+	if os.Getenv(samplerEnv) == "" {
+		tpOptions = append(tpOptions, sdktrace.WithSampler(newSampler(cfg.SampleRatio)))
+	}
+
+	tp := sdktrace.NewTracerProvider(tpOptions...)
+
+	// Set the global tracer provider
+	otel.SetTracerProvider(tp)
+
+	// Set the global propagator
+	propagators := []propagation.TextMapPropagator{
+		propagation.TraceContext{},
+		propagation.Baggage{},
+		jaeger.Jaeger{},
+		b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader | b3.B3SingleHeader)),
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagators...))
+
+	return tp.Shutdown, nil
+}
+
+// newExporter creates span exporter according to the configuration.
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	if cfg.StdoutWriter != nil {
+		return &stdoutExporter{w: cfg.StdoutWriter}, nil
+	}
+
+	endpoint := cfg.OTLPEndpoint
+	if endpoint == "" {
+		endpoint = DefaultOTLPEndpoint
+	}
+
+	otlpGrpcOptions := []otlptracegrpc.Option{
+		otlptracegrpc.WithEndpoint(endpoint),
+	}
+	if cfg.Insecure {
+		otlpGrpcOptions = append(otlpGrpcOptions, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, otlpGrpcOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+	}
+
+	return exporter, nil
+}
+
+// newSampler returns parent based sampler with ratio of root spans.
+func newSampler(ratio float64) sdktrace.Sampler {
+	if ratio <= 0 || ratio >= 1 {
+		return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	}
+
+	return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
+}
@@ -0,0 +1,104 @@
+package telemetry
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// restoreGlobals restores global tracer provider and propagator after the test.
+func restoreGlobals(t *testing.T) {
+	t.Helper()
+
+	tp, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(tp)
+		otel.SetTextMapPropagator(propagator)
+	})
+}
+
+func TestInitTracing(t *testing.T) {
+	restoreGlobals(t)
+
+	// OTLP exporter connects lazily, so the collector is not required
+	shutdown, err := InitTracing(context.Background(), Config{ServiceName: "test", Insecure: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = shutdown(ctx)
+	})
+
+	if _, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider); !ok {
+		t.Fatalf("global tracer provider is not set, got %T", otel.GetTracerProvider())
+	}
+
+	fields := otel.GetTextMapPropagator().Fields()
+	for _, field := range []string{"traceparent", "baggage", "uber-trace-id", "b3"} {
+		if !slices.Contains(fields, field) {
+			t.Fatalf("propagator field %q is missing in %v", field, fields)
+		}
+	}
+}
+
+func TestNewSampler(t *testing.T) {
+	sampled := func(s sdktrace.Sampler, parent trace.SpanContext, traceID trace.TraceID) bool {
+		ctx := trace.ContextWithSpanContext(context.Background(), parent)
+		res := s.ShouldSample(sdktrace.SamplingParameters{ParentContext: ctx, TraceID: traceID, Name: "test"})
+		return res.Decision == sdktrace.RecordAndSample
+	}
+
+	lowID := trace.TraceID{15: 1}                                                  // sampled by any ratio
+	highID := trace.TraceID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 15: 1} // not sampled by ratio < 1
+	sampledParent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: highID, SpanID: trace.SpanID{1}, TraceFlags: trace.FlagsSampled, Remote: true,
+	})
+	notSampledParent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: lowID, SpanID: trace.SpanID{1}, Remote: true,
+	})
+
+	for _, ratio := range []float64{0, -1, 1, 2} {
+		if !sampled(newSampler(ratio), trace.SpanContext{}, highID) {
+			t.Fatalf("ratio %v must sample all root spans", ratio)
+		}
+	}
+
+	s := newSampler(0.5)
+	if !sampled(s, trace.SpanContext{}, lowID) || sampled(s, trace.SpanContext{}, highID) {
+		t.Fatal("root spans must be sampled by trace ID ratio")
+	}
+	if !sampled(s, sampledParent, highID) {
+		t.Fatal("child of sampled parent must be sampled")
+	}
+	if sampled(s, notSampledParent, lowID) {
+		t.Fatal("child of not sampled parent must not be sampled")
+	}
+}
+
+func TestSamplerEnvOverride(t *testing.T) {
+	restoreGlobals(t)
+	t.Setenv(samplerEnv, "always_off")
+
+	shutdown, err := InitTracing(context.Background(), Config{ServiceName: "test", Insecure: true, SampleRatio: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = shutdown(ctx)
+	}()
+
+	_, span := otel.Tracer("").Start(context.Background(), "test")
+	defer span.End()
+	if span.SpanContext().IsSampled() {
+		t.Fatal("sampler from environment must override configured ratio")
+	}
+}
@@ -0,0 +1,186 @@
+package grpcsrv
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// certReloader keeps TLS certificate and allows to reload it without restarting the server.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// reload re-reads certificate files.
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+
+	return nil
+}
+
+// getCertificate implements tls.Config.GetCertificate.
+func (r *certReloader) getCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// prepareTLS loads TLS certificate if TLS is enabled.
+func (s *Service) prepareTLS() error {
+	if s.tlsCertFile == "" {
+		return nil
+	}
+
+	reloader, err := newCertReloader(s.tlsCertFile, s.tlsKeyFile)
+	if err != nil {
+		return fmt.Errorf("%s. %w", s.name, err)
+	}
+	s.certReloader = reloader
+
+	if s.tlsClientCAFile != "" {
+		caPEM, err := os.ReadFile(s.tlsClientCAFile)
+		if err != nil {
+			return fmt.Errorf("%s. failed to read client CA file: %w", s.name, err)
+		}
+
+		s.tlsClientCAs = x509.NewCertPool()
+		if !s.tlsClientCAs.AppendCertsFromPEM(caPEM) {
+			return fmt.Errorf("%s. no certificates found in client CA file %s", s.name, s.tlsClientCAFile)
+		}
+	}
+
+	return nil
+}
+
+// getTLSConfig returns TLS configuration for gRPC server.
+func (s *Service) getTLSConfig() *tls.Config {
+	cfg := &tls.Config{
+		GetCertificate: s.certReloader.getCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+
+	if s.tlsClientCAs != nil {
+		// client certificate is optional at TLS level, required methods are checked by interceptor
+		cfg.ClientCAs = s.tlsClientCAs
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return cfg
+}
+
+// PeerCertificateFromContext returns verified leaf certificate of the client (mTLS).
+// Can be used in handlers for certificate-based authorization (e.g. by Subject.CommonName).
+// Requires WithTLS and WithTLSClientCA.
+func PeerCertificateFromContext(ctx context.Context) (*x509.Certificate, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+
+	return tlsInfo.State.VerifiedChains[0][0], true
+}
+
+// clientCertUnaryInterceptor rejects unary calls to clientCertMethods without verified client certificate.
+func (s *Service) clientCertUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if _, ok := s.clientCertMethods[info.FullMethod]; ok {
+		if _, ok := PeerCertificateFromContext(ctx); !ok {
+			return nil, status.Error(codes.Unauthenticated, "client certificate required")
+		}
+	}
+
+	return handler(ctx, req)
+}
+
+// clientCertStreamInterceptor rejects streams of clientCertMethods without verified client certificate.
+func (s *Service) clientCertStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if _, ok := s.clientCertMethods[info.FullMethod]; ok {
+		if _, ok := PeerCertificateFromContext(ss.Context()); !ok {
+			return status.Error(codes.Unauthenticated, "client certificate required")
+		}
+	}
+
+	return handler(srv, ss)
+}
+
+// startTLSReload starts periodic reloading of TLS certificate if enabled.
+func (s *Service) startTLSReload(ctx context.Context) {
+	if s.certReloader == nil || s.tlsReloadInterval <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	s.tlsReloadStop = stop
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.tlsReloadInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := s.certReloader.reload(); err != nil {
+					s.logger.Error(ctx, "failed to reload TLS certificate", "error", err)
+				} else {
+					s.logger.Debug(ctx, "TLS certificate reloaded")
+				}
+			}
+		}
+	}()
+}
+
+// stopTLSReload stops periodic reloading of TLS certificate. Safe to call several times.
+func (s *Service) stopTLSReload() {
+	if s.tlsReloadStop != nil {
+		close(s.tlsReloadStop)
+		s.tlsReloadStop = nil
+	}
+}
@@ -0,0 +1,127 @@
+package grpcsrv
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestStopTLSReloadTwice(t *testing.T) {
+	s := New(context.Background(), nil)
+	s.certReloader = &certReloader{}
+	s.tlsReloadInterval = time.Hour
+
+	s.startTLSReload(context.Background())
+
+	// abortStart followed by Stop
+	s.stopTLSReload()
+	s.stopTLSReload()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("TLS reload goroutine is not stopped")
+	}
+}
+
+func certContext(cert *x509.Certificate) context.Context {
+	state := tls.ConnectionState{}
+	if cert != nil {
+		state.PeerCertificates = []*x509.Certificate{cert}
+		state.VerifiedChains = [][]*x509.Certificate{{cert}}
+	}
+
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+}
+
+func TestClientCertUnaryInterceptor(t *testing.T) {
+	s := New(context.Background(), nil, WithClientCertRequiredMethods("/test.Service/Secure"))
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "client"}}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		code   codes.Code
+	}{
+		{name: "required with cert", ctx: certContext(cert), method: "/test.Service/Secure", code: codes.OK},
+		{name: "required without cert", ctx: certContext(nil), method: "/test.Service/Secure", code: codes.Unauthenticated},
+		{name: "required without peer", ctx: context.Background(), method: "/test.Service/Secure",
+			code: codes.Unauthenticated},
+		{name: "not required", ctx: context.Background(), method: "/test.Service/Public", code: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := func(context.Context, any) (any, error) {
+				called = true
+				return nil, nil
+			}
+
+			_, err := s.clientCertUnaryInterceptor(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if status.Code(err) != tt.code {
+				t.Fatalf("expected code %s, got %v", tt.code, err)
+			}
+			if called != (tt.code == codes.OK) {
+				t.Fatalf("expected handler called %v, got %v", tt.code == codes.OK, called)
+			}
+		})
+	}
+}
+
+func TestClientCertStreamInterceptor(t *testing.T) {
+	s := New(context.Background(), nil, WithClientCertRequiredMethods("/test.Service/Secure"))
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Service/Secure"}
+	handler := func(any, grpc.ServerStream) error { return nil }
+
+	err := s.clientCertStreamInterceptor(nil, &testServerStream{ctx: certContext(nil)}, info, handler)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", err)
+	}
+
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "client"}}
+	if err := s.clientCertStreamInterceptor(nil, &testServerStream{ctx: certContext(cert)}, info, handler); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestPeerCertificateFromContext(t *testing.T) {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "client"}}
+
+	got, ok := PeerCertificateFromContext(certContext(cert))
+	if !ok || got.Subject.CommonName != "client" {
+		t.Fatalf("expected client certificate, got %v, %v", got, ok)
+	}
+
+	if _, ok := PeerCertificateFromContext(context.Background()); ok {
+		t.Fatal("expected no certificate without peer")
+	}
+
+	if _, ok := PeerCertificateFromContext(certContext(nil)); ok {
+		t.Fatal("expected no certificate without verified chains")
+	}
+
+	// presented but not verified certificate must not be returned
+	unverified := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+	}})
+	if _, ok := PeerCertificateFromContext(unverified); ok {
+		t.Fatal("expected no certificate for unverified peer")
+	}
+}
@@ -0,0 +1,64 @@
+package grpcsrv
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// filters trailer metadata by allowlist.
+func (s *Service) filterTrailer(md metadata.MD) metadata.MD {
+	filtered := make(metadata.MD, len(md))
+	for key, vals := range md {
+		if _, ok := s.trailerAllowlist[strings.ToLower(key)]; ok {
+			filtered[key] = vals
+		}
+	}
+
+	return filtered
+}
+
+// trailerFilterTransportStream removes trailers that are not in allowlist.
+type trailerFilterTransportStream struct {
+	grpc.ServerTransportStream
+	s *Service
+}
+
+// SetTrailer sets the trailer metadata removing keys that are not in allowlist.
+func (t *trailerFilterTransportStream) SetTrailer(md metadata.MD) error {
+	return t.ServerTransportStream.SetTrailer(t.s.filterTrailer(md))
+}
+
+// trailerFilterServerStream removes trailers that are not in allowlist.
+type trailerFilterServerStream struct {
+	grpc.ServerStream
+	s *Service
+}
+
+// SetTrailer sets the trailer metadata removing keys that are not in allowlist.
+func (t *trailerFilterServerStream) SetTrailer(md metadata.MD) {
+	t.ServerStream.SetTrailer(t.s.filterTrailer(md))
+}
+
+// interceptor for filtering trailers of unary calls.
+func (s *Service) trailerFilterUnaryInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if stream := grpc.ServerTransportStreamFromContext(ctx); stream != nil {
+		ctx = grpc.NewContextWithServerTransportStream(ctx, &trailerFilterTransportStream{
+			ServerTransportStream: stream,
+			s:                     s,
+		})
+	}
+
+	return handler(ctx, req)
+}
+
+// interceptor for filtering trailers of streams.
+func (s *Service) trailerFilterStreamInterceptor(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	return handler(srv, &trailerFilterServerStream{ServerStream: ss, s: s})
+}
@@ -0,0 +1,63 @@
+package grpcsrv
+
+import (
+	"context"
+	"testing"
+
+	api "github.com/n-r-w/grpcsrv/example/protogen"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// trailerServerStream testServerStream, which records trailers.
+type trailerServerStream struct {
+	testServerStream
+	trailer metadata.MD
+}
+
+func (s *trailerServerStream) SetTrailer(md metadata.MD) {
+	s.trailer = metadata.Join(s.trailer, md)
+}
+
+func TestTrailerFilterUnary(t *testing.T) {
+	greeter := &testGreeter{sayHello: func(ctx context.Context, req *api.HelloRequest) (*api.HelloResponse, error) {
+		_ = grpc.SetTrailer(ctx, metadata.Pairs("x-public", "1", "x-internal-db-host", "db1"))
+		return &api.HelloResponse{Message: "hello " + req.GetName()}, nil
+	}}
+	s, _ := startGatewayTestService(t, greeter, WithTrailerFilter([]string{"X-Public"}))
+
+	var trailer metadata.MD
+	_, err := api.NewGreeterClient(dialTestService(t, s)).SayHello(context.Background(),
+		&api.HelloRequest{Name: "bob"}, grpc.Trailer(&trailer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := trailer.Get("x-public"); len(v) != 1 || v[0] != "1" {
+		t.Fatalf("allowed trailer must be preserved, got %v", trailer)
+	}
+	if v := trailer.Get("x-internal-db-host"); len(v) != 0 {
+		t.Fatalf("not allowed trailer must be removed, got %v", v)
+	}
+}
+
+func TestTrailerFilterStream(t *testing.T) {
+	s := New(context.Background(), nil, WithTrailerFilter([]string{"x-public"}))
+	ss := &trailerServerStream{}
+
+	err := s.trailerFilterStreamInterceptor(nil, ss, &grpc.StreamServerInfo{FullMethod: "/test.Service/Watch"},
+		func(_ any, stream grpc.ServerStream) error {
+			stream.SetTrailer(metadata.Pairs("x-public", "1", "x-internal", "2", TraceIDKey, "trace"))
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ss.trailer.Get("x-public")) != 1 || len(ss.trailer.Get(TraceIDKey)) != 1 {
+		t.Fatalf("allowed and service trailers must be preserved, got %v", ss.trailer)
+	}
+	if len(ss.trailer.Get("x-internal")) != 0 {
+		t.Fatalf("not allowed trailer must be removed, got %v", ss.trailer)
+	}
+}
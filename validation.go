@@ -0,0 +1,160 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// validatorAll message with ValidateAll method, e.g. generated by protoc-gen-validate.
+// ValidateAll returns all violations of the message, not only the first one.
+type validatorAll interface {
+	ValidateAll() error
+}
+
+// validator message with Validate method, e.g. generated by protoc-gen-validate.
+type validator interface {
+	Validate() error
+}
+
+// fieldViolation validation error of a single field, e.g. generated by protoc-gen-validate.
+type fieldViolation interface {
+	Field() string
+	Reason() string
+}
+
+// multiError validation error with several violations, e.g. generated by protoc-gen-validate.
+type multiError interface {
+	AllErrors() []error
+}
+
+// validateRequest validates request if it implements ValidateAll or Validate method.
+func validateRequest(req any) error {
+	var err error
+	switch v := req.(type) {
+	case validatorAll:
+		err = v.ValidateAll()
+	case validator:
+		err = v.Validate()
+	default:
+		return nil
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	if st := validationStatus(err); st != nil {
+		return st.Err()
+	}
+
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+// validationStatus converts error with field violations to codes.InvalidArgument status
+// with google.rpc.BadRequest details. Returns nil if the error has no field violations
+// or already is a gRPC status.
+func validationStatus(err error) *status.Status {
+	if _, ok := status.FromError(err); ok {
+		return nil
+	}
+
+	violations := collectFieldViolations(err, "")
+	if len(violations) == 0 {
+		return nil
+	}
+
+	st := status.New(codes.InvalidArgument, err.Error())
+	stDetails, errDetails := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if errDetails != nil {
+		return st
+	}
+
+	return stDetails
+}
+
+// collectFieldViolations returns field violations of the error. Nested violations (e.g. of embedded messages)
+// get field path prefixed with the parent field.
+func collectFieldViolations(err error, prefix string) []*errdetails.BadRequest_FieldViolation {
+	var multi multiError
+	if errors.As(err, &multi) {
+		var res []*errdetails.BadRequest_FieldViolation
+		for _, e := range multi.AllErrors() {
+			res = append(res, collectFieldViolations(e, prefix)...)
+		}
+		return res
+	}
+
+	var fv fieldViolation
+	if !errors.As(err, &fv) {
+		return nil
+	}
+
+	field := fv.Field()
+	if prefix != "" {
+		field = prefix + "." + field
+	}
+
+	// violation of embedded message
+	if cause := errors.Unwrap(err); cause != nil {
+		if nested := collectFieldViolations(cause, field); len(nested) > 0 {
+			return nested
+		}
+	}
+
+	return []*errdetails.BadRequest_FieldViolation{{
+		Field:       field,
+		Description: fv.Reason(),
+	}}
+}
+
+// interceptor for validation of unary requests. Validation errors of the handler with field violations
+// are converted to google.rpc.BadRequest details as well.
+func (s *Service) validationUnaryInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := handler(ctx, req)
+	if err != nil {
+		if st := validationStatus(err); st != nil {
+			return resp, st.Err()
+		}
+	}
+
+	return resp, err
+}
+
+// validatingServerStream validates received messages.
+type validatingServerStream struct {
+	grpc.ServerStream
+}
+
+// RecvMsg implements grpc.ServerStream.
+func (v *validatingServerStream) RecvMsg(m any) error {
+	if err := v.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return validateRequest(m)
+}
+
+// interceptor for validation of stream messages.
+func (s *Service) validationStreamInterceptor(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	err := handler(srv, &validatingServerStream{ServerStream: ss})
+	if err != nil {
+		if st := validationStatus(err); st != nil {
+			return st.Err()
+		}
+	}
+
+	return err
+}
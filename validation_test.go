@@ -0,0 +1,151 @@
+package grpcsrv
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testFieldError field violation in the form of protoc-gen-validate errors.
+type testFieldError struct {
+	field  string
+	reason string
+	cause  error
+}
+
+func (e testFieldError) Field() string  { return e.field }
+func (e testFieldError) Reason() string { return e.reason }
+func (e testFieldError) Error() string  { return e.field + ": " + e.reason }
+func (e testFieldError) Unwrap() error  { return e.cause }
+
+// testMultiError several violations in the form of protoc-gen-validate errors.
+type testMultiError []error
+
+func (m testMultiError) AllErrors() []error { return m }
+func (m testMultiError) Error() string      { return errors.Join(m...).Error() }
+
+// testAllValidated request with ValidateAll method.
+type testAllValidated struct{ err error }
+
+func (r testAllValidated) ValidateAll() error { return r.err }
+
+// testValidated request with Validate method.
+type testValidated struct{ err error }
+
+func (r testValidated) Validate() error { return r.err }
+
+// violationFields returns fields of BadRequest violations of the error.
+func violationFields(t *testing.T, err error) []string {
+	t.Helper()
+
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+
+	var fields []string
+	for _, d := range status.Convert(err).Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			for _, v := range br.GetFieldViolations() {
+				fields = append(fields, v.GetField())
+			}
+		}
+	}
+
+	return fields
+}
+
+func TestValidateRequest(t *testing.T) {
+	nameErr := testFieldError{field: "name", reason: "must not be empty"}
+	cityErr := testFieldError{
+		field: "address", reason: "invalid", cause: testFieldError{field: "city", reason: "too long"},
+	}
+
+	tests := []struct {
+		name       string
+		req        any
+		wantErr    bool
+		wantFields []string
+	}{
+		{name: "no validator", req: "plain"},
+		{name: "valid", req: testAllValidated{}},
+		{name: "validate all", req: testAllValidated{err: testMultiError{nameErr, cityErr}}, wantErr: true,
+			wantFields: []string{"name", "address.city"}},
+		{name: "validate", req: testValidated{err: nameErr}, wantErr: true, wantFields: []string{"name"}},
+		{name: "without violations", req: testValidated{err: errors.New("invalid")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRequest(tt.req)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+
+			if fields := violationFields(t, err); !slices.Equal(fields, tt.wantFields) {
+				t.Fatalf("expected violations %v, got %v", tt.wantFields, fields)
+			}
+		})
+	}
+}
+
+func TestValidationUnaryInterceptor(t *testing.T) {
+	s := New(context.Background(), nil)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+	called := false
+	_, err := s.validationUnaryInterceptor(context.Background(),
+		testValidated{err: testFieldError{field: "name", reason: "required"}}, info,
+		func(context.Context, any) (any, error) {
+			called = true
+			return nil, nil
+		})
+	if called {
+		t.Fatal("handler must not be called for invalid request")
+	}
+	if fields := violationFields(t, err); !slices.Equal(fields, []string{"name"}) {
+		t.Fatalf("expected name violation, got %v", fields)
+	}
+
+	// field violations returned by the handler are converted as well
+	_, err = s.validationUnaryInterceptor(context.Background(), testValidated{}, info,
+		func(context.Context, any) (any, error) {
+			return nil, testFieldError{field: "id", reason: "not found"}
+		})
+	if fields := violationFields(t, err); !slices.Equal(fields, []string{"id"}) {
+		t.Fatalf("expected id violation, got %v", fields)
+	}
+
+	// gRPC status errors are returned as is
+	_, err = s.validationUnaryInterceptor(context.Background(), testValidated{}, info,
+		func(context.Context, any) (any, error) {
+			return nil, status.Error(codes.NotFound, "not found")
+		})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+}
+
+func TestValidationStreamInterceptor(t *testing.T) {
+	s := New(context.Background(), nil)
+	ss := &testServerStream{recv: func(any) error { return nil }}
+
+	err := s.validationStreamInterceptor(nil, ss, &grpc.StreamServerInfo{},
+		func(_ any, stream grpc.ServerStream) error {
+			if err := stream.RecvMsg(testAllValidated{}); err != nil {
+				t.Fatalf("expected valid message, got %v", err)
+			}
+			return stream.RecvMsg(testAllValidated{err: testFieldError{field: "name", reason: "required"}})
+		})
+	if fields := violationFields(t, err); !slices.Equal(fields, []string{"name"}) {
+		t.Fatalf("expected name violation, got %v", fields)
+	}
+}